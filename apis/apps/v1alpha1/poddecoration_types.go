@@ -0,0 +1,272 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// AnnotationPodDecorationInitContainers configures how init containers in the PodDecoration template are
+// injected. The value is a JSON object keyed by init container name, e.g.
+//
+//	{"istio-proxy": {"injectPolicy": "BeforeInitContainers", "restartPolicy": "Always"}}
+//
+// Init containers with restartPolicy Always are injected as native sidecars, which start before the
+// following init containers and app containers, and are stopped after them.
+const AnnotationPodDecorationInitContainers = "poddecoration.kusionstack.io/init-containers"
+
+// AnnotationPodNativeSidecars lists names of init containers injected as native sidecars on pod, separated
+// by comma. The pod webhook sets restartPolicy Always on them, because the field is not in the
+// corev1.Container of this version.
+const AnnotationPodNativeSidecars = "poddecoration.kusionstack.io/native-sidecars"
+
+// AnnotationPodDecorationPatches carries patches on fields of pod not in the PodDecoration template, e.g.
+// securityContext, dnsConfig or priorityClassName. The value is an ordered JSON list of PodPatch, e.g.
+//
+//	[{"type": "json", "patch": [{"op": "add", "path": "/spec/priorityClassName", "value": "high"}]},
+//	 {"type": "strategic", "patch": {"spec": {"dnsConfig": {"options": [{"name": "ndots", "value": "2"}]}}}}]
+//
+// Patches are applied in order after the template. They are validated by the webhook on a sample pod with
+// the template applied, so JSON patches should not remove or test fields which may be absent on pods.
+const AnnotationPodDecorationPatches = "poddecoration.kusionstack.io/patches"
+
+// AnnotationPodDecorationConflictPolicy sets how conflicts with other PodDecorations are handled. Two
+// PodDecorations conflict if they may select the same pods, and patch the same container, env, volume,
+// metadata key or runtimeClassName with different values, so that the result depends on their order.
+const AnnotationPodDecorationConflictPolicy = "poddecoration.kusionstack.io/conflict-policy"
+
+// AnnotationPodDecorationConflicts is set by controller with the JSON list of PodDecorationConflict on pods
+// selected by the PodDecoration, because PodDecorationStatus has no field for them.
+const AnnotationPodDecorationConflicts = "poddecoration.kusionstack.io/conflicts"
+
+// AnnotationPodDecorationWebhookInjection set "true" decorates pods not controlled by CollaSet, e.g. pods of
+// Deployment, StatefulSet or bare pods, which are selected by the PodDecoration. They are decorated by the pod
+// webhook on creation if the PodDecorationWebhookInjection feature gate is enabled, and are not updated
+// in-place, so a new revision takes effect on pods created afterwards.
+const AnnotationPodDecorationWebhookInjection = "poddecoration.kusionstack.io/webhook-injection"
+
+// LabelPodWebhookInjection set "true" on pods not controlled by CollaSet opts in decorating by the pod webhook.
+// The pod webhook only receives pods with this label or controlled by KusionStack.
+const LabelPodWebhookInjection = "poddecoration.kusionstack.io/webhook-injection"
+
+// AnnotationPodDecorationPinnedRevision pins the PodDecoration to a ControllerRevision in its history, which
+// is rolled out as the updated revision by the update strategy instead of the revision of current spec. It is
+// used to roll back without re-applying the old spec. Pinned revisions are kept from history truncation.
+const AnnotationPodDecorationPinnedRevision = "poddecoration.kusionstack.io/pinned-revision"
+
+// AnnotationPodDecorationRevisionHistory is set by controller with the JSON list of PodDecorationRevision in
+// history, because PodDecorationStatus has no field for them.
+const AnnotationPodDecorationRevisionHistory = "poddecoration.kusionstack.io/revision-history"
+
+// AnnotationPodDecorationNamespaceSelector makes the PodDecoration cluster-wide. The value is the JSON of
+// metav1.LabelSelector, e.g.
+//
+//	{"matchLabels": {"log-agent": "enabled"}}
+//
+// The PodDecoration decorates pods selected by spec.selector in namespaces selected by it, instead of pods in
+// its own namespace. It is only honored on PodDecorations in the namespace of kuperator, where its revisions
+// are kept. Template and update strategy work as namespace-local ones, and pods are not owned by it.
+const AnnotationPodDecorationNamespaceSelector = "poddecoration.kusionstack.io/namespace-selector"
+
+// AnnotationPodDecorationGroup puts the PodDecoration in a group. Only one PodDecoration in a group decorates
+// a pod, which has the highest weight. On equal weight, namespace-local PodDecorations override cluster-wide
+// ones. A namespace-local PodDecoration also overrides the cluster-wide one with the same name.
+const AnnotationPodDecorationGroup = "poddecoration.kusionstack.io/group"
+
+// AnnotationPodDecorationNamespaceStatus is set by controller on cluster-wide PodDecorations with the JSON
+// list of PodDecorationNamespaceStatus, because PodDecorationStatus aggregates pods of all namespaces.
+const AnnotationPodDecorationNamespaceStatus = "poddecoration.kusionstack.io/namespace-status"
+
+// AnnotationPodDecorationCanary rolls out the updated revision in steps, instead of spec.updateStrategy. The value
+// is the JSON of PodDecorationCanary, e.g.
+//
+//	{"steps": [{"percent": 5, "waitForAvailable": true, "pauseSeconds": 600},
+//	           {"percent": 50, "waitForAvailable": true}, {"percent": 100}],
+//	 "progressDeadlineSeconds": 1200}
+//
+// Each step updates the percent of pods selected by the PodDecoration like partition. The controller moves to
+// the next step once pods of the step are updated, and service available if waitForAvailable, and the pause is
+// over. If pods of the step are not ready within the deadline, the rollout halts. Steps start over for each
+// updated revision. The last step must be 100, and spec.updateStrategy is not used once steps are completed.
+const AnnotationPodDecorationCanary = "poddecoration.kusionstack.io/canary"
+
+// AnnotationPodDecorationCanaryStatus is set by controller with the JSON of PodDecorationCanaryStatus. Removing it
+// restarts a halted rollout from the first step.
+const AnnotationPodDecorationCanaryStatus = "poddecoration.kusionstack.io/canary-status"
+
+// DefaultCanaryProgressDeadlineSeconds is the default deadline of each canary step
+const DefaultCanaryProgressDeadlineSeconds int32 = 600
+
+type PodDecorationCanary struct {
+	// Steps to roll out the updated revision, in ascending percent, ending with 100
+	Steps []PodDecorationCanaryStep `json:"steps"`
+
+	// ProgressDeadlineSeconds is the maximum seconds for pods of a step to be ready, defaults to 600
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+}
+
+type PodDecorationCanaryStep struct {
+	// Percent of pods selected by the PodDecoration to update in the step, from 1 to 100
+	Percent int32 `json:"percent"`
+
+	// WaitForAvailable waits for updated pods of the step to be service available before the next step
+	WaitForAvailable bool `json:"waitForAvailable,omitempty"`
+
+	// PauseSeconds to wait after pods of the step are ready, before the next step
+	PauseSeconds int32 `json:"pauseSeconds,omitempty"`
+}
+
+type CanaryCondition string
+
+const (
+	// CanaryProgressing means pods of the current step are being updated
+	CanaryProgressing CanaryCondition = "Progressing"
+	// CanaryPaused means pods of the current step are ready, and the rollout pauses before the next step
+	CanaryPaused CanaryCondition = "Paused"
+	// CanaryHalted means pods of the current step were not ready within the deadline
+	CanaryHalted CanaryCondition = "Halted"
+	// CanaryCompleted means all steps are done, and all pods are updated
+	CanaryCompleted CanaryCondition = "Completed"
+)
+
+type PodDecorationCanaryStatus struct {
+	// Revision is the updated revision rolled out by steps
+	Revision string `json:"revision"`
+
+	// Step is the index of the current step
+	Step int32 `json:"step"`
+
+	// StepStartTime is the time the current step started
+	StepStartTime metav1.Time `json:"stepStartTime"`
+
+	// StepReadyTime is the time pods of the current step were ready, when the pause starts
+	StepReadyTime *metav1.Time `json:"stepReadyTime,omitempty"`
+
+	// Condition of the rollout
+	Condition CanaryCondition `json:"condition"`
+
+	// Message about the condition
+	Message string `json:"message,omitempty"`
+}
+
+type PodDecorationNamespaceStatus struct {
+	// Namespace of the pods
+	Namespace string `json:"namespace"`
+
+	// MatchedPods is the number of pods selected by the PodDecoration in the namespace
+	MatchedPods int32 `json:"matchedPods"`
+
+	// InjectedPods is the number of pods decorated with any revision of the PodDecoration
+	InjectedPods int32 `json:"injectedPods"`
+
+	// UpdatedPods is the number of pods decorated with the updated revision
+	UpdatedPods int32 `json:"updatedPods"`
+
+	// UpdatedReadyPods is the number of ready pods decorated with the updated revision
+	UpdatedReadyPods int32 `json:"updatedReadyPods"`
+
+	// UpdatedAvailablePods is the number of service available pods decorated with the updated revision
+	UpdatedAvailablePods int32 `json:"updatedAvailablePods"`
+}
+
+type PodDecorationRevision struct {
+	// Revision is the name of the ControllerRevision
+	Revision string `json:"revision"`
+
+	// Number is the revision number of the ControllerRevision
+	Number int64 `json:"number"`
+
+	// CreationTimestamp of the ControllerRevision
+	CreationTimestamp metav1.Time `json:"creationTimestamp"`
+
+	// Pods is the number of pods decorated with the revision
+	Pods int32 `json:"pods,omitempty"`
+}
+
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyReport reports conflicts by warnings of webhook, events and annotation, it is the default
+	ConflictPolicyReport ConflictPolicy = "Report"
+	// ConflictPolicyStrict rejects the newer one of conflicting PodDecorations by webhook, if either of
+	// them is strict
+	ConflictPolicyStrict ConflictPolicy = "Strict"
+)
+
+type PodDecorationConflict struct {
+	// PodDecoration is the name of the conflicting PodDecoration, or namespace/name if it is in another namespace
+	PodDecoration string `json:"podDecoration"`
+
+	// Fields are patched by both PodDecorations with different values, e.g. "env LOG_LEVEL"
+	Fields []string `json:"fields"`
+
+	// Pods is the number of pods selected by both PodDecorations
+	Pods int32 `json:"pods"`
+}
+
+// PodDecorationRevisionAnnotations are annotations of PodDecoration saved in revisions, so that changing
+// them rolls out a new revision like changing the template
+var PodDecorationRevisionAnnotations = []string{
+	AnnotationPodDecorationInitContainers,
+	AnnotationPodDecorationPatches,
+}
+
+type InitContainerInjectPolicy string
+
+const (
+	// BeforeInitContainers injects the init container before existing init containers of pod
+	BeforeInitContainers InitContainerInjectPolicy = "BeforeInitContainers"
+	// AfterInitContainers injects the init container after existing init containers of pod, it is the default
+	AfterInitContainers InitContainerInjectPolicy = "AfterInitContainers"
+)
+
+type ContainerRestartPolicy string
+
+const (
+	ContainerRestartPolicyAlways ContainerRestartPolicy = "Always"
+)
+
+type InitContainerPatch struct {
+	// InjectPolicy is the position of the init container, defaults to AfterInitContainers
+	InjectPolicy InitContainerInjectPolicy `json:"injectPolicy,omitempty"`
+
+	// RestartPolicy Always injects the init container as a native sidecar
+	RestartPolicy *ContainerRestartPolicy `json:"restartPolicy,omitempty"`
+}
+
+// IsNativeSidecar returns true if the init container is injected as a native sidecar
+func (p *InitContainerPatch) IsNativeSidecar() bool {
+	return p != nil && p.RestartPolicy != nil && *p.RestartPolicy == ContainerRestartPolicyAlways
+}
+
+type PodPatchType string
+
+const (
+	// JSONPatchType is the RFC 6902 JSON patch
+	JSONPatchType PodPatchType = "json"
+	// StrategicMergePatchType is the strategic merge patch of pod
+	StrategicMergePatchType PodPatchType = "strategic"
+)
+
+type PodPatch struct {
+	// Type of the patch, json or strategic
+	Type PodPatchType `json:"type"`
+
+	// Patch is the list of JSON patch operations, or the strategic merge patch object
+	Patch runtime.RawExtension `json:"patch"`
+}
@@ -0,0 +1,358 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// AnnotationPodTransitionRuleExtendedRules carries the definitions of rule types which are not in
+// PodTransitionRule spec yet. The value is a JSON object keyed by rule name, e.g.
+//
+//	{"error-rate": {"metricCheck": {"address": "http://prometheus:9090", "query": "...", ...}}}
+//
+// The rule with the same name in spec.rules supplies stage, conditions and filter, and leaves its
+// own definition empty.
+const AnnotationPodTransitionRuleExtendedRules = "podtransitionrule.kusionstack.io/extended-rules"
+
+// AnnotationPodTransitionRuleMode sets the default mode of all rules in the PodTransitionRule, and the
+// mode of extended rule definition overrides it
+const AnnotationPodTransitionRuleMode = "podtransitionrule.kusionstack.io/mode"
+
+type RuleMode string
+
+const (
+	// RuleModeEnforce blocks pods rejected by the rule, it is the default mode
+	RuleModeEnforce RuleMode = "Enforce"
+	// RuleModeAudit records rejections of the rule in status, events and metrics, but treats pods as passed
+	RuleModeAudit RuleMode = "Audit"
+)
+
+// ExtendedRuleDefinition is the definition of one rule in AnnotationPodTransitionRuleExtendedRules. Exactly one
+// rule type is set in it and the rule in spec together, Webhook and AvailablePolicy only extend the rule in spec.
+type ExtendedRuleDefinition struct {
+	// Mode of the rule, defaults to the mode of PodTransitionRule
+	Mode RuleMode `json:"mode,omitempty"`
+
+	// MetricCheck checks pods by the result of a PromQL query
+	MetricCheck *MetricCheckRule `json:"metricCheck,omitempty"`
+
+	// Cel checks pods by a CEL expression
+	Cel *CelRule `json:"cel,omitempty"`
+
+	// MaintenanceWindow allows transitions only in recurring windows and out of freeze periods
+	MaintenanceWindow *MaintenanceWindowRule `json:"maintenanceWindow,omitempty"`
+
+	// RateLimit admits at most Limit pods through the stage in any sliding Window
+	RateLimit *RateLimitRule `json:"rateLimit,omitempty"`
+
+	// AvailablePolicy extends the availablePolicy of the rule in spec
+	AvailablePolicy *AvailablePolicyExtension `json:"availablePolicy,omitempty"`
+
+	// Webhook extends the webhook of the rule in spec
+	Webhook *WebhookExtension `json:"webhook,omitempty"`
+
+	// Dependency passes pods only when another workload is healthy
+	Dependency *DependencyRule `json:"dependency,omitempty"`
+
+	// ManualApproval passes pods only after they are approved in AnnotationPodTransitionRuleApprovals
+	ManualApproval *ManualApprovalRule `json:"manualApproval,omitempty"`
+
+	// Group passes pods passing at least MinPassed of its member rules
+	Group *RuleGroup `json:"group,omitempty"`
+
+	// Custom is handled by the ruler registered with its kind in process
+	Custom *CustomRule `json:"custom,omitempty"`
+}
+
+// RuleGroup evaluates member rules with OR semantics, e.g. pass pods approved by the webhook or
+// carrying the emergency label
+//
+//	{"approval-or-emergency": {"group": {"rules": ["approval", "emergency"]}}}
+//
+// Member rules are defined in spec.rules, and they are only evaluated in the group, in the stage of the
+// group rule. Conditions and filter of member rules are ignored. Pods skipping a member rule by the
+// skip-rule annotation of pod are taken as passing it.
+type RuleGroup struct {
+	// Rules are names of member rules, groups can not be nested
+	Rules []string `json:"rules"`
+
+	// MinPassed is the number of member rules a pod is required to pass, defaults to 1
+	MinPassed int32 `json:"minPassed,omitempty"`
+}
+
+// AnnotationPodTransitionRuleApprovals is the approval record of manual approval rules. The value is a
+// JSON object keyed by rule name, e.g.
+//
+//	{"approve": [{"pods": ["foo-0", "foo-1"]}, {"count": 2}]}
+//
+// ID, ApprovedBy and ApprovedAt of new approvals are set by the webhook with the user of the request.
+const AnnotationPodTransitionRuleApprovals = "podtransitionrule.kusionstack.io/approvals"
+
+// ManualApprovalRule passes approved pods, and shows pods waiting for approval in the TaskStates of
+// RuleState. Pods skipping the rule by the skip-rule annotation of pod need no approval.
+type ManualApprovalRule struct {
+	// Message is the reject reason of pods waiting for approval
+	Message string `json:"message,omitempty"`
+}
+
+// Approval approves pods of a manual approval rule, either by names or by count
+type Approval struct {
+	// ID identifies the approval in RuleState
+	ID string `json:"id,omitempty"`
+
+	// Pods are names of approved pods
+	Pods []string `json:"pods,omitempty"`
+
+	// Count approves this number of pods waiting for approval when the approval is first processed,
+	// in order of pod name. Approved pods are recorded in the History of RuleState.
+	Count int32 `json:"count,omitempty"`
+
+	// ApprovedBy is the user who made the approval
+	ApprovedBy string `json:"approvedBy,omitempty"`
+
+	// ApprovedAt is the time of the approval
+	ApprovedAt *metav1.Time `json:"approvedAt,omitempty"`
+}
+
+// DependencyRule passes pods only when the workload in the namespace of PodTransitionRule is healthy. The
+// workload is any object exposing spec.replicas, standard status replicas or status conditions, and at least
+// one of Conditions, MinReadyValue and Updated is required. Changes of CollaSets, Deployments and StatefulSets
+// trigger the rule at once, and workloads of other kinds are checked periodically. Workloads of other kinds are
+// read from the API server, so operators must grant get on them to the controller, e.g. with a ClusterRole
+// bound to the service account of kuperator.
+type DependencyRule struct {
+	// APIVersion of the workload, e.g. apps.kusionstack.io/v1alpha1
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the workload, e.g. CollaSet
+	Kind string `json:"kind"`
+
+	// Name of the workload
+	Name string `json:"name"`
+
+	// Conditions are types of status conditions of the workload required to be True, e.g. Available
+	Conditions []string `json:"conditions,omitempty"`
+
+	// MinReadyValue is the number or percentage of spec.replicas required to be ready, e.g. 80%
+	MinReadyValue *intstr.IntOrString `json:"minReadyValue,omitempty"`
+
+	// Updated requires the workload to observe its latest generation and update all replicas
+	Updated bool `json:"updated,omitempty"`
+}
+
+// WebhookExtension extends TransitionRuleWebhook of PodTransitionRule
+type WebhookExtension struct {
+	// Protocol of webhook and polling requests, defaults to http. With grpc, URLs of clientConfig and poll
+	// are like grpc://host:port for plaintext or grpcs://host:port for TLS verified by the caBundle, and
+	// the server implements the PodTransitionRuleWebhook service in apis/webhook/v1alpha1/webhook.proto.
+	Protocol WebhookProtocol `json:"protocol,omitempty"`
+
+	// BearerTokenSecretRef selects the key of a Secret in the namespace of PodTransitionRule, whose value
+	// is sent as bearer token in the Authorization header of webhook and polling requests
+	BearerTokenSecretRef *corev1.SecretKeySelector `json:"bearerTokenSecretRef,omitempty"`
+
+	// ClientCertSecretRef refers to a kubernetes.io/tls Secret in the namespace of PodTransitionRule,
+	// whose tls.crt and tls.key are used as client certificate of webhook and polling requests
+	ClientCertSecretRef *corev1.LocalObjectReference `json:"clientCertSecretRef,omitempty"`
+
+	// Headers are added to webhook and polling requests
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Backoff delays the next request of pods failed or rejected by the webhook exponentially
+	Backoff *WebhookBackoff `json:"backoff,omitempty"`
+
+	// ApprovalCacheSeconds keeps approvals of pods for the period, so that the pod with the same revision
+	// is approved again without calling the webhook. Caching is disabled if it is 0.
+	ApprovalCacheSeconds int32 `json:"approvalCacheSeconds,omitempty"`
+
+	// Polling limits polling tasks of the webhook
+	Polling *WebhookPolling `json:"polling,omitempty"`
+}
+
+type WebhookPolling struct {
+	// MaxConcurrentTasks is the max number of polling tasks of the webhook querying at the same time,
+	// bounded by the limit of controller. There is no limit per webhook if it is 0.
+	MaxConcurrentTasks int32 `json:"maxConcurrentTasks,omitempty"`
+
+	// DeadlineSeconds is how long the result of timeout polling task is kept, defaults to 60
+	DeadlineSeconds int32 `json:"deadlineSeconds,omitempty"`
+}
+
+type WebhookProtocol string
+
+const (
+	WebhookProtocolHTTP WebhookProtocol = "http"
+	WebhookProtocolGRPC WebhookProtocol = "grpc"
+)
+
+var (
+	DefaultWebhookBackoffInitialIntervalSeconds = int32(5)
+	DefaultWebhookBackoffMaxIntervalSeconds     = int32(300)
+)
+
+type WebhookBackoff struct {
+	// InitialIntervalSeconds is the interval after the first failure, defaults to 5
+	InitialIntervalSeconds int32 `json:"initialIntervalSeconds,omitempty"`
+
+	// MaxIntervalSeconds caps the interval, defaults to 300
+	MaxIntervalSeconds int32 `json:"maxIntervalSeconds,omitempty"`
+
+	// MaxRetries is the number of failures before pods are rejected permanently, until their revision
+	// changes. Pods are retried forever if it is 0.
+	MaxRetries int32 `json:"maxRetries,omitempty"`
+}
+
+// CustomRule configures a rule type registered by projects embedding kuperator, e.g.
+//
+//	{"quota": {"custom": {"kind": "team-quota", "params": {"team": "foo"}}}}
+type CustomRule struct {
+	// Kind is the name the ruler is registered with
+	Kind string `json:"kind"`
+
+	// Params is passed to the ruler as is
+	Params *runtime.RawExtension `json:"params,omitempty"`
+}
+
+// AvailablePolicyExtension extends AvailableRule of PodTransitionRule
+type AvailablePolicyExtension struct {
+	// TopologyKey applies minAvailableValue and maxUnavailableValue to each topology domain instead
+	// of all targets, e.g. topology.kubernetes.io/zone. The domain of a pod is the value of the key in
+	// labels of its node, or in its own labels if the node does not have the key.
+	TopologyKey string `json:"topologyKey,omitempty"`
+}
+
+// CelRule passes pods on which the expression evaluates to true. Variables in the expression:
+//
+//	pod:     the pod object, e.g. pod.metadata.annotations["x"] == "y"
+//	owner:   the controller owner reference of the pod, empty if it has no controller
+//	targets: all pod objects selected by the PodTransitionRule
+type CelRule struct {
+	// Expression is a CEL expression returning bool
+	Expression string `json:"expression"`
+
+	// Reason of rejected pods, it is a text/template rendered with the variables of the expression
+	// and the rule name, e.g. "{{ .pod.metadata.name }} is blocked by {{ .rule }}"
+	Reason string `json:"reason,omitempty"`
+}
+
+// MaintenanceWindowRule rejects all pods out of windows or in freeze periods, and checks them again
+// when the next window opens
+type MaintenanceWindowRule struct {
+	// TimeZone of window schedules, e.g. Asia/Shanghai, UTC by default
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Windows allowing transitions, transitions are allowed at any time out of freeze periods if empty
+	Windows []RecurringWindow `json:"windows,omitempty"`
+
+	// Freezes forbidding transitions, they take precedence over Windows
+	Freezes []FreezePeriod `json:"freezes,omitempty"`
+}
+
+// RecurringWindow opens at each time of Schedule and lasts for Duration
+type RecurringWindow struct {
+	// Schedule is a standard cron expression, e.g. "0 2 * * 1-5" for weekdays 02:00
+	Schedule string `json:"schedule"`
+
+	// Duration of the window, e.g. 3h
+	Duration metav1.Duration `json:"duration"`
+}
+
+// FreezePeriod forbids transitions during [Start, End)
+type FreezePeriod struct {
+	Name  string      `json:"name,omitempty"`
+	Start metav1.Time `json:"start"`
+	End   metav1.Time `json:"end"`
+}
+
+// RateLimitRule limits the throughput of pods passing the rule. Admissions of pods are recorded in the
+// RuleState of the rule, so the budget is kept across reconciliations and restarts of controller.
+type RateLimitRule struct {
+	// Limit is the number of pods admitted in each Window
+	Limit int32 `json:"limit"`
+
+	// Window is the length of the sliding window, e.g. 10m
+	Window metav1.Duration `json:"window"`
+}
+
+type MetricCheckScope string
+
+const (
+	// MetricCheckScopePod compares the sample of each pod with the threshold
+	MetricCheckScopePod MetricCheckScope = "Pod"
+	// MetricCheckScopeAggregate compares the only sample of the query with the threshold, and passes or rejects all pods
+	MetricCheckScopeAggregate MetricCheckScope = "Aggregate"
+)
+
+type MetricCheckRule struct {
+	// Address of the Prometheus-compatible HTTP API, e.g. http://prometheus.monitoring:9090
+	Address string `json:"address"`
+
+	// Query is an instant PromQL query. In Pod scope, the result vector is matched with pods by PodLabel.
+	Query string `json:"query"`
+
+	// Scope is Pod by default
+	Scope MetricCheckScope `json:"scope,omitempty"`
+
+	// PodLabel is the label of result samples holding pod name in Pod scope, "pod" by default
+	PodLabel string `json:"podLabel,omitempty"`
+
+	// Threshold the sample compared with
+	Threshold MetricThreshold `json:"threshold"`
+
+	// NoDataPolicy decides the result of pods without samples, Reject by default
+	NoDataPolicy *NoDataPolicyType `json:"noDataPolicy,omitempty"`
+
+	// TimeoutSeconds of each query, 10 by default
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// IntervalSeconds before checking rejected pods again, 30 by default
+	IntervalSeconds *int32 `json:"intervalSeconds,omitempty"`
+}
+
+type MetricOperator string
+
+const (
+	MetricOperatorLessThan       MetricOperator = "<"
+	MetricOperatorLessOrEqual    MetricOperator = "<="
+	MetricOperatorGreaterThan    MetricOperator = ">"
+	MetricOperatorGreaterOrEqual MetricOperator = ">="
+	MetricOperatorEqual          MetricOperator = "=="
+	MetricOperatorNotEqual       MetricOperator = "!="
+)
+
+// MetricThreshold passes the sample satisfying `sample Operator Value`
+type MetricThreshold struct {
+	Operator MetricOperator `json:"operator"`
+	// Value is a float number in string
+	Value string `json:"value"`
+}
+
+type NoDataPolicyType string
+
+const (
+	NoDataPolicyReject NoDataPolicyType = "Reject"
+	NoDataPolicyPass   NoDataPolicyType = "Pass"
+)
+
+var (
+	DefaultMetricCheckTimeoutSeconds  = int32(10)
+	DefaultMetricCheckIntervalSeconds = int32(30)
+)
@@ -0,0 +1,172 @@
+// Copyright 2024 The KusionStack Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The gRPC protocol of PodTransitionRule webhooks, mirroring WebhookRequest, WebhookResponse and
+// PollResponse of the HTTP protocol in kusionstack.io/kube-api/apps/v1alpha1.
+//
+// Regenerate with:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative webhook.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.23.4
+// source: webhook.proto
+
+package v1alpha1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	PodTransitionRuleWebhook_Check_FullMethodName = "/kuperator.webhook.v1alpha1.PodTransitionRuleWebhook/Check"
+	PodTransitionRuleWebhook_Poll_FullMethodName  = "/kuperator.webhook.v1alpha1.PodTransitionRuleWebhook/Poll"
+)
+
+// PodTransitionRuleWebhookClient is the client API for PodTransitionRuleWebhook service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PodTransitionRuleWebhookClient interface {
+	// Check checks pods like the POST request of HTTP protocol
+	Check(ctx context.Context, in *WebhookRequest, opts ...grpc.CallOption) (*WebhookResponse, error)
+	// Poll queries the task started by Check, like the GET request of polling URL of HTTP protocol
+	Poll(ctx context.Context, in *PollRequest, opts ...grpc.CallOption) (*PollResponse, error)
+}
+
+type podTransitionRuleWebhookClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPodTransitionRuleWebhookClient(cc grpc.ClientConnInterface) PodTransitionRuleWebhookClient {
+	return &podTransitionRuleWebhookClient{cc}
+}
+
+func (c *podTransitionRuleWebhookClient) Check(ctx context.Context, in *WebhookRequest, opts ...grpc.CallOption) (*WebhookResponse, error) {
+	out := new(WebhookResponse)
+	err := c.cc.Invoke(ctx, PodTransitionRuleWebhook_Check_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *podTransitionRuleWebhookClient) Poll(ctx context.Context, in *PollRequest, opts ...grpc.CallOption) (*PollResponse, error) {
+	out := new(PollResponse)
+	err := c.cc.Invoke(ctx, PodTransitionRuleWebhook_Poll_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PodTransitionRuleWebhookServer is the server API for PodTransitionRuleWebhook service.
+// All implementations must embed UnimplementedPodTransitionRuleWebhookServer
+// for forward compatibility
+type PodTransitionRuleWebhookServer interface {
+	// Check checks pods like the POST request of HTTP protocol
+	Check(context.Context, *WebhookRequest) (*WebhookResponse, error)
+	// Poll queries the task started by Check, like the GET request of polling URL of HTTP protocol
+	Poll(context.Context, *PollRequest) (*PollResponse, error)
+	mustEmbedUnimplementedPodTransitionRuleWebhookServer()
+}
+
+// UnimplementedPodTransitionRuleWebhookServer must be embedded to have forward compatible implementations.
+type UnimplementedPodTransitionRuleWebhookServer struct {
+}
+
+func (UnimplementedPodTransitionRuleWebhookServer) Check(context.Context, *WebhookRequest) (*WebhookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Check not implemented")
+}
+func (UnimplementedPodTransitionRuleWebhookServer) Poll(context.Context, *PollRequest) (*PollResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Poll not implemented")
+}
+func (UnimplementedPodTransitionRuleWebhookServer) mustEmbedUnimplementedPodTransitionRuleWebhookServer() {
+}
+
+// UnsafePodTransitionRuleWebhookServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PodTransitionRuleWebhookServer will
+// result in compilation errors.
+type UnsafePodTransitionRuleWebhookServer interface {
+	mustEmbedUnimplementedPodTransitionRuleWebhookServer()
+}
+
+func RegisterPodTransitionRuleWebhookServer(s grpc.ServiceRegistrar, srv PodTransitionRuleWebhookServer) {
+	s.RegisterService(&PodTransitionRuleWebhook_ServiceDesc, srv)
+}
+
+func _PodTransitionRuleWebhook_Check_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WebhookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PodTransitionRuleWebhookServer).Check(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PodTransitionRuleWebhook_Check_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PodTransitionRuleWebhookServer).Check(ctx, req.(*WebhookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PodTransitionRuleWebhook_Poll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PollRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PodTransitionRuleWebhookServer).Poll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PodTransitionRuleWebhook_Poll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PodTransitionRuleWebhookServer).Poll(ctx, req.(*PollRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PodTransitionRuleWebhook_ServiceDesc is the grpc.ServiceDesc for PodTransitionRuleWebhook service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PodTransitionRuleWebhook_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "kuperator.webhook.v1alpha1.PodTransitionRuleWebhook",
+	HandlerType: (*PodTransitionRuleWebhookServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Check",
+			Handler:    _PodTransitionRuleWebhook_Check_Handler,
+		},
+		{
+			MethodName: "Poll",
+			Handler:    _PodTransitionRuleWebhook_Poll_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "webhook.proto",
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// preview renders the pod of a CollaSet with the instance ID, with PodDecorations in cluster and candidate
+// PodDecorations not applied yet, and shows the diff against the running pod and how it would be updated.
+//
+//	preview -n default --collaset foo --instance-id 0 -f pd.yaml
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/pflag"
+	appsv1 "k8s.io/api/apps/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"kusionstack.io/kuperator/pkg/controllers/collaset/synccontrol"
+	utilspoddecoration "kusionstack.io/kuperator/pkg/controllers/utils/poddecoration"
+	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/strategy"
+)
+
+var scheme = clientgoscheme.Scheme
+
+// syncTimeout bounds the wait for PodDecorations to be observed by controller
+const syncTimeout = 30 * time.Second
+
+func init() {
+	utilruntime.Must(appsv1alpha1.AddToScheme(scheme))
+}
+
+func main() {
+	var (
+		namespace       string
+		collaSetName    string
+		collaSetFile    string
+		podDecorations  []string
+		instanceID      string
+		showRenderedPod bool
+	)
+	pflag.StringVarP(&namespace, "namespace", "n", "default", "The namespace of the CollaSet.")
+	pflag.StringVar(&collaSetName, "collaset", "", "The name of the CollaSet in cluster.")
+	pflag.StringVar(&collaSetFile, "collaset-file", "", "The YAML file of the CollaSet, whose template is rendered instead of the updated revision in cluster.")
+	pflag.StringArrayVarP(&podDecorations, "filename", "f", nil, "YAML files of candidate PodDecorations not applied yet, could be repeated.")
+	pflag.StringVar(&instanceID, "instance-id", "", "The instance ID of the pod to render.")
+	pflag.BoolVar(&showRenderedPod, "show-pod", true, "Print the rendered pod.")
+	pflag.Parse()
+
+	if err := run(namespace, collaSetName, collaSetFile, podDecorations, instanceID, showRenderedPod); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(namespace, collaSetName, collaSetFile string, podDecorationFiles []string, instanceID string, showRenderedPod bool) error {
+	if instanceID == "" {
+		return fmt.Errorf("--instance-id is required")
+	}
+	if (collaSetName == "") == (collaSetFile == "") {
+		return fmt.Errorf("one of --collaset and --collaset-file is required")
+	}
+	ctx := context.Background()
+	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	opts := &synccontrol.PreviewOptions{InstanceID: instanceID}
+	if collaSetFile != "" {
+		cls := &appsv1alpha1.CollaSet{}
+		if err = readObjects(collaSetFile, func(b []byte) error {
+			return yaml.Unmarshal(b, cls)
+		}); err != nil {
+			return err
+		}
+		if cls.Namespace == "" {
+			cls.Namespace = namespace
+		}
+		opts.CollaSet = cls
+	} else {
+		cls := &appsv1alpha1.CollaSet{}
+		if err = c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: collaSetName}, cls); err != nil {
+			return err
+		}
+		opts.CollaSet = cls
+		if cls.Status.UpdatedRevision != "" {
+			revision := &appsv1.ControllerRevision{}
+			if err = c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: cls.Status.UpdatedRevision}, revision); err != nil {
+				return err
+			}
+			opts.Revision = revision
+		}
+	}
+	for _, file := range podDecorationFiles {
+		if err = readObjects(file, func(b []byte) error {
+			pd := &appsv1alpha1.PodDecoration{}
+			if err := yaml.Unmarshal(b, pd); err != nil {
+				return err
+			}
+			opts.PodDecorations = append(opts.PodDecorations, pd)
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	// load PodDecorations of the namespace and their selected pods, like the controller does on start
+	if err = strategy.SharedStrategyController.InjectClient(c); err != nil {
+		return err
+	}
+	syncCtx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+	if err = strategy.SharedStrategyController.SyncNamespace(syncCtx, opts.CollaSet.Namespace); err != nil {
+		return err
+	}
+	getter, err := utilspoddecoration.NewPodDecorationGetter(c, opts.CollaSet.Namespace)
+	if err != nil {
+		return err
+	}
+	preview, err := synccontrol.PreviewPod(ctx, c, getter, opts)
+	if err != nil {
+		return err
+	}
+
+	if showRenderedPod {
+		b, err := yaml.Marshal(preview.Pod)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# rendered pod\n%s\n", b)
+	}
+	revisions := make([]string, 0, len(preview.PodDecorations))
+	for revision := range preview.PodDecorations {
+		revisions = append(revisions, revision)
+	}
+	sort.Strings(revisions)
+	for _, revision := range revisions {
+		fmt.Printf("# PodDecoration %s, revision %s\n", preview.PodDecorations[revision].Name, revision)
+	}
+	fmt.Printf("# update mode: %s\n", preview.UpdateMode)
+	fmt.Print(preview.Diff)
+	return nil
+}
+
+// readObjects calls fn with each document in the YAML file
+func readObjects(file string, fn func([]byte) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(f))
+	for {
+		b, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("fail to read %s: %w", file, err)
+		}
+		if len(bytes.TrimSpace(b)) == 0 {
+			continue
+		}
+		if err = fn(b); err != nil {
+			return fmt.Errorf("fail to parse %s: %w", file, err)
+		}
+	}
+}
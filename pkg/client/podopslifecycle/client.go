@@ -0,0 +1,65 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podopslifecycle
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"kusionstack.io/kuperator/pkg/controllers/utils/podopslifecycle"
+)
+
+var _ Interface = &lifecycleClient{}
+
+// New returns an Interface which updates pods through the client
+func New(c client.Client) Interface {
+	return &lifecycleClient{client: c}
+}
+
+type lifecycleClient struct {
+	client client.Client
+}
+
+func (l *lifecycleClient) Begin(adapter LifecycleAdapter, pod *corev1.Pod, updateFunc ...UpdateFunc) (bool, error) {
+	return podopslifecycle.Begin(l.client, adapter, pod, updateFunc...)
+}
+
+func (l *lifecycleClient) BeginWithCleaningOld(adapter LifecycleAdapter, pod *corev1.Pod, updateFunc ...UpdateFunc) (bool, error) {
+	return podopslifecycle.BeginWithCleaningOld(l.client, adapter, pod, updateFunc...)
+}
+
+func (l *lifecycleClient) AllowOps(adapter LifecycleAdapter, operationDelaySeconds int32, pod *corev1.Pod) (*time.Duration, bool) {
+	return podopslifecycle.AllowOps(adapter, operationDelaySeconds, pod)
+}
+
+func (l *lifecycleClient) Finish(adapter LifecycleAdapter, pod *corev1.Pod, updateFunc ...UpdateFunc) (bool, error) {
+	return podopslifecycle.Finish(l.client, adapter, pod, updateFunc...)
+}
+
+func (l *lifecycleClient) Undo(adapter LifecycleAdapter, pod *corev1.Pod) error {
+	return podopslifecycle.Undo(l.client, adapter, pod)
+}
+
+func (l *lifecycleClient) IsDuringOps(adapter LifecycleAdapter, pod *corev1.Pod) bool {
+	return podopslifecycle.IsDuringOps(adapter, pod)
+}
+
+func (l *lifecycleClient) GetState(pod *corev1.Pod, id string) (*LifecycleState, error) {
+	return GetLifecycleState(pod, id)
+}
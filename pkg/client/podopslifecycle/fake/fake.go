@@ -0,0 +1,224 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fake provides an in-memory podopslifecycle.Interface for unit tests of operators.
+// It keeps lifecycle states by pod key instead of pod labels, and the stages are moved forward
+// by the test through SetStage, which stands for the work of PodOpsLifecycle controller and webhook.
+package fake
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"kusionstack.io/kuperator/pkg/client/podopslifecycle"
+)
+
+var _ podopslifecycle.Interface = &Lifecycle{}
+
+// Lifecycle is an in-memory implementation of podopslifecycle.Interface
+type Lifecycle struct {
+	mu     sync.Mutex
+	states map[types.NamespacedName]map[string]*podopslifecycle.LifecycleState
+}
+
+// NewLifecycle returns an empty fake Lifecycle
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{
+		states: map[types.NamespacedName]map[string]*podopslifecycle.LifecycleState{},
+	}
+}
+
+func (l *Lifecycle) Begin(adapter podopslifecycle.LifecycleAdapter, pod *corev1.Pod, updateFunc ...podopslifecycle.UpdateFunc) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	podStates := l.podStates(pod)
+	needUpdate := false
+	if state, ok := podStates[adapter.GetID()]; ok && state.Operating {
+		if state.Type != adapter.GetType() {
+			return false, fmt.Errorf("operatingID %s already has operationType %s", adapter.GetID(), state.Type)
+		}
+	} else {
+		if !adapter.AllowMultiType() {
+			for id, state := range podStates {
+				if id != adapter.GetID() && state.Operating && state.Type == adapter.GetType() {
+					return false, fmt.Errorf("operationType %s exists: %v", adapter.GetType(), id)
+				}
+			}
+		}
+		podStates[adapter.GetID()] = newState(adapter.GetID(), adapter.GetType(), podopslifecycle.StagePreCheck)
+		needUpdate = true
+	}
+
+	updated, err := runUpdateFuncs(pod, append(updateFunc, adapter.WhenBegin)...)
+	if err != nil {
+		return false, err
+	}
+	return needUpdate || updated, nil
+}
+
+func (l *Lifecycle) BeginWithCleaningOld(adapter podopslifecycle.LifecycleAdapter, pod *corev1.Pod, updateFunc ...podopslifecycle.UpdateFunc) (bool, error) {
+	l.mu.Lock()
+	delete(l.podStates(pod), adapter.GetID())
+	l.mu.Unlock()
+	return l.Begin(adapter, pod, updateFunc...)
+}
+
+func (l *Lifecycle) AllowOps(adapter podopslifecycle.LifecycleAdapter, operationDelaySeconds int32, pod *corev1.Pod) (*time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.podStates(pod)[adapter.GetID()]
+	if !ok || !state.Operating || state.Type != adapter.GetType() {
+		return nil, false
+	}
+	if state.Stage != podopslifecycle.StageOperate {
+		return nil, false
+	}
+	if operationDelaySeconds <= 0 || state.StageTime == nil {
+		return nil, true
+	}
+	delay := time.Duration(operationDelaySeconds) * time.Second
+	if duration := time.Since(*state.StageTime); duration < delay {
+		du := delay - duration
+		return &du, true
+	}
+	return nil, true
+}
+
+func (l *Lifecycle) Finish(adapter podopslifecycle.LifecycleAdapter, pod *corev1.Pod, updateFunc ...podopslifecycle.UpdateFunc) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	needUpdate := false
+	if state, ok := l.podStates(pod)[adapter.GetID()]; ok {
+		if state.Type != adapter.GetType() {
+			return false, fmt.Errorf("operatingID %s has invalid operationType %s", adapter.GetID(), state.Type)
+		}
+		if state.Operating {
+			state.Operating = false
+			setStage(state, podopslifecycle.StageOperated)
+			needUpdate = true
+		}
+	}
+
+	updated, err := runUpdateFuncs(pod, append(updateFunc, adapter.WhenFinish)...)
+	if err != nil {
+		return false, err
+	}
+	return needUpdate || updated, nil
+}
+
+// Undo marks the lifecycle as undone and keeps it, as the real client does. It is cleaned up by BeginWithCleaningOld
+func (l *Lifecycle) Undo(adapter podopslifecycle.LifecycleAdapter, pod *corev1.Pod) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if state, ok := l.podStates(pod)[adapter.GetID()]; ok {
+		state.Undo = true
+	}
+	return nil
+}
+
+func (l *Lifecycle) IsDuringOps(adapter podopslifecycle.LifecycleAdapter, pod *corev1.Pod) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.podStates(pod)[adapter.GetID()]
+	return ok && state.Operating && state.Type == adapter.GetType()
+}
+
+func (l *Lifecycle) GetState(pod *corev1.Pod, id string) (*podopslifecycle.LifecycleState, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.podStates(pod)[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *state
+	return &copied, nil
+}
+
+// SetStage moves the lifecycle with the ID on the pod to the stage, as the PodOpsLifecycle controller and webhook do
+func (l *Lifecycle) SetStage(pod *corev1.Pod, id string, stage podopslifecycle.Stage) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.podStates(pod)[id]
+	if !ok {
+		return fmt.Errorf("lifecycle %s not found on pod %s/%s", id, pod.Namespace, pod.Name)
+	}
+	setStage(state, stage)
+	return nil
+}
+
+// Complete removes the finished lifecycle with the ID on the pod, as the PodOpsLifecycle webhook does
+func (l *Lifecycle) Complete(pod *corev1.Pod, id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.podStates(pod)[id]
+	if !ok {
+		return fmt.Errorf("lifecycle %s not found on pod %s/%s", id, pod.Namespace, pod.Name)
+	}
+	if state.Operating {
+		return fmt.Errorf("lifecycle %s on pod %s/%s is not finished", id, pod.Namespace, pod.Name)
+	}
+	delete(l.podStates(pod), id)
+	return nil
+}
+
+func (l *Lifecycle) podStates(pod *corev1.Pod) map[string]*podopslifecycle.LifecycleState {
+	key := types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}
+	podStates, ok := l.states[key]
+	if !ok {
+		podStates = map[string]*podopslifecycle.LifecycleState{}
+		l.states[key] = podStates
+	}
+	return podStates
+}
+
+func newState(id string, operationType podopslifecycle.OperationType, stage podopslifecycle.Stage) *podopslifecycle.LifecycleState {
+	state := &podopslifecycle.LifecycleState{
+		ID:        id,
+		Type:      operationType,
+		Operating: true,
+	}
+	setStage(state, stage)
+	return state
+}
+
+func setStage(state *podopslifecycle.LifecycleState, stage podopslifecycle.Stage) {
+	now := time.Now()
+	state.Stage = stage
+	state.StageTime = &now
+}
+
+func runUpdateFuncs(pod *corev1.Pod, updateFunc ...podopslifecycle.UpdateFunc) (updated bool, err error) {
+	for _, f := range updateFunc {
+		ok, updateErr := f(pod)
+		if updateErr != nil {
+			return updated, updateErr
+		}
+		updated = updated || ok
+	}
+	return updated, nil
+}
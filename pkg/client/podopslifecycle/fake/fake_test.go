@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"kusionstack.io/kuperator/pkg/client/podopslifecycle"
+)
+
+type mockAdapter struct {
+	id            string
+	operationType podopslifecycle.OperationType
+}
+
+func (m *mockAdapter) GetID() string                              { return m.id }
+func (m *mockAdapter) GetType() podopslifecycle.OperationType     { return m.operationType }
+func (m *mockAdapter) AllowMultiType() bool                       { return false }
+func (m *mockAdapter) WhenBegin(pod client.Object) (bool, error)  { return false, nil }
+func (m *mockAdapter) WhenFinish(pod client.Object) (bool, error) { return false, nil }
+
+func TestLifecycle(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	lc := NewLifecycle()
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-1"}}
+	a := &mockAdapter{id: "id-1", operationType: "type-1"}
+	b := &mockAdapter{id: "id-2", operationType: "type-1"}
+
+	updated, err := lc.Begin(a, pod)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(updated).Should(gomega.BeTrue())
+	g.Expect(lc.IsDuringOps(a, pod)).Should(gomega.BeTrue())
+
+	// begin again is idempotent
+	updated, err = lc.Begin(a, pod)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(updated).Should(gomega.BeFalse())
+
+	// another ID with the same type is not allowed
+	_, err = lc.Begin(b, pod)
+	g.Expect(err).Should(gomega.HaveOccurred())
+
+	_, allow := lc.AllowOps(a, 0, pod)
+	g.Expect(allow).Should(gomega.BeFalse())
+
+	g.Expect(lc.SetStage(pod, a.GetID(), podopslifecycle.StageOperate)).Should(gomega.Succeed())
+	_, allow = lc.AllowOps(a, 0, pod)
+	g.Expect(allow).Should(gomega.BeTrue())
+	requeueAfter, allow := lc.AllowOps(a, 60, pod)
+	g.Expect(allow).Should(gomega.BeTrue())
+	g.Expect(requeueAfter).ShouldNot(gomega.BeNil())
+
+	g.Expect(lc.Complete(pod, a.GetID())).Should(gomega.HaveOccurred())
+	updated, err = lc.Finish(a, pod)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(updated).Should(gomega.BeTrue())
+	g.Expect(lc.IsDuringOps(a, pod)).Should(gomega.BeFalse())
+
+	state, err := lc.GetState(pod, a.GetID())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(state.Stage).Should(gomega.Equal(podopslifecycle.StageOperated))
+
+	g.Expect(lc.Complete(pod, a.GetID())).Should(gomega.Succeed())
+	state, err = lc.GetState(pod, a.GetID())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(state).Should(gomega.BeNil())
+
+	_, err = lc.Begin(b, pod)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(lc.Undo(b, pod)).Should(gomega.Succeed())
+	state, err = lc.GetState(pod, b.GetID())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(state.Undo).Should(gomega.BeTrue())
+
+	// the undone lifecycle is cleaned up when beginning again
+	_, err = lc.BeginWithCleaningOld(b, pod)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	state, err = lc.GetState(pod, b.GetID())
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(state.Undo).Should(gomega.BeFalse())
+	g.Expect(lc.IsDuringOps(b, pod)).Should(gomega.BeTrue())
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package podopslifecycle is the stable entry for third-party operators to drive PodOpsLifecycle.
+// Operators implement LifecycleAdapter for their own operation type, and use Interface to begin,
+// check and finish a lifecycle on pods. The fake subpackage provides an in-memory Interface for unit tests.
+package podopslifecycle
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"kusionstack.io/kuperator/pkg/controllers/utils/podopslifecycle"
+)
+
+type (
+	// OperationType indicates the type of operation an operator does during a lifecycle
+	OperationType = podopslifecycle.OperationType
+
+	// LifecycleAdapter helps CRD Operators to easily access PodOpsLifecycle
+	LifecycleAdapter = podopslifecycle.LifecycleAdapter
+
+	// UpdateFunc is used to update the pod in the same request which begins or finishes a lifecycle
+	UpdateFunc = podopslifecycle.UpdateFunc
+)
+
+var (
+	OpsLifecycleTypeUpdate  = podopslifecycle.OpsLifecycleTypeUpdate
+	OpsLifecycleTypeScaleIn = podopslifecycle.OpsLifecycleTypeScaleIn
+	OpsLifecycleTypeDelete  = podopslifecycle.OpsLifecycleTypeDelete
+)
+
+// Interface drives PodOpsLifecycle on pods for one operator
+type Interface interface {
+	// Begin begins a lifecycle on the pod, it is safe to be called repeatedly
+	Begin(adapter LifecycleAdapter, pod *corev1.Pod, updateFunc ...UpdateFunc) (updated bool, err error)
+
+	// BeginWithCleaningOld undoes the existing lifecycle with the same ID before beginning a new one
+	BeginWithCleaningOld(adapter LifecycleAdapter, pod *corev1.Pod, updateFunc ...UpdateFunc) (updated bool, err error)
+
+	// AllowOps checks whether the pod is permitted to be operated. If operationDelaySeconds is set,
+	// requeueAfter indicates how long to wait before the operation is allowed.
+	AllowOps(adapter LifecycleAdapter, operationDelaySeconds int32, pod *corev1.Pod) (requeueAfter *time.Duration, allow bool)
+
+	// Finish finishes the lifecycle on the pod after the operation is done
+	Finish(adapter LifecycleAdapter, pod *corev1.Pod, updateFunc ...UpdateFunc) (updated bool, err error)
+
+	// Undo cancels the lifecycle on the pod
+	Undo(adapter LifecycleAdapter, pod *corev1.Pod) error
+
+	// IsDuringOps indicates whether the pod is in the lifecycle of the adapter and not finished yet
+	IsDuringOps(adapter LifecycleAdapter, pod *corev1.Pod) bool
+
+	// GetState returns the current state of the lifecycle with the ID on the pod, nil if it does not exist
+	GetState(pod *corev1.Pod, id string) (*LifecycleState, error)
+}
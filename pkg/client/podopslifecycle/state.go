@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podopslifecycle
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"kusionstack.io/kube-api/apps/v1alpha1"
+
+	controllerspodopslifecycle "kusionstack.io/kuperator/pkg/controllers/podopslifecycle"
+)
+
+// Stage is the stage of one lifecycle on a pod
+type Stage = controllerspodopslifecycle.Stage
+
+const (
+	StageBegin       = controllerspodopslifecycle.StageBegin
+	StagePreCheck    = controllerspodopslifecycle.StagePreCheck
+	StagePreChecked  = controllerspodopslifecycle.StagePreChecked
+	StagePreparing   = controllerspodopslifecycle.StagePreparing
+	StageOperate     = controllerspodopslifecycle.StageOperate
+	StageOperated    = controllerspodopslifecycle.StageOperated
+	StagePostCheck   = controllerspodopslifecycle.StagePostCheck
+	StagePostChecked = controllerspodopslifecycle.StagePostChecked
+	StageCompleting  = controllerspodopslifecycle.StageCompleting
+)
+
+// LifecycleState is the typed view of the labels of one lifecycle on a pod
+type LifecycleState struct {
+	// ID of the lifecycle
+	ID string
+	// Type is the operation type of the lifecycle, it is kept after the operation is done
+	Type OperationType
+	// Stage is the current stage of the lifecycle
+	Stage Stage
+	// StageTime is the time entering current stage, nil if unknown
+	StageTime *time.Time
+	// Operating indicates the lifecycle is not finished by its operator yet
+	Operating bool
+	// Undo indicates the lifecycle is canceled and waiting for cleaning up
+	Undo bool
+}
+
+// GetLifecycleStates returns states of all lifecycles on the pod, ordered by ID
+func GetLifecycleStates(pod *corev1.Pod) ([]*LifecycleState, error) {
+	if pod == nil {
+		return nil, nil
+	}
+	idToLabelsMap, _, err := controllerspodopslifecycle.IDToLabelsMap(pod)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]*LifecycleState, 0, len(idToLabelsMap))
+	for id, labels := range idToLabelsMap {
+		states = append(states, newLifecycleState(id, labels))
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].ID < states[j].ID
+	})
+	return states, nil
+}
+
+// GetLifecycleState returns state of the lifecycle with the ID on the pod, nil if it does not exist
+func GetLifecycleState(pod *corev1.Pod, id string) (*LifecycleState, error) {
+	if pod == nil {
+		return nil, nil
+	}
+	idToLabelsMap, _, err := controllerspodopslifecycle.IDToLabelsMap(pod)
+	if err != nil {
+		return nil, err
+	}
+	labels, ok := idToLabelsMap[id]
+	if !ok {
+		return nil, nil
+	}
+	return newLifecycleState(id, labels), nil
+}
+
+func newLifecycleState(id string, labels map[string]string) *LifecycleState {
+	state := &LifecycleState{ID: id}
+	if t, ok := labels[v1alpha1.PodOperationTypeLabelPrefix]; ok {
+		state.Type = OperationType(t)
+	} else if t, ok := labels[v1alpha1.PodDoneOperationTypeLabelPrefix]; ok {
+		state.Type = OperationType(t)
+	}
+	_, state.Operating = labels[v1alpha1.PodOperatingLabelPrefix]
+	_, state.Undo = labels[v1alpha1.PodUndoOperationTypeLabelPrefix]
+
+	stage, val := controllerspodopslifecycle.CurrentStage(labels)
+	state.Stage = stage
+	if nano, err := strconv.ParseInt(val, 10, 64); err == nil {
+		tm := time.Unix(0, nano)
+		state.StageTime = &tm
+	}
+	return state
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podopslifecycle
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"kusionstack.io/kube-api/apps/v1alpha1"
+)
+
+func TestGetLifecycleStates(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "pod-1",
+			Labels: map[string]string{
+				fmt.Sprintf("%s/%s", v1alpha1.PodOperatingLabelPrefix, "id-1"):         "1704865098763959176",
+				fmt.Sprintf("%s/%s", v1alpha1.PodOperationTypeLabelPrefix, "id-1"):     "update",
+				fmt.Sprintf("%s/%s", v1alpha1.PodPreCheckedLabelPrefix, "id-1"):        "1704865098763959176",
+				fmt.Sprintf("%s/%s", v1alpha1.PodOperateLabelPrefix, "id-1"):           "1704865098763959176",
+				fmt.Sprintf("%s/%s", v1alpha1.PodOperatedLabelPrefix, "id-2"):          "1704865098763959176",
+				fmt.Sprintf("%s/%s", v1alpha1.PodDoneOperationTypeLabelPrefix, "id-2"): "scale-in",
+				fmt.Sprintf("%s/%s", v1alpha1.PodPostCheckLabelPrefix, "id-2"):         "invalid-time",
+			},
+		},
+	}
+
+	states, err := GetLifecycleStates(pod)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(states).Should(gomega.HaveLen(2))
+
+	g.Expect(states[0].ID).Should(gomega.Equal("id-1"))
+	g.Expect(states[0].Type).Should(gomega.Equal(OpsLifecycleTypeUpdate))
+	g.Expect(states[0].Stage).Should(gomega.Equal(StageOperate))
+	g.Expect(states[0].Operating).Should(gomega.BeTrue())
+	g.Expect(states[0].StageTime).ShouldNot(gomega.BeNil())
+	g.Expect(states[0].StageTime.UnixNano()).Should(gomega.Equal(int64(1704865098763959176)))
+
+	g.Expect(states[1].ID).Should(gomega.Equal("id-2"))
+	g.Expect(states[1].Type).Should(gomega.Equal(OpsLifecycleTypeScaleIn))
+	g.Expect(states[1].Stage).Should(gomega.Equal(StagePostCheck))
+	g.Expect(states[1].Operating).Should(gomega.BeFalse())
+	g.Expect(states[1].StageTime).Should(gomega.BeNil())
+
+	state, err := GetLifecycleState(pod, "id-3")
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(state).Should(gomega.BeNil())
+
+	g.Expect(StagePreCheck.Before(StageOperate)).Should(gomega.BeTrue())
+	g.Expect(StageCompleting.Before(StageOperate)).Should(gomega.BeFalse())
+}
+
+func TestStageChanged(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	oldPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				fmt.Sprintf("%s/%s", v1alpha1.PodOperatingLabelPrefix, "id-1"):     "1704865098763959176",
+				fmt.Sprintf("%s/%s", v1alpha1.PodOperationTypeLabelPrefix, "id-1"): "update",
+				fmt.Sprintf("%s/%s", v1alpha1.PodPreCheckLabelPrefix, "id-1"):      "1704865098763959176",
+			},
+		},
+	}
+	newPod := oldPod.DeepCopy()
+	newPod.Labels["foo"] = "bar"
+	g.Expect(StageChanged()(oldPod, newPod)).Should(gomega.BeFalse())
+
+	newPod.Labels[fmt.Sprintf("%s/%s", v1alpha1.PodPreCheckedLabelPrefix, "id-1")] = "1704865098763959177"
+	g.Expect(StageChanged()(oldPod, newPod)).Should(gomega.BeTrue())
+	g.Expect(StageChanged("id-1")(oldPod, newPod)).Should(gomega.BeTrue())
+	g.Expect(StageChanged("id-2")(oldPod, newPod)).Should(gomega.BeFalse())
+	g.Expect(StageChanged("id-1")(nil, newPod)).Should(gomega.BeTrue())
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podopslifecycle
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	controllerspodopslifecycle "kusionstack.io/kuperator/pkg/controllers/podopslifecycle"
+)
+
+// StageChanged returns a function reporting whether the stage of any lifecycle with the given IDs is changed
+// between the old and new pod. All lifecycles are concerned if no ID is given.
+func StageChanged(ids ...string) controllerspodopslifecycle.NeedOpsLifecycle {
+	concerned := sets.NewString(ids...)
+	return func(oldPod, newPod *corev1.Pod) bool {
+		oldStages := stagesByID(oldPod, concerned)
+		newStages := stagesByID(newPod, concerned)
+		if len(oldStages) != len(newStages) {
+			return true
+		}
+		for id, state := range newStages {
+			old, ok := oldStages[id]
+			if !ok || old.Stage != state.Stage || old.Operating != state.Operating || old.Undo != state.Undo {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// StageChangedPredicate filters pod events to the ones changing stages of lifecycles with the given IDs,
+// so that operators can watch pods and reconcile only when PodOpsLifecycle moves forward.
+func StageChangedPredicate(ids ...string) predicate.Predicate {
+	return &controllerspodopslifecycle.PodPredicate{
+		NeedOpsLifecycle: StageChanged(ids...),
+	}
+}
+
+func stagesByID(pod *corev1.Pod, concerned sets.String) map[string]*LifecycleState {
+	res := map[string]*LifecycleState{}
+	states, err := GetLifecycleStates(pod)
+	if err != nil {
+		return res
+	}
+	for i := range states {
+		if concerned.Len() > 0 && !concerned.Has(states[i].ID) {
+			continue
+		}
+		res[states[i].ID] = states[i]
+	}
+	return res
+}
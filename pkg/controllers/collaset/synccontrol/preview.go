@@ -0,0 +1,265 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pmezard/go-difflib/difflib"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	collasetutils "kusionstack.io/kuperator/pkg/controllers/collaset/utils"
+	utilspoddecoration "kusionstack.io/kuperator/pkg/controllers/utils/poddecoration"
+)
+
+type PreviewUpdateMode string
+
+const (
+	// PreviewUnchanged means the running pod is already up to date
+	PreviewUnchanged PreviewUpdateMode = "Unchanged"
+	// PreviewCreate means no pod is running with the instance ID, and the rendered pod is created
+	PreviewCreate PreviewUpdateMode = "Create"
+	// PreviewInPlace means the running pod is updated in-place
+	PreviewInPlace PreviewUpdateMode = "InPlace"
+	// PreviewRecreate means the running pod is deleted and created again
+	PreviewRecreate PreviewUpdateMode = "Recreate"
+	// PreviewReplace means a new pod is created to replace the running pod
+	PreviewReplace PreviewUpdateMode = "Replace"
+)
+
+// previewRevisionSuffix is the suffix of revisions of candidate PodDecorations, which are not applied yet
+const previewRevisionSuffix = "-preview"
+
+type PreviewOptions struct {
+	// CollaSet to render pods of
+	CollaSet *appsv1alpha1.CollaSet
+	// Revision of CollaSet to render, it is built from the template of CollaSet if nil
+	Revision *appsv1.ControllerRevision
+	// PodDecorations are candidate PodDecorations not applied yet. They override PodDecorations with the
+	// same name, and are taken as fully rolled out.
+	PodDecorations []*appsv1alpha1.PodDecoration
+	// InstanceID of the pod to render
+	InstanceID string
+}
+
+// PodPreview is the pod rendered from the revision of CollaSet and the effective PodDecorations
+type PodPreview struct {
+	// Pod is the rendered pod
+	Pod *corev1.Pod
+	// Running is the running pod with the instance ID, nil if not found
+	Running *corev1.Pod
+	// PodDecorations patched on the rendered pod, keyed by revision
+	PodDecorations map[string]*appsv1alpha1.PodDecoration
+	// UpdateMode is how the running pod is updated to the rendered pod
+	UpdateMode PreviewUpdateMode
+	// Diff is the unified diff of the running pod and the pod updated with changes of the rendered pod
+	Diff string
+}
+
+// PreviewPod renders the pod of CollaSet with the instance ID like NewPodFrom in scaling and updating, and
+// compares it with the running pod
+func PreviewPod(ctx context.Context, c client.Client, getter utilspoddecoration.Getter, opts *PreviewOptions) (*PodPreview, error) {
+	cls := opts.CollaSet
+	revision := opts.Revision
+	if revision == nil {
+		var err error
+		if revision, err = newPreviewRevision(cls); err != nil {
+			return nil, err
+		}
+	}
+	running, err := getPodByInstanceID(ctx, c, cls, opts.InstanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerRef := metav1.NewControllerRef(cls, appsv1alpha1.SchemeGroupVersion.WithKind("CollaSet"))
+	preview := &PodPreview{Running: running}
+	preview.Pod, err = collasetutils.NewPodFrom(cls, ownerRef, revision, func(in *corev1.Pod) error {
+		in.Labels[appsv1alpha1.PodInstanceIDLabelKey] = opts.InstanceID
+		target := in
+		if running != nil {
+			in.Name = running.Name
+			target = running
+		}
+		pds, localErr := getter.GetEffective(ctx, target)
+		if localErr != nil {
+			return localErr
+		}
+		preview.PodDecorations = withCandidates(pds, opts.PodDecorations, in)
+		return utilspoddecoration.PatchListOfDecorations(in, preview.PodDecorations)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fail to render pod from revision %s: %w", revision.Name, err)
+	}
+	if running == nil {
+		preview.UpdateMode = PreviewCreate
+		preview.Diff, err = diffPodYaml(nil, preview.Pod)
+		return preview, err
+	}
+
+	// build the pod of running revisions, to find changes of the rendered pod as updating does
+	currentRevision := &appsv1.ControllerRevision{}
+	if err = c.Get(ctx, types.NamespacedName{Namespace: cls.Namespace, Name: running.Labels[appsv1.ControllerRevisionHashLabelKey]}, currentRevision); err != nil {
+		return nil, fmt.Errorf("fail to get revision of pod %s: %w", running.Name, err)
+	}
+	currentPDs, err := getter.GetOnPod(ctx, running)
+	if err != nil {
+		return nil, err
+	}
+	currentPod, err := collasetutils.NewPodFrom(cls, ownerRef, currentRevision, withPodIdentity(running), func(in *corev1.Pod) error {
+		return utilspoddecoration.PatchListOfDecorations(in, currentPDs)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fail to build pod from revision %s: %w", currentRevision.Name, err)
+	}
+
+	preview.UpdateMode = previewUpdateMode(cls, currentPod, preview.Pod)
+	if preview.UpdateMode != PreviewUnchanged && currentRevision.Name == revision.Name {
+		// images of sidecar containers are updated in-place whatever the PodUpdatePolicy is
+		if _, ok := utilspoddecoration.SidecarImageChanges(running, currentPDs, preview.PodDecorations); ok {
+			preview.UpdateMode = PreviewInPlace
+		}
+	}
+	updated, err := collasetutils.PatchToPod(currentPod, preview.Pod, running)
+	if err != nil {
+		return nil, err
+	}
+	preview.Diff, err = diffPodYaml(running, updated)
+	return preview, err
+}
+
+// withCandidates replaces effective PodDecorations with the candidates of the same name, and adds the
+// candidates selecting the pod
+func withCandidates(pds map[string]*appsv1alpha1.PodDecoration, candidates []*appsv1alpha1.PodDecoration, pod *corev1.Pod) map[string]*appsv1alpha1.PodDecoration {
+	if len(candidates) == 0 {
+		return pds
+	}
+	res := map[string]*appsv1alpha1.PodDecoration{}
+	names := map[string]bool{}
+	for _, pd := range candidates {
+		names[pd.Name] = true
+	}
+	for revision, pd := range pds {
+		if !names[pd.Name] {
+			res[revision] = pd
+		}
+	}
+	for _, pd := range candidates {
+		selector, err := metav1.LabelSelectorAsSelector(pd.Spec.Selector)
+		if err != nil || pd.Spec.Selector == nil || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		res[pd.Name+previewRevisionSuffix] = pd
+	}
+	return res
+}
+
+func previewUpdateMode(cls *appsv1alpha1.CollaSet, currentPod, updatedPod *corev1.Pod) PreviewUpdateMode {
+	if equality.Semantic.DeepEqual(currentPod, updatedPod) {
+		return PreviewUnchanged
+	}
+	switch cls.Spec.UpdateStrategy.PodUpdatePolicy {
+	case appsv1alpha1.CollaSetRecreatePodUpdateStrategyType:
+		return PreviewRecreate
+	case appsv1alpha1.CollaSetReplacePodUpdateStrategyType:
+		return PreviewReplace
+	}
+	if inPlace, _, _ := (&inPlaceIfPossibleUpdater{}).diffPod(currentPod, updatedPod); inPlace {
+		return PreviewInPlace
+	}
+	return PreviewRecreate
+}
+
+func getPodByInstanceID(ctx context.Context, c client.Client, cls *appsv1alpha1.CollaSet, instanceID string) (*corev1.Pod, error) {
+	podList := &corev1.PodList{}
+	if err := c.List(ctx, podList, client.InNamespace(cls.Namespace), client.MatchingLabels{appsv1alpha1.PodInstanceIDLabelKey: instanceID}); err != nil {
+		return nil, err
+	}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "CollaSet" && owner.Name == cls.Name {
+			return pod, nil
+		}
+	}
+	return nil, nil
+}
+
+// newPreviewRevision builds the revision from the template of CollaSet, which may be not applied yet
+func newPreviewRevision(cls *appsv1alpha1.CollaSet) (*appsv1.ControllerRevision, error) {
+	template, err := json.Marshal(cls.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"template": json.RawMessage(template)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	name := cls.Status.UpdatedRevision
+	if name == "" {
+		name = cls.Name + previewRevisionSuffix
+	}
+	return &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: cls.Namespace},
+		Data:       runtime.RawExtension{Raw: patch},
+	}, nil
+}
+
+// diffPodYaml returns the unified diff of pods in YAML, status and managed fields are not compared
+func diffPodYaml(from, to *corev1.Pod) (string, error) {
+	fromYaml, err := podYaml(from)
+	if err != nil {
+		return "", err
+	}
+	toYaml, err := podYaml(to)
+	if err != nil {
+		return "", err
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(fromYaml),
+		B:        difflib.SplitLines(toYaml),
+		FromFile: "running",
+		ToFile:   "rendered",
+		Context:  3,
+	})
+}
+
+func podYaml(pod *corev1.Pod) (string, error) {
+	if pod == nil {
+		return "", nil
+	}
+	pod = pod.DeepCopy()
+	pod.Status = corev1.PodStatus{}
+	pod.ManagedFields = nil
+	b, err := yaml.Marshal(pod)
+	return string(b), err
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	collasetutils "kusionstack.io/kuperator/pkg/controllers/collaset/utils"
+)
+
+type fakeGetter map[string]*appsv1alpha1.PodDecoration
+
+func (g fakeGetter) GetOnPod(context.Context, *corev1.Pod) (map[string]*appsv1alpha1.PodDecoration, error) {
+	return g, nil
+}
+
+func (g fakeGetter) GetEffective(context.Context, *corev1.Pod) (map[string]*appsv1alpha1.PodDecoration, error) {
+	return g, nil
+}
+
+func (g fakeGetter) GetByRevisions(context.Context, ...string) (map[string]*appsv1alpha1.PodDecoration, error) {
+	return g, nil
+}
+
+func TestPreviewPod(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).Should(gomega.Succeed())
+	g.Expect(appsv1alpha1.AddToScheme(scheme)).Should(gomega.Succeed())
+
+	cls := &appsv1alpha1.CollaSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo", UID: "uid"},
+		Spec: appsv1alpha1.CollaSetSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "foo"}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "main", Image: "nginx:v1"}},
+				},
+			},
+		},
+	}
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	// no running pod
+	preview, err := PreviewPod(ctx, c, fakeGetter{}, &PreviewOptions{CollaSet: cls, InstanceID: "0"})
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(preview.UpdateMode).Should(gomega.Equal(PreviewCreate))
+	g.Expect(preview.Pod.Labels[appsv1alpha1.PodInstanceIDLabelKey]).Should(gomega.Equal("0"))
+	g.Expect(preview.Diff).Should(gomega.ContainSubstring("+  - image: nginx:v1"))
+
+	revision, err := newPreviewRevision(cls)
+	g.Expect(err).Should(gomega.BeNil())
+	running := preview.Pod.DeepCopy()
+	running.Name = "foo-0"
+	g.Expect(c.Create(ctx, revision)).Should(gomega.Succeed())
+	g.Expect(c.Create(ctx, running)).Should(gomega.Succeed())
+
+	preview, err = PreviewPod(ctx, c, fakeGetter{}, &PreviewOptions{CollaSet: cls, InstanceID: "0"})
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(preview.Running).ShouldNot(gomega.BeNil())
+	g.Expect(preview.UpdateMode).Should(gomega.Equal(PreviewUnchanged))
+	g.Expect(preview.Diff).Should(gomega.BeEmpty())
+
+	// candidate PodDecoration changing image only
+	main, image := "main", "nginx:v2"
+	pd := &appsv1alpha1.PodDecoration{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "image"},
+		Spec: appsv1alpha1.PodDecorationSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+			Template: appsv1alpha1.PodDecorationPodTemplate{
+				PrimaryContainers: []*appsv1alpha1.PrimaryContainerPatch{
+					{
+						TargetPolicy:                  appsv1alpha1.InjectByName,
+						PodDecorationPrimaryContainer: appsv1alpha1.PodDecorationPrimaryContainer{Name: &main, Image: &image},
+					},
+				},
+			},
+		},
+	}
+	preview, err = PreviewPod(ctx, c, fakeGetter{}, &PreviewOptions{CollaSet: cls, InstanceID: "0", PodDecorations: []*appsv1alpha1.PodDecoration{pd}})
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(preview.PodDecorations).Should(gomega.HaveKey("image" + previewRevisionSuffix))
+	g.Expect(preview.UpdateMode).Should(gomega.Equal(PreviewInPlace))
+	g.Expect(preview.Diff).Should(gomega.ContainSubstring("-  - image: nginx:v1"))
+	g.Expect(preview.Diff).Should(gomega.ContainSubstring("+  - image: nginx:v2"))
+
+	// candidate PodDecoration adding a container
+	pd.Spec.Template.Containers = []*appsv1alpha1.ContainerPatch{{Container: corev1.Container{Name: "sidecar", Image: "proxy"}}}
+	preview, err = PreviewPod(ctx, c, fakeGetter{}, &PreviewOptions{CollaSet: cls, InstanceID: "0", PodDecorations: []*appsv1alpha1.PodDecoration{pd}})
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(preview.UpdateMode).Should(gomega.Equal(PreviewRecreate))
+
+	// pod of another instance is not selected
+	preview, err = PreviewPod(ctx, c, fakeGetter{}, &PreviewOptions{CollaSet: cls, InstanceID: "1"})
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(preview.UpdateMode).Should(gomega.Equal(PreviewCreate))
+	g.Expect(preview.Pod.GenerateName).Should(gomega.Equal(collasetutils.GetPodsPrefix(cls.Name)))
+}
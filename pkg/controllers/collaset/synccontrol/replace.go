@@ -144,9 +144,7 @@ func (r *RealSyncControl) replaceOriginPods(
 			return err
 		}
 		// create pod using update revision if replaced by update, otherwise using current revision
-		newPod, err := collasetutils.NewPodFrom(instance, ownerRef, replaceRevision, func(in *corev1.Pod) error {
-			return utilspoddecoration.PatchListOfDecorations(in, updatedPDs)
-		})
+		newPod, err := collasetutils.NewPodFrom(instance, ownerRef, replaceRevision)
 		if err != nil {
 			return err
 		}
@@ -173,6 +171,10 @@ func (r *RealSyncControl) replaceOriginPods(
 			ownedIDs[newPodId].Put(ReplaceOriginPodIDContextDataKey, strconv.Itoa(originPodId))
 			ownedIDs[newPodId].Remove(podcontext.JustCreateContextDataKey)
 		}
+		// decorate after instance id is allocated, which may be referenced by variables of PodDecorations
+		if err = utilspoddecoration.PatchListOfDecorations(newPod, updatedPDs); err != nil {
+			return err
+		}
 		newPod.Labels[appsv1alpha1.PodReplacePairOriginName] = originPod.GetName()
 		newPod.Labels[appsv1alpha1.PodCreatingLabel] = strconv.FormatInt(time.Now().UnixNano(), 10)
 		newPodContext.Put(podcontext.RevisionContextDataKey, replaceRevision.Name)
@@ -67,11 +67,15 @@ type PodUpdateInfo struct {
 
 	// indicates effected PodDecorations changed
 	PodDecorationChanged bool
+	// indicates only images of sidecar containers from PodDecorations changed, which are always updated in-place
+	OnlyDecorationImageChanged bool
 	// indicate if the pvc template changed
 	PvcTmpHashChanged bool
 
 	CurrentPodDecorations map[string]*appsv1alpha1.PodDecoration
 	UpdatedPodDecorations map[string]*appsv1alpha1.PodDecoration
+	// updated images of sidecar containers, keyed by container name
+	decorationImages map[string]string
 
 	// indicates the Pod is during UpdateOpsLifecycle
 	isDuringUpdateOps bool
@@ -169,6 +173,9 @@ func (r *RealSyncControl) attachPodUpdateInfo(ctx context.Context, cls *appsv1al
 		if err != nil {
 			return nil, fmt.Errorf("fail to check pvc template changed, %w", err)
 		}
+		if updateInfo.IsUpdatedRevision && updateInfo.PodDecorationChanged && !updateInfo.PvcTmpHashChanged {
+			updateInfo.decorationImages, updateInfo.OnlyDecorationImageChanged = utilspoddecoration.SidecarImageChanges(pod.Pod, currentPDs, updatedPDs)
+		}
 		podUpdateInfoList[i] = updateInfo
 	}
 
@@ -455,7 +462,7 @@ func (u *GenericPodUpdater) FilterAllowOpsPods(_ context.Context, candidates []*
 
 		// mark podContext "PodRecreateUpgrade" if upgrade by recreate
 		isRecreateUpdatePolicy := u.CollaSet.Spec.UpdateStrategy.PodUpdatePolicy == appsv1alpha1.CollaSetRecreatePodUpdateStrategyType
-		if (!podInfo.OnlyMetadataChanged && !podInfo.InPlaceUpdateSupport) || isRecreateUpdatePolicy {
+		if !podInfo.OnlyDecorationImageChanged && ((!podInfo.OnlyMetadataChanged && !podInfo.InPlaceUpdateSupport) || isRecreateUpdatePolicy) {
 			ownedIDs[podInfo.ID].Put(podcontext.RecreateUpdateContextDataKey, "true")
 		}
 
@@ -531,10 +538,106 @@ func newPodUpdater(client client.Client, cls *appsv1alpha1.CollaSet, podControl
 	default:
 		podUpdater = &inPlaceIfPossibleUpdater{}
 	}
+	podUpdater = &decorationImageUpdater{PodUpdater: podUpdater, inPlace: &inPlaceIfPossibleUpdater{}}
 	podUpdater.Setup(client, cls, podControl, recorder)
 	return podUpdater
 }
 
+// decorationImageUpdater updates Pods, whose only changes are images of sidecar containers from PodDecorations,
+// in-place through the update PodOpsLifecycle whatever the PodUpdatePolicy is, and leaves other Pods to the updater
+// of PodUpdatePolicy. So that upgrading sidecars, like the proxy of service mesh, never recreates Pods.
+type decorationImageUpdater struct {
+	PodUpdater
+	inPlace *inPlaceIfPossibleUpdater
+}
+
+func (u *decorationImageUpdater) Setup(client client.Client, cls *appsv1alpha1.CollaSet, podControl podcontrol.Interface, recorder record.EventRecorder) {
+	u.PodUpdater.Setup(client, cls, podControl, recorder)
+	u.inPlace.Setup(client, cls, podControl, recorder)
+}
+
+func (u *decorationImageUpdater) FulfillPodUpdatedInfo(ctx context.Context, revision *appsv1.ControllerRevision, podUpdateInfo *PodUpdateInfo) error {
+	if podUpdateInfo.OnlyDecorationImageChanged {
+		return u.inPlace.fulfillDecorationImageUpdatedInfo(podUpdateInfo)
+	}
+	return u.PodUpdater.FulfillPodUpdatedInfo(ctx, revision, podUpdateInfo)
+}
+
+func (u *decorationImageUpdater) BeginUpdatePod(ctx context.Context, resources *collasetutils.RelatedResources, podCh chan *PodUpdateInfo) (bool, error) {
+	decorationCh := make(chan *PodUpdateInfo, len(podCh))
+	otherCh := make(chan *PodUpdateInfo, len(podCh))
+	for len(podCh) > 0 {
+		podInfo := <-podCh
+		if podInfo.OnlyDecorationImageChanged {
+			decorationCh <- podInfo
+		} else {
+			otherCh <- podInfo
+		}
+	}
+
+	updating, err := u.inPlace.BeginUpdatePod(ctx, resources, decorationCh)
+	if err != nil {
+		return updating, err
+	}
+	otherUpdating, err := u.PodUpdater.BeginUpdatePod(ctx, resources, otherCh)
+	return updating || otherUpdating, err
+}
+
+func (u *decorationImageUpdater) FilterAllowOpsPods(ctx context.Context, candidates []*PodUpdateInfo, ownedIDs map[int]*appsv1alpha1.ContextDetail, resources *collasetutils.RelatedResources, podCh chan *PodUpdateInfo) (*time.Duration, error) {
+	var decorationCandidates, otherCandidates []*PodUpdateInfo
+	for i := range candidates {
+		if candidates[i].OnlyDecorationImageChanged {
+			decorationCandidates = append(decorationCandidates, candidates[i])
+		} else {
+			otherCandidates = append(otherCandidates, candidates[i])
+		}
+	}
+
+	requeueAfter, err := u.inPlace.FilterAllowOpsPods(ctx, decorationCandidates, ownedIDs, resources, podCh)
+	if err != nil {
+		return requeueAfter, err
+	}
+	otherRequeueAfter, err := u.PodUpdater.FilterAllowOpsPods(ctx, otherCandidates, ownedIDs, resources, podCh)
+	if requeueAfter == nil || (otherRequeueAfter != nil && *otherRequeueAfter < *requeueAfter) {
+		requeueAfter = otherRequeueAfter
+	}
+	return requeueAfter, err
+}
+
+func (u *decorationImageUpdater) UpgradePod(ctx context.Context, podInfo *PodUpdateInfo) error {
+	if podInfo.OnlyDecorationImageChanged {
+		return u.inPlace.UpgradePod(ctx, podInfo)
+	}
+	return u.PodUpdater.UpgradePod(ctx, podInfo)
+}
+
+func (u *decorationImageUpdater) GetPodUpdateFinishStatus(ctx context.Context, podUpdateInfo *PodUpdateInfo) (bool, string, error) {
+	if isDecorationImageUpdating(podUpdateInfo) {
+		return u.inPlace.GetPodUpdateFinishStatus(ctx, podUpdateInfo)
+	}
+	return u.PodUpdater.GetPodUpdateFinishStatus(ctx, podUpdateInfo)
+}
+
+func (u *decorationImageUpdater) FinishUpdatePod(ctx context.Context, podInfo *PodUpdateInfo, finishByCancelUpdate bool) error {
+	if isDecorationImageUpdating(podInfo) {
+		return u.inPlace.FinishUpdatePod(ctx, podInfo, finishByCancelUpdate)
+	}
+	return u.PodUpdater.FinishUpdatePod(ctx, podInfo, finishByCancelUpdate)
+}
+
+// isDecorationImageUpdating indicates the Pod is going to update images of sidecar containers, or has been updated
+// in-place and is waiting for kubelet to pull the images
+func isDecorationImageUpdating(podInfo *PodUpdateInfo) bool {
+	if podInfo.OnlyDecorationImageChanged {
+		return true
+	}
+	if podInfo.PlaceHolder || !podInfo.IsUpdatedRevision || podInfo.PodDecorationChanged || !podInfo.isDuringUpdateOps {
+		return false
+	}
+	_, exist := podInfo.Annotations[appsv1alpha1.LastPodStatusAnnotationKey]
+	return exist
+}
+
 type PodStatus struct {
 	ContainerStates map[string]*ContainerStatus `json:"containerStates,omitempty"`
 }
@@ -544,6 +647,18 @@ type ContainerStatus struct {
 	LastImageID string `json:"lastImageID,omitempty"`
 }
 
+// withPodIdentity sets name and instance id of the pod on pods built from revisions, so that variables of
+// PodDecorations are resolved to the same values as the pod
+func withPodIdentity(pod *corev1.Pod) func(*corev1.Pod) error {
+	return func(in *corev1.Pod) error {
+		in.Name = pod.Name
+		if id, ok := pod.Labels[appsv1alpha1.PodInstanceIDLabelKey]; ok {
+			in.Labels[appsv1alpha1.PodInstanceIDLabelKey] = id
+		}
+		return nil
+	}
+}
+
 type inPlaceIfPossibleUpdater struct {
 	GenericPodUpdater
 }
@@ -552,7 +667,7 @@ func (u *inPlaceIfPossibleUpdater) FulfillPodUpdatedInfo(_ context.Context, _ *a
 	// 1. build pod from current and updated revision
 	ownerRef := metav1.NewControllerRef(u.CollaSet, appsv1alpha1.SchemeGroupVersion.WithKind("CollaSet"))
 	// TODO: use cache
-	currentPod, err := collasetutils.NewPodFrom(u.CollaSet, ownerRef, podUpdateInfo.CurrentRevision, func(in *corev1.Pod) error {
+	currentPod, err := collasetutils.NewPodFrom(u.CollaSet, ownerRef, podUpdateInfo.CurrentRevision, withPodIdentity(podUpdateInfo.Pod), func(in *corev1.Pod) error {
 		return utilspoddecoration.PatchListOfDecorations(in, podUpdateInfo.CurrentPodDecorations)
 	})
 	if err != nil {
@@ -560,7 +675,7 @@ func (u *inPlaceIfPossibleUpdater) FulfillPodUpdatedInfo(_ context.Context, _ *a
 	}
 
 	// TODO: use cache
-	podUpdateInfo.UpdatedPod, err = collasetutils.NewPodFrom(u.CollaSet, ownerRef, podUpdateInfo.UpdateRevision, func(in *corev1.Pod) error {
+	podUpdateInfo.UpdatedPod, err = collasetutils.NewPodFrom(u.CollaSet, ownerRef, podUpdateInfo.UpdateRevision, withPodIdentity(podUpdateInfo.Pod), func(in *corev1.Pod) error {
 		return utilspoddecoration.PatchListOfDecorations(in, podUpdateInfo.UpdatedPodDecorations)
 	})
 	if err != nil {
@@ -586,46 +701,72 @@ func (u *inPlaceIfPossibleUpdater) FulfillPodUpdatedInfo(_ context.Context, _ *a
 		return err
 	}
 
+	return recordLastPodStatus(podUpdateInfo, imageChangedContainers)
+}
+
+// fulfillDecorationImageUpdatedInfo builds the updated Pod by setting images of sidecar containers and revisions of
+// PodDecorations on the Pod directly, so that it does not depend on the Pod built from revisions to be updated in-place.
+func (u *inPlaceIfPossibleUpdater) fulfillDecorationImageUpdatedInfo(podUpdateInfo *PodUpdateInfo) error {
+	podUpdateInfo.UpdatedPod = podUpdateInfo.Pod.DeepCopy()
+	imageChangedContainers := sets.String{}
+	for i := range podUpdateInfo.UpdatedPod.Spec.Containers {
+		container := &podUpdateInfo.UpdatedPod.Spec.Containers[i]
+		if image, ok := podUpdateInfo.decorationImages[container.Name]; ok && image != container.Image {
+			container.Image = image
+			imageChangedContainers.Insert(container.Name)
+		}
+	}
+	anno.SetDecorationInfo(podUpdateInfo.UpdatedPod, podUpdateInfo.UpdatedPodDecorations)
+
+	podUpdateInfo.InPlaceUpdateSupport = true
+	podUpdateInfo.OnlyMetadataChanged = imageChangedContainers.Len() == 0
+	return recordLastPodStatus(podUpdateInfo, imageChangedContainers)
+}
+
+// recordLastPodStatus stores images of updated Pod and image IDs of changed containers in annotation, which are
+// used to check whether the in-place update is finished by kubelet.
+func recordLastPodStatus(podUpdateInfo *PodUpdateInfo, imageChangedContainers sets.String) error {
 	if podUpdateInfo.OnlyMetadataChanged {
 		if podUpdateInfo.UpdatedPod.Annotations != nil {
 			delete(podUpdateInfo.UpdatedPod.Annotations, appsv1alpha1.LastPodStatusAnnotationKey)
 		}
-	} else {
-		containerCurrentStatusMapping := map[string]*corev1.ContainerStatus{}
-		for i := range podUpdateInfo.Status.ContainerStatuses {
-			status := podUpdateInfo.Status.ContainerStatuses[i]
-			// only store and compare imageID of changed containers
-			if imageChangedContainers != nil && imageChangedContainers.Has(status.Name) {
-				containerCurrentStatusMapping[status.Name] = &status
-			}
-		}
-
-		podStatus := &PodStatus{ContainerStates: map[string]*ContainerStatus{}}
-		for _, container := range podUpdateInfo.UpdatedPod.Spec.Containers {
-			podStatus.ContainerStates[container.Name] = &ContainerStatus{
-				// store image of each container in updated Pod
-				LatestImage: container.Image,
-			}
-
-			containerCurrentStatus, exist := containerCurrentStatusMapping[container.Name]
-			if !exist {
-				continue
-			}
+		return nil
+	}
 
-			// store image ID of each container in current Pod
-			podStatus.ContainerStates[container.Name].LastImageID = containerCurrentStatus.ImageID
+	containerCurrentStatusMapping := map[string]*corev1.ContainerStatus{}
+	for i := range podUpdateInfo.Status.ContainerStatuses {
+		status := podUpdateInfo.Status.ContainerStatuses[i]
+		// only store and compare imageID of changed containers
+		if imageChangedContainers != nil && imageChangedContainers.Has(status.Name) {
+			containerCurrentStatusMapping[status.Name] = &status
 		}
+	}
 
-		podStatusStr, err := json.Marshal(podStatus)
-		if err != nil {
-			return err
+	podStatus := &PodStatus{ContainerStates: map[string]*ContainerStatus{}}
+	for _, container := range podUpdateInfo.UpdatedPod.Spec.Containers {
+		podStatus.ContainerStates[container.Name] = &ContainerStatus{
+			// store image of each container in updated Pod
+			LatestImage: container.Image,
 		}
 
-		if podUpdateInfo.UpdatedPod.Annotations == nil {
-			podUpdateInfo.UpdatedPod.Annotations = map[string]string{}
+		containerCurrentStatus, exist := containerCurrentStatusMapping[container.Name]
+		if !exist {
+			continue
 		}
-		podUpdateInfo.UpdatedPod.Annotations[appsv1alpha1.LastPodStatusAnnotationKey] = string(podStatusStr)
+
+		// store image ID of each container in current Pod
+		podStatus.ContainerStates[container.Name].LastImageID = containerCurrentStatus.ImageID
+	}
+
+	podStatusStr, err := json.Marshal(podStatus)
+	if err != nil {
+		return err
+	}
+
+	if podUpdateInfo.UpdatedPod.Annotations == nil {
+		podUpdateInfo.UpdatedPod.Annotations = map[string]string{}
 	}
+	podUpdateInfo.UpdatedPod.Annotations[appsv1alpha1.LastPodStatusAnnotationKey] = string(podStatusStr)
 	return nil
 }
 
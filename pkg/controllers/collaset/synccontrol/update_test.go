@@ -0,0 +1,128 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package synccontrol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kusionstack.io/kuperator/pkg/controllers/collaset/podcontrol"
+	collasetutils "kusionstack.io/kuperator/pkg/controllers/collaset/utils"
+	utilspoddecoration "kusionstack.io/kuperator/pkg/controllers/utils/poddecoration"
+	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/anno"
+)
+
+func TestDecorationImageUpdater(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).Should(gomega.Succeed())
+	g.Expect(appsv1alpha1.AddToScheme(scheme)).Should(gomega.Succeed())
+
+	newPD := func(image string) *appsv1alpha1.PodDecoration {
+		return &appsv1alpha1.PodDecoration{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "mesh"},
+			Spec: appsv1alpha1.PodDecorationSpec{
+				Template: appsv1alpha1.PodDecorationPodTemplate{
+					Containers: []*appsv1alpha1.ContainerPatch{
+						{Container: corev1.Container{Name: "proxy", Image: image}},
+					},
+				},
+			},
+		}
+	}
+	currentPDs := map[string]*appsv1alpha1.PodDecoration{"mesh-1": newPD("proxy:v1")}
+	updatedPDs := map[string]*appsv1alpha1.PodDecoration{"mesh-2": newPD("proxy:v2")}
+
+	// pods are recreated by the policy, except updating images of sidecars
+	cls := &appsv1alpha1.CollaSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo", UID: "uid"},
+		Spec: appsv1alpha1.CollaSetSpec{
+			UpdateStrategy: appsv1alpha1.UpdateStrategy{
+				PodUpdatePolicy: appsv1alpha1.CollaSetRecreatePodUpdateStrategyType,
+			},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo-0"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "main", Image: "nginx:v1"}, {Name: "proxy", Image: "proxy:v1"}},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "main", ImageID: "nginx-v1"}, {Name: "proxy", ImageID: "proxy-v1"}},
+		},
+	}
+	anno.SetDecorationInfo(pod, currentPDs)
+
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+	collasetutils.InitExpectations(c)
+	updater := newPodUpdater(c, cls, podcontrol.NewRealPodControl(c, scheme), record.NewFakeRecorder(10))
+
+	revision := &appsv1.ControllerRevision{ObjectMeta: metav1.ObjectMeta{Name: "foo-1"}}
+	podInfo := &PodUpdateInfo{
+		PodWrapper:            &collasetutils.PodWrapper{Pod: pod.DeepCopy()},
+		IsUpdatedRevision:     true,
+		CurrentRevision:       revision,
+		UpdateRevision:        revision,
+		PodDecorationChanged:  true,
+		CurrentPodDecorations: currentPDs,
+		UpdatedPodDecorations: updatedPDs,
+	}
+	podInfo.decorationImages, podInfo.OnlyDecorationImageChanged = utilspoddecoration.SidecarImageChanges(pod, currentPDs, updatedPDs)
+	g.Expect(podInfo.OnlyDecorationImageChanged).Should(gomega.BeTrue())
+
+	g.Expect(updater.FulfillPodUpdatedInfo(ctx, revision, podInfo)).Should(gomega.Succeed())
+	g.Expect(podInfo.InPlaceUpdateSupport).Should(gomega.BeTrue())
+	g.Expect(podInfo.OnlyMetadataChanged).Should(gomega.BeFalse())
+	g.Expect(podInfo.UpdatedPod.Spec.Containers[1].Image).Should(gomega.Equal("proxy:v2"))
+	g.Expect(podInfo.UpdatedPod.Annotations[appsv1alpha1.LastPodStatusAnnotationKey]).Should(gomega.ContainSubstring(`"lastImageID":"proxy-v1"`))
+	g.Expect(anno.GetDecorationRevisionInfo(podInfo.UpdatedPod).GetRevision("mesh")).Should(gomega.Equal(func(s string) *string { return &s }("mesh-2")))
+
+	// the pod is updated in-place instead of recreated
+	g.Expect(updater.UpgradePod(ctx, podInfo)).Should(gomega.Succeed())
+	updated := &corev1.Pod{}
+	g.Expect(c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "foo-0"}, updated)).Should(gomega.Succeed())
+	g.Expect(updated.Spec.Containers[1].Image).Should(gomega.Equal("proxy:v2"))
+
+	// the update is finished after kubelet pulls the image
+	podInfo = &PodUpdateInfo{
+		PodWrapper:        &collasetutils.PodWrapper{Pod: updated},
+		IsUpdatedRevision: true,
+		CurrentRevision:   revision,
+		UpdateRevision:    revision,
+		isDuringUpdateOps: true,
+	}
+	finished, _, err := updater.GetPodUpdateFinishStatus(ctx, podInfo)
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(finished).Should(gomega.BeFalse())
+
+	updated.Status.ContainerStatuses[1].ImageID = "proxy-v2"
+	finished, _, err = updater.GetPodUpdateFinishStatus(ctx, podInfo)
+	g.Expect(err).Should(gomega.BeNil())
+	g.Expect(finished).Should(gomega.BeTrue())
+}
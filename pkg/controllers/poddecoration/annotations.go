@@ -0,0 +1,149 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poddecoration
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/sets"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+	"kusionstack.io/kuperator/pkg/utils"
+)
+
+// counterPatchInterval is the minimal interval of patching annotations of a PodDecoration, if only pod counters
+// in them are changed. Pods change frequently during rolling out, and each patch is seen by all watchers.
+const counterPatchInterval = 30 * time.Second
+
+var (
+	// counterAnnotations are annotations recorded by controller which contain pod counters
+	counterAnnotations = []string{
+		kuperatorv1alpha1.AnnotationPodDecorationRevisionHistory,
+		kuperatorv1alpha1.AnnotationPodDecorationNamespaceStatus,
+		kuperatorv1alpha1.AnnotationPodDecorationConflicts,
+	}
+	// counterFields are json fields of pod counters in counterAnnotations
+	counterFields = sets.NewString("pods", "matchedPods", "injectedPods", "updatedPods", "updatedReadyPods", "updatedAvailablePods")
+
+	counterPatchLimiter = &patchLimiter{lastPatched: map[string]time.Time{}}
+)
+
+func setAnnotation(instance *appsv1alpha1.PodDecoration, key, val string) {
+	if instance.Annotations == nil {
+		instance.Annotations = map[string]string{}
+	}
+	instance.Annotations[key] = val
+}
+
+// patchAnnotations patches annotations set on instance since base in one patch. If only pod counters are changed,
+// the patch is delayed until counterPatchInterval passes, and the duration to wait is returned.
+func (r *ReconcilePodDecoration) patchAnnotations(ctx context.Context, base, instance *appsv1alpha1.PodDecoration) (time.Duration, error) {
+	if equality.Semantic.DeepEqual(base.Annotations, instance.Annotations) {
+		return 0, nil
+	}
+	key := utils.ObjectKeyString(instance)
+	now := time.Now()
+	if onlyCountersChanged(base.Annotations, instance.Annotations) {
+		if wait := counterPatchLimiter.wait(key, now); wait > 0 {
+			return wait, nil
+		}
+	}
+	if err := r.Patch(ctx, instance, client.MergeFrom(base)); err != nil {
+		return 0, err
+	}
+	counterPatchLimiter.record(key, now)
+	return 0, nil
+}
+
+// onlyCountersChanged checks whether the only changes between annotations are pod counters
+func onlyCountersChanged(old, updated map[string]string) bool {
+	old, updated = copyAnnotations(old), copyAnnotations(updated)
+	for _, key := range counterAnnotations {
+		oldVal, oldOk := old[key]
+		updatedVal, updatedOk := updated[key]
+		if oldOk != updatedOk || withoutCounters(oldVal) != withoutCounters(updatedVal) {
+			return false
+		}
+		delete(old, key)
+		delete(updated, key)
+	}
+	return equality.Semantic.DeepEqual(old, updated)
+}
+
+func copyAnnotations(annotations map[string]string) map[string]string {
+	res := make(map[string]string, len(annotations))
+	for k, v := range annotations {
+		res[k] = v
+	}
+	return res
+}
+
+// withoutCounters removes counterFields from the list in json
+func withoutCounters(val string) string {
+	var items []map[string]interface{}
+	if err := json.Unmarshal([]byte(val), &items); err != nil {
+		return val
+	}
+	for _, item := range items {
+		for field := range counterFields {
+			delete(item, field)
+		}
+	}
+	byt, err := json.Marshal(items)
+	if err != nil {
+		return val
+	}
+	return string(byt)
+}
+
+// patchLimiter records the last time annotations of each PodDecoration are patched
+type patchLimiter struct {
+	mu          sync.Mutex
+	lastPatched map[string]time.Time
+}
+
+// wait returns the duration to wait until next patch is allowed
+func (l *patchLimiter) wait(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	last, ok := l.lastPatched[key]
+	if !ok {
+		return 0
+	}
+	if wait := last.Add(counterPatchInterval).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+func (l *patchLimiter) record(key string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastPatched[key] = now
+}
+
+func (l *patchLimiter) forget(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.lastPatched, key)
+}
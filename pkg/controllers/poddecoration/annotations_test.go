@@ -0,0 +1,60 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poddecoration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+func TestOnlyCountersChanged(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	old := map[string]string{
+		kuperatorv1alpha1.AnnotationPodDecorationRevisionHistory: `[{"revision":"foo-1","number":1,"pods":2}]`,
+		"app": "foo",
+	}
+	updated := map[string]string{
+		kuperatorv1alpha1.AnnotationPodDecorationRevisionHistory: `[{"revision":"foo-1","number":1,"pods":3}]`,
+		"app": "foo",
+	}
+	g.Expect(onlyCountersChanged(old, updated)).Should(gomega.BeTrue())
+
+	updated[kuperatorv1alpha1.AnnotationPodDecorationRevisionHistory] = `[{"revision":"foo-1","number":1,"pods":3},{"revision":"foo-2","number":2}]`
+	g.Expect(onlyCountersChanged(old, updated)).Should(gomega.BeFalse())
+
+	updated[kuperatorv1alpha1.AnnotationPodDecorationRevisionHistory] = old[kuperatorv1alpha1.AnnotationPodDecorationRevisionHistory]
+	updated[kuperatorv1alpha1.AnnotationPodDecorationCanaryStatus] = `{"revision":"foo-1","step":1}`
+	g.Expect(onlyCountersChanged(old, updated)).Should(gomega.BeFalse())
+}
+
+func TestPatchLimiter(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	limiter := &patchLimiter{lastPatched: map[string]time.Time{}}
+	now := time.Now()
+	g.Expect(limiter.wait("default/foo", now)).Should(gomega.BeZero())
+
+	limiter.record("default/foo", now)
+	g.Expect(limiter.wait("default/foo", now.Add(10*time.Second))).Should(gomega.Equal(counterPatchInterval - 10*time.Second))
+	g.Expect(limiter.wait("default/foo", now.Add(counterPatchInterval))).Should(gomega.BeZero())
+
+	limiter.forget("default/foo")
+	g.Expect(limiter.wait("default/foo", now)).Should(gomega.BeZero())
+}
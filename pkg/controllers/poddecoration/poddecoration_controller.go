@@ -18,10 +18,13 @@ package poddecoration
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -29,6 +32,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
@@ -41,9 +45,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
 	"kusionstack.io/kuperator/pkg/controllers/collaset/podcontrol"
 	controllerutils "kusionstack.io/kuperator/pkg/controllers/utils"
 	"kusionstack.io/kuperator/pkg/controllers/utils/expectations"
+	poddecorationutils "kusionstack.io/kuperator/pkg/controllers/utils/poddecoration"
 	utilspoddecoration "kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/anno"
 	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/revision"
 	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/strategy"
@@ -92,16 +98,60 @@ func AddToMgr(mgr manager.Manager, r reconcile.Reconciler) error {
 		if listErr := managerClient.List(context.TODO(), pdList, client.InNamespace(podObject.GetNamespace())); listErr != nil {
 			return nil
 		}
+		clusterWide, listErr := listClusterWide(managerClient)
+		if listErr != nil {
+			return nil
+		}
+		if len(clusterWide) > 0 {
+			ns := &corev1.Namespace{}
+			if getErr := managerClient.Get(context.TODO(), types.NamespacedName{Name: podObject.GetNamespace()}, ns); getErr != nil {
+				return nil
+			}
+			for _, pd := range clusterWide {
+				if utilspoddecoration.SelectsNamespace(pd, ns) {
+					pdList.Items = append(pdList.Items, *pd)
+				}
+			}
+		}
 		var requests []reconcile.Request
 		for _, pd := range pdList.Items {
 			selector, _ := metav1.LabelSelectorAsSelector(pd.Spec.Selector)
 			if selector.Matches(labels.Set(podObject.GetLabels())) {
-				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: podObject.GetNamespace(), Name: pd.GetName()}})
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: pd.Namespace, Name: pd.GetName()}})
 			}
 		}
 		return requests
 	}))
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Watch labels of Namespaces which can be selected by cluster-wide PodDecorations
+	return c.Watch(&source.Kind{Type: &corev1.Namespace{}}, handler.EnqueueRequestsFromMapFunc(func(client.Object) []reconcile.Request {
+		clusterWide, listErr := listClusterWide(managerClient)
+		if listErr != nil {
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, pd := range clusterWide {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: pd.Namespace, Name: pd.Name}})
+		}
+		return requests
+	}))
+}
+
+func listClusterWide(c client.Client) ([]*appsv1alpha1.PodDecoration, error) {
+	pdList := &appsv1alpha1.PodDecorationList{}
+	if err := c.List(context.TODO(), pdList, client.InNamespace(utilspoddecoration.ClusterNamespace)); err != nil {
+		return nil, err
+	}
+	var res []*appsv1alpha1.PodDecoration
+	for i := range pdList.Items {
+		if utilspoddecoration.IsClusterWide(&pdList.Items[i]) {
+			res = append(res, &pdList.Items[i])
+		}
+	}
+	return res, nil
 }
 
 var (
@@ -123,6 +173,7 @@ type ReconcilePodDecoration struct {
 // +kubebuilder:rbac:groups=apps.kusionstack.io,resources=collasets/finalizers,verbs=update
 // +kubebuilder:rbac:groups=apps,resources=controllerrevisions,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;update;patch
 
 // Reconcile reads that state of the cluster for a PodDecoration object and makes changes based on the state read
@@ -144,6 +195,7 @@ func (r *ReconcilePodDecoration) Reconcile(ctx context.Context, request reconcil
 	if instance.DeletionTimestamp != nil {
 		strategy.SharedStrategyController.DeletePodDecoration(instance)
 		statusUpToDateExpectation.DeleteExpectations(key)
+		counterPatchLimiter.forget(key)
 		if !r.shouldEscape(ctx, instance) {
 			return reconcile.Result{RequeueAfter: 5 * time.Second}, nil
 		}
@@ -153,29 +205,27 @@ func (r *ReconcilePodDecoration) Reconcile(ctx context.Context, request reconcil
 		return reconcile.Result{}, err
 	}
 
-	_, updatedRevision, _, collisionCount, _, err := r.revisionManager.ConstructRevisions(ctx, instance)
+	_, updatedRevision, revisions, collisionCount, _, err := r.revisionManager.ConstructRevisions(ctx, instance)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
-	podList := &corev1.PodList{}
-	var selectedPods []*corev1.Pod
-	var sel labels.Selector
-	if instance.Spec.Selector != nil {
-		sel, _ = metav1.LabelSelectorAsSelector(instance.Spec.Selector)
-	}
-	if err = r.List(ctx, podList, &client.ListOptions{
-		Namespace:     instance.Namespace,
-		LabelSelector: sel,
-	}); err != nil {
+	if pinned := utilspoddecoration.GetPinnedRevision(instance); pinned != "" {
+		if updatedRevision, err = r.pinnedRevision(instance, pinned, revisions); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	pods, err := strategy.ListSelectedPods(ctx, r.Client, instance)
+	if err != nil {
 		return reconcile.Result{}, err
 	}
-	for i := range podList.Items {
-		if podcontrol.IsPodInactive(&podList.Items[i]) || podList.Items[i].DeletionTimestamp != nil {
+	var selectedPods []*corev1.Pod
+	for _, pod := range pods {
+		if podcontrol.IsPodInactive(pod) || pod.DeletionTimestamp != nil {
 			continue
 		}
-		selectedPods = append(selectedPods, &podList.Items[i])
+		selectedPods = append(selectedPods, pod)
 	}
-	affectedPods, affectedCollaSets := r.filterOutPodAndCollaSet(selectedPods)
+	affectedPods, affectedCollaSets := r.filterOutPodAndCollaSet(instance, selectedPods)
 	newStatus := &appsv1alpha1.PodDecorationStatus{
 		ObservedGeneration: instance.Generation,
 		CurrentRevision:    instance.Status.CurrentRevision,
@@ -191,7 +241,34 @@ func (r *ReconcilePodDecoration) Reconcile(ctx context.Context, request reconcil
 		return reconcile.Result{}, err
 	}
 
-	return reconcile.Result{}, strategy.SharedStrategyController.UpdateSelectedPods(ctx, instance, selectedPods)
+	// annotations recorded by controller are set on instance, and patched at once after pods are updated
+	base := instance.DeepCopy()
+	if err = r.setRevisionHistory(instance, revisions, affectedPods); err != nil {
+		return reconcile.Result{}, err
+	}
+	if err = r.setNamespaceStatus(instance, newStatus.UpdatedRevision, affectedPods); err != nil {
+		return reconcile.Result{}, err
+	}
+	requeueAfter, err := r.setCanary(instance, newStatus)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	conflicts, err := r.detectConflicts(ctx, instance, selectedPods)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if err = r.setConflicts(instance, conflicts); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err = strategy.SharedStrategyController.UpdateSelectedPods(ctx, instance, selectedPods); err != nil {
+		return reconcile.Result{}, err
+	}
+	counterRequeueAfter, err := r.patchAnnotations(ctx, base, instance)
+	if requeueAfter == 0 || (counterRequeueAfter > 0 && counterRequeueAfter < requeueAfter) {
+		requeueAfter = counterRequeueAfter
+	}
+	return reconcile.Result{RequeueAfter: requeueAfter}, err
 }
 
 func (r *ReconcilePodDecoration) calculateStatus(
@@ -251,8 +328,17 @@ func (r *ReconcilePodDecoration) calculateStatus(
 
 func (r *ReconcilePodDecoration) allCollaSetsSatisfyReplicas(collaSets sets.String, ns string) bool {
 	collaSet := &appsv1alpha1.CollaSet{}
-	for name := range collaSets {
-		if err := r.Get(context.TODO(), types.NamespacedName{Namespace: ns, Name: name}, collaSet); err != nil {
+	for key := range collaSets {
+		// CollaSets of cluster-wide PodDecorations are keyed by namespace/name
+		namespace, name, _ := cache.SplitMetaNamespaceKey(key)
+		if namespace == "" {
+			namespace = ns
+		}
+		// pods not controlled by CollaSet
+		if name == "" {
+			continue
+		}
+		if err := r.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, collaSet); err != nil {
 			if errors.IsNotFound(err) {
 				continue
 			}
@@ -268,14 +354,25 @@ func (r *ReconcilePodDecoration) allCollaSetsSatisfyReplicas(collaSets sets.Stri
 
 func (r *ReconcilePodDecoration) shouldEscape(ctx context.Context, instance *appsv1alpha1.PodDecoration) bool {
 	podList := &corev1.PodList{}
-	if err := r.List(ctx, podList,
-		client.InNamespace(instance.Namespace),
-		client.HasLabels{appsv1alpha1.PodDecorationLabelPrefix + instance.Name},
-	); err != nil {
+	opts := []client.ListOption{client.HasLabels{appsv1alpha1.PodDecorationLabelPrefix + instance.Name}}
+	if !utilspoddecoration.IsClusterWide(instance) {
+		opts = append(opts, client.InNamespace(instance.Namespace))
+	}
+	if err := r.List(ctx, podList, opts...); err != nil {
 		klog.Errorf("failed to list pods: %v", err)
 		return false
 	}
-	return len(podList.Items) == 0
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Namespace == instance.Namespace {
+			return false
+		}
+		// pods in other namespaces may be decorated by the namespace-local PodDecoration with the same name
+		if err := r.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: instance.Name}, &appsv1alpha1.PodDecoration{}); err != nil {
+			return false
+		}
+	}
+	return true
 }
 
 func (r *ReconcilePodDecoration) updateStatus(
@@ -305,17 +402,252 @@ func (r *ReconcilePodDecoration) updateStatus(
 	})
 }
 
-func (r *ReconcilePodDecoration) filterOutPodAndCollaSet(pods []*corev1.Pod) (
+// pinnedRevision returns the pinned revision in history, which is rolled out as the updated revision
+func (r *ReconcilePodDecoration) pinnedRevision(instance *appsv1alpha1.PodDecoration, pinned string, revisions []*appsv1.ControllerRevision) (*appsv1.ControllerRevision, error) {
+	for _, rev := range revisions {
+		if rev.Name == pinned {
+			return rev, nil
+		}
+	}
+	r.Recorder.Eventf(instance, corev1.EventTypeWarning, "PinnedRevisionNotFound", "pinned revision %s is not in history", pinned)
+	return nil, fmt.Errorf("pinned revision %s of PodDecoration %s not found", pinned, utils.ObjectKeyString(instance))
+}
+
+// setRevisionHistory records revisions in history and the number of pods decorated with them in annotation
+func (r *ReconcilePodDecoration) setRevisionHistory(instance *appsv1alpha1.PodDecoration, revisions []*appsv1.ControllerRevision, affectedPods map[string][]*corev1.Pod) error {
+	podsOfRevision := map[string]int32{}
+	for _, pods := range affectedPods {
+		for _, pod := range pods {
+			if currentRevision := utilspoddecoration.CurrentRevision(pod, instance.Name); currentRevision != nil {
+				podsOfRevision[*currentRevision]++
+			}
+		}
+	}
+	history := make([]kuperatorv1alpha1.PodDecorationRevision, 0, len(revisions))
+	for _, rev := range revisions {
+		history = append(history, kuperatorv1alpha1.PodDecorationRevision{
+			Revision:          rev.Name,
+			Number:            rev.Revision,
+			CreationTimestamp: rev.CreationTimestamp,
+			Pods:              podsOfRevision[rev.Name],
+		})
+	}
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].Number < history[j].Number
+	})
+	val, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	setAnnotation(instance, kuperatorv1alpha1.AnnotationPodDecorationRevisionHistory, string(val))
+	return nil
+}
+
+// setNamespaceStatus records status of pods in each namespace in annotation of cluster-wide PodDecorations
+func (r *ReconcilePodDecoration) setNamespaceStatus(instance *appsv1alpha1.PodDecoration, updatedRevision string, affectedPods map[string][]*corev1.Pod) error {
+	if !utilspoddecoration.IsClusterWide(instance) {
+		delete(instance.Annotations, kuperatorv1alpha1.AnnotationPodDecorationNamespaceStatus)
+		return nil
+	}
+	statuses := map[string]*kuperatorv1alpha1.PodDecorationNamespaceStatus{}
+	for _, pods := range affectedPods {
+		for _, pod := range pods {
+			status, ok := statuses[pod.Namespace]
+			if !ok {
+				status = &kuperatorv1alpha1.PodDecorationNamespaceStatus{Namespace: pod.Namespace}
+				statuses[pod.Namespace] = status
+			}
+			status.MatchedPods++
+			currentRevision := utilspoddecoration.CurrentRevision(pod, instance.Name)
+			if currentRevision == nil {
+				continue
+			}
+			status.InjectedPods++
+			if *currentRevision != updatedRevision {
+				continue
+			}
+			status.UpdatedPods++
+			if controllerutils.IsPodReady(pod) {
+				status.UpdatedReadyPods++
+			}
+			if controllerutils.IsPodServiceAvailable(pod) {
+				status.UpdatedAvailablePods++
+			}
+		}
+	}
+	list := make([]*kuperatorv1alpha1.PodDecorationNamespaceStatus, 0, len(statuses))
+	for _, status := range statuses {
+		list = append(list, status)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Namespace < list[j].Namespace
+	})
+	val, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	setAnnotation(instance, kuperatorv1alpha1.AnnotationPodDecorationNamespaceStatus, string(val))
+	return nil
+}
+
+// setCanary advances canary steps with status of pods, and records the progress in annotation. It returns the
+// duration after which the progress should be checked again.
+func (r *ReconcilePodDecoration) setCanary(instance *appsv1alpha1.PodDecoration, status *appsv1alpha1.PodDecorationStatus) (time.Duration, error) {
+	old := utilspoddecoration.GetCanaryStatus(instance)
+	progress, requeueAfter, err := strategy.AdvanceCanary(instance, status, metav1.Now())
+	if err != nil {
+		return 0, err
+	}
+	if progress == nil {
+		delete(instance.Annotations, kuperatorv1alpha1.AnnotationPodDecorationCanaryStatus)
+		return 0, nil
+	}
+	if old == nil || old.Revision != progress.Revision || old.Step != progress.Step || old.Condition != progress.Condition {
+		switch progress.Condition {
+		case kuperatorv1alpha1.CanaryHalted:
+			r.Recorder.Eventf(instance, corev1.EventTypeWarning, "CanaryHalted", "revision %s: %s", progress.Revision, progress.Message)
+		case kuperatorv1alpha1.CanaryCompleted:
+			r.Recorder.Eventf(instance, corev1.EventTypeNormal, "CanaryCompleted", "revision %s is rolled out to all pods", progress.Revision)
+		case kuperatorv1alpha1.CanaryProgressing:
+			r.Recorder.Eventf(instance, corev1.EventTypeNormal, "CanaryStepStarted", "revision %s: step %d started", progress.Revision, progress.Step)
+		}
+	}
+	val, err := json.Marshal(progress)
+	if err != nil {
+		return 0, err
+	}
+	setAnnotation(instance, kuperatorv1alpha1.AnnotationPodDecorationCanaryStatus, string(val))
+	return requeueAfter, nil
+}
+
+// detectConflicts finds PodDecorations selecting the same pods, and patching the same fields with
+// different values
+func (r *ReconcilePodDecoration) detectConflicts(ctx context.Context, instance *appsv1alpha1.PodDecoration, pods []*corev1.Pod) ([]kuperatorv1alpha1.PodDecorationConflict, error) {
+	if len(pods) == 0 {
+		return nil, nil
+	}
+	// PodDecorations which may decorate pods in namespaces of the selected pods
+	namespaces := sets.NewString(instance.Namespace)
+	if utilspoddecoration.IsClusterWide(instance) {
+		namespaces = sets.NewString()
+		for _, pod := range pods {
+			namespaces.Insert(pod.Namespace)
+		}
+	}
+	var pds []appsv1alpha1.PodDecoration
+	// namespaces of pods which may be decorated by each PodDecoration
+	decoratedNamespaces := map[string]sets.String{}
+	for _, ns := range namespaces.List() {
+		nsPDs, err := strategy.ListNamespaceDecorations(ctx, r.Client, ns)
+		if err != nil {
+			return nil, err
+		}
+		for i := range nsPDs {
+			key := utils.ObjectKeyString(&nsPDs[i])
+			if _, ok := decoratedNamespaces[key]; !ok {
+				decoratedNamespaces[key] = sets.NewString()
+				pds = append(pds, nsPDs[i])
+			}
+			decoratedNamespaces[key].Insert(ns)
+		}
+	}
+	var conflicts []kuperatorv1alpha1.PodDecorationConflict
+	for i := range pds {
+		other := &pds[i]
+		// the namespace-local PodDecoration overrides the cluster-wide one with the same name
+		if other.Name == instance.Name || other.DeletionTimestamp != nil {
+			continue
+		}
+		fields := poddecorationutils.Conflicts(instance, other)
+		if len(fields) == 0 {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(other.Spec.Selector)
+		if err != nil {
+			continue
+		}
+		var count int32
+		for _, pod := range pods {
+			if decoratedNamespaces[utils.ObjectKeyString(other)].Has(pod.Namespace) && selector.Matches(labels.Set(pod.Labels)) {
+				count++
+			}
+		}
+		if count > 0 {
+			name := other.Name
+			if other.Namespace != instance.Namespace {
+				name = utils.ObjectKeyString(other)
+			}
+			conflicts = append(conflicts, kuperatorv1alpha1.PodDecorationConflict{
+				PodDecoration: name,
+				Fields:        fields,
+				Pods:          count,
+			})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].PodDecoration < conflicts[j].PodDecoration
+	})
+	return conflicts, nil
+}
+
+// setConflicts records conflicts in annotation, and emits events for new conflicts
+func (r *ReconcilePodDecoration) setConflicts(instance *appsv1alpha1.PodDecoration, conflicts []kuperatorv1alpha1.PodDecorationConflict) error {
+	var oldConflicts []kuperatorv1alpha1.PodDecorationConflict
+	if val, ok := instance.Annotations[kuperatorv1alpha1.AnnotationPodDecorationConflicts]; ok {
+		_ = json.Unmarshal([]byte(val), &oldConflicts)
+	}
+	if equality.Semantic.DeepEqual(oldConflicts, conflicts) {
+		return nil
+	}
+	reported := map[string]kuperatorv1alpha1.PodDecorationConflict{}
+	for _, conflict := range oldConflicts {
+		reported[conflict.PodDecoration] = conflict
+	}
+	for _, conflict := range conflicts {
+		if old, ok := reported[conflict.PodDecoration]; ok && equality.Semantic.DeepEqual(old.Fields, conflict.Fields) {
+			continue
+		}
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning, "PodDecorationConflict", "conflicts with PodDecoration %s on %d pods: %s",
+			conflict.PodDecoration, conflict.Pods, strings.Join(conflict.Fields, ", "))
+	}
+
+	if len(conflicts) == 0 {
+		delete(instance.Annotations, kuperatorv1alpha1.AnnotationPodDecorationConflicts)
+		return nil
+	}
+	val, err := json.Marshal(conflicts)
+	if err != nil {
+		return err
+	}
+	setAnnotation(instance, kuperatorv1alpha1.AnnotationPodDecorationConflicts, string(val))
+	return nil
+}
+
+// filterOutPodAndCollaSet groups pods by CollaSet. Pods not controlled by CollaSet are grouped by the empty name
+// if they are decorated by the pod webhook. Groups of cluster-wide PodDecorations are keyed by namespace/name.
+// Pods on which the PodDecoration is overridden are filtered out.
+func (r *ReconcilePodDecoration) filterOutPodAndCollaSet(instance *appsv1alpha1.PodDecoration, pods []*corev1.Pod) (
 	affectedPods map[string][]*corev1.Pod, affectedCollaSets sets.String,
 ) {
 	affectedPods = map[string][]*corev1.Pod{}
 	affectedCollaSets = sets.NewString()
+	clusterWide := utilspoddecoration.IsClusterWide(instance)
 	for i := range pods {
+		if strategy.SharedStrategyController.IsOverridden(pods[i], instance) {
+			continue
+		}
+		var key string
 		ownerRef := metav1.GetControllerOf(pods[i])
 		if ownerRef != nil && ownerRef.Kind == "CollaSet" {
-			affectedPods[ownerRef.Name] = append(affectedPods[ownerRef.Name], pods[i])
-			affectedCollaSets.Insert(ownerRef.Name)
+			key = ownerRef.Name
+		} else if !utilspoddecoration.InjectedByWebhook(pods[i], instance) {
+			continue
+		}
+		if clusterWide {
+			key = pods[i].Namespace + "/" + key
 		}
+		affectedPods[key] = append(affectedPods[key], pods[i])
+		affectedCollaSets.Insert(key)
 	}
 	for key, collaSetPods := range affectedPods {
 		sort.Slice(collaSetPods, func(i, j int) bool {
@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podopslifecycle
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"kusionstack.io/kube-api/apps/v1alpha1"
+
+	"kusionstack.io/kuperator/pkg/controllers/podtransitionrule/checker"
+	controllersutils "kusionstack.io/kuperator/pkg/controllers/utils"
+)
+
+// PodOpsLifecycleCondition is the pod condition summarizing the lifecycles on the pod. Core pods can not
+// have additional printer columns, it can be listed by:
+//
+//	kubectl get pod -o custom-columns='NAME:.metadata.name,REASON:.status.conditions[?(@.type=="OpsLifecycle")].reason,MESSAGE:.status.conditions[?(@.type=="OpsLifecycle")].message'
+const PodOpsLifecycleCondition corev1.PodConditionType = "OpsLifecycle"
+
+const (
+	// OpsLifecycleReasonIdle means no lifecycle is on the pod
+	OpsLifecycleReasonIdle = "Idle"
+	// OpsLifecycleReasonInProgress means lifecycles are moving forward
+	OpsLifecycleReasonInProgress = "InProgress"
+	// OpsLifecycleReasonBlocked means lifecycles are rejected by PodTransitionRules
+	OpsLifecycleReasonBlocked = "Blocked"
+)
+
+// updateOpsLifecycleCondition updates the OpsLifecycle condition of pod, and returns whether pod status is updated
+func (r *ReconcilePodOpsLifecycle) updateOpsLifecycleCondition(ctx context.Context, pod *corev1.Pod, idToLabelsMap map[string]map[string]string, state checker.CheckState) (bool, error) {
+	cond := buildOpsLifecycleCondition(idToLabelsMap, state)
+	if !setOpsLifecycleCondition(pod, cond) {
+		return false, nil
+	}
+
+	key := controllerKey(pod)
+	logger := r.Logger.WithValues("pod", key)
+
+	_ = r.expectation.ExpectUpdate(key, pod.ResourceVersion)
+	if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		newPod := &corev1.Pod{}
+		err := r.Client.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, newPod)
+		if err != nil {
+			return err
+		}
+		if !setOpsLifecycleCondition(newPod, cond) {
+			return nil
+		}
+		return r.Client.Status().Update(ctx, newPod)
+	}); err != nil {
+		logger.Error(err, "failed to update pod OpsLifecycle condition")
+		r.expectation.DeleteExpectations(key)
+		return false, err
+	}
+
+	return true, nil
+}
+
+// buildOpsLifecycleCondition summarizes active lifecycles with their types and stages, and the reason blocking them
+func buildOpsLifecycleCondition(idToLabelsMap map[string]map[string]string, state checker.CheckState) *corev1.PodCondition {
+	if len(idToLabelsMap) == 0 {
+		return &corev1.PodCondition{
+			Type:   PodOpsLifecycleCondition,
+			Status: corev1.ConditionFalse,
+			Reason: OpsLifecycleReasonIdle,
+		}
+	}
+
+	ids := make([]string, 0, len(idToLabelsMap))
+	for id := range idToLabelsMap {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	lifecycles := make([]string, 0, len(ids))
+	for _, id := range ids {
+		labels := idToLabelsMap[id]
+		operationType, ok := labels[v1alpha1.PodOperationTypeLabelPrefix]
+		if !ok {
+			operationType = labels[v1alpha1.PodDoneOperationTypeLabelPrefix]
+		}
+		stage, _ := CurrentStage(labels)
+		lifecycles = append(lifecycles, fmt.Sprintf("%s(%s): %s", id, operationType, stage))
+	}
+
+	cond := &corev1.PodCondition{
+		Type:    PodOpsLifecycleCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  OpsLifecycleReasonInProgress,
+		Message: strings.Join(lifecycles, ", "),
+	}
+	if state.Stage != "" && !state.InStageAndPassed() {
+		cond.Reason = OpsLifecycleReasonBlocked
+		cond.Message = fmt.Sprintf("%s; blocked in stage %s by %s", cond.Message, state.Stage, strings.TrimSpace(state.Message))
+	}
+	return cond
+}
+
+// setOpsLifecycleCondition sets the condition to pod, and returns whether pod status is changed
+func setOpsLifecycleCondition(pod *corev1.Pod, cond *corev1.PodCondition) bool {
+	index, current := controllersutils.GetPodCondition(&pod.Status, PodOpsLifecycleCondition)
+	if current == nil {
+		if cond.Status == corev1.ConditionFalse {
+			// no need to record pods never operated
+			return false
+		}
+		newCond := *cond
+		newCond.LastTransitionTime = metav1.Now()
+		pod.Status.Conditions = append(pod.Status.Conditions, newCond)
+		return true
+	}
+
+	if current.Status == cond.Status && current.Reason == cond.Reason && current.Message == cond.Message {
+		return false
+	}
+	newCond := *cond
+	newCond.LastTransitionTime = current.LastTransitionTime
+	if current.Status != cond.Status {
+		newCond.LastTransitionTime = metav1.Now()
+	}
+	pod.Status.Conditions[index] = newCond
+	return true
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podopslifecycle
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"kusionstack.io/kube-api/apps/v1alpha1"
+
+	"kusionstack.io/kuperator/pkg/controllers/podtransitionrule/checker"
+)
+
+func TestOpsLifecycleCondition(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	pod := &corev1.Pod{}
+
+	// no condition for pods never operated
+	cond := buildOpsLifecycleCondition(nil, checker.CheckState{})
+	g.Expect(setOpsLifecycleCondition(pod, cond)).Should(gomega.BeFalse())
+	g.Expect(pod.Status.Conditions).Should(gomega.BeEmpty())
+
+	idToLabelsMap := map[string]map[string]string{
+		"id-2": {
+			v1alpha1.PodOperatedLabelPrefix:          "1704865098763959176",
+			v1alpha1.PodDoneOperationTypeLabelPrefix: "scale-in",
+		},
+		"id-1": {
+			v1alpha1.PodOperatingLabelPrefix:     "1704865098763959176",
+			v1alpha1.PodOperationTypeLabelPrefix: "update",
+			v1alpha1.PodPreCheckLabelPrefix:      "1704865098763959176",
+		},
+	}
+	state := checker.CheckState{
+		Stage: v1alpha1.PodOpsLifecyclePreCheckStage,
+		States: []checker.State{
+			{
+				PodTransitionRuleName: "rule",
+				Detail:                &v1alpha1.PodTransitionDetail{Stage: v1alpha1.PodOpsLifecyclePreCheckStage, Passed: true},
+			},
+		},
+	}
+	cond = buildOpsLifecycleCondition(idToLabelsMap, state)
+	g.Expect(cond.Status).Should(gomega.Equal(corev1.ConditionTrue))
+	g.Expect(cond.Reason).Should(gomega.Equal(OpsLifecycleReasonInProgress))
+	g.Expect(cond.Message).Should(gomega.Equal("id-1(update): PreCheck, id-2(scale-in): Operated"))
+	g.Expect(setOpsLifecycleCondition(pod, cond)).Should(gomega.BeTrue())
+	g.Expect(pod.Status.Conditions).Should(gomega.HaveLen(1))
+	transitionTime := pod.Status.Conditions[0].LastTransitionTime
+	g.Expect(setOpsLifecycleCondition(pod, cond)).Should(gomega.BeFalse())
+
+	// blocked by rules
+	state.States[0].Detail.Passed = false
+	state.Message = "[PodTransitionRule: rule, RejectInfo: available:blocked] "
+	cond = buildOpsLifecycleCondition(idToLabelsMap, state)
+	g.Expect(cond.Reason).Should(gomega.Equal(OpsLifecycleReasonBlocked))
+	g.Expect(cond.Message).Should(gomega.ContainSubstring("blocked in stage PreCheck by [PodTransitionRule: rule, RejectInfo: available:blocked]"))
+	g.Expect(setOpsLifecycleCondition(pod, cond)).Should(gomega.BeTrue())
+	g.Expect(pod.Status.Conditions[0].Reason).Should(gomega.Equal(OpsLifecycleReasonBlocked))
+	g.Expect(pod.Status.Conditions[0].LastTransitionTime).Should(gomega.Equal(transitionTime))
+
+	// all lifecycles finished
+	cond = buildOpsLifecycleCondition(map[string]map[string]string{}, checker.CheckState{})
+	g.Expect(setOpsLifecycleCondition(pod, cond)).Should(gomega.BeTrue())
+	g.Expect(pod.Status.Conditions[0].Status).Should(gomega.Equal(corev1.ConditionFalse))
+	g.Expect(pod.Status.Conditions[0].Reason).Should(gomega.Equal(OpsLifecycleReasonIdle))
+	g.Expect(pod.Status.Conditions[0].Message).Should(gomega.BeEmpty())
+}
@@ -40,7 +40,9 @@ import (
 	"kusionstack.io/kuperator/pkg/controllers/podtransitionrule"
 	controllersutils "kusionstack.io/kuperator/pkg/controllers/utils"
 	"kusionstack.io/kuperator/pkg/controllers/utils/expectations"
+	"kusionstack.io/kuperator/pkg/features"
 	"kusionstack.io/kuperator/pkg/utils"
+	"kusionstack.io/kuperator/pkg/utils/feature"
 	"kusionstack.io/kuperator/pkg/utils/mixin"
 )
 
@@ -160,6 +162,16 @@ func (r *ReconcilePodOpsLifecycle) Reconcile(ctx context.Context, request reconc
 		return reconcile.Result{}, err
 	}
 
+	if feature.DefaultFeatureGate.Enabled(features.PodOpsLifecycleCondition) {
+		updated, err := r.updateOpsLifecycleCondition(ctx, pod, idToLabelsMap, state)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if updated {
+			return reconcile.Result{}, nil
+		}
+	}
+
 	var labels map[string]string
 	if state.InStageAndPassed() {
 		switch state.Stage {
@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podopslifecycle
+
+import (
+	"kusionstack.io/kube-api/apps/v1alpha1"
+)
+
+// Stage is the stage of one lifecycle on a pod
+type Stage string
+
+const (
+	StageBegin       Stage = "Begin"
+	StagePreCheck    Stage = "PreCheck"
+	StagePreChecked  Stage = "PreChecked"
+	StagePreparing   Stage = "Preparing"
+	StageOperate     Stage = "Operate"
+	StageOperated    Stage = "Operated"
+	StagePostCheck   Stage = "PostCheck"
+	StagePostChecked Stage = "PostChecked"
+	StageCompleting  Stage = "Completing"
+)
+
+// stages is ordered from the latest stage to the earliest one, the first matched label decides the stage
+var stages = []struct {
+	stage       Stage
+	labelPrefix string
+}{
+	{StageCompleting, v1alpha1.PodCompletingLabelPrefix},
+	{StagePostChecked, v1alpha1.PodPostCheckedLabelPrefix},
+	{StagePostCheck, v1alpha1.PodPostCheckLabelPrefix},
+	{StageOperated, v1alpha1.PodOperatedLabelPrefix},
+	{StageOperate, v1alpha1.PodOperateLabelPrefix},
+	{StagePreparing, v1alpha1.PodPreparingLabelPrefix},
+	{StagePreChecked, v1alpha1.PodPreCheckedLabelPrefix},
+	{StagePreCheck, v1alpha1.PodPreCheckLabelPrefix},
+	{StageBegin, v1alpha1.PodOperatingLabelPrefix},
+}
+
+// Before indicates whether the stage s is earlier than the stage o
+func (s Stage) Before(o Stage) bool {
+	return s.order() > o.order()
+}
+
+func (s Stage) order() int {
+	for i := range stages {
+		if stages[i].stage == s {
+			return i
+		}
+	}
+	return len(stages)
+}
+
+// CurrentStage returns the stage of one lifecycle and the value of the label deciding it.
+// The labels are keyed by prefix, as the values of IDToLabelsMap.
+func CurrentStage(labels map[string]string) (Stage, string) {
+	for _, s := range stages {
+		if val, ok := labels[s.labelPrefix]; ok {
+			return s.stage, val
+		}
+	}
+	return "", ""
+}
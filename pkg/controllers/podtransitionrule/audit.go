@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podtransitionrule
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// auditReasonPrefix marks rejections of rules in audit mode in RejectInfo
+const auditReasonPrefix = "[Audit] "
+
+var auditRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "podtransitionrule_audit_rejections_total",
+	Help: "Total number of pods which would be rejected by rules in audit mode",
+}, []string{"namespace", "podtransitionrule", "rule"})
+
+func init() {
+	metrics.Registry.MustRegister(auditRejections)
+}
+
+// recordAuditRejections emits events and metrics for rejections of rules in audit mode which are not
+// in status yet, so that the same rejection is reported only once. Rejections are identified by pod and rule,
+// since reasons may contain times and counts which change on each reconciling.
+func (r *PodTransitionRuleReconciler) recordAuditRejections(instance *appsv1alpha1.PodTransitionRule, pods map[string]*corev1.Pod, details map[string]*appsv1alpha1.PodTransitionDetail) {
+	recorded := map[string]sets.String{}
+	for _, detail := range instance.Status.Details {
+		recorded[detail.Name] = sets.NewString()
+		for _, rej := range detail.RejectInfo {
+			if strings.HasPrefix(rej.Reason, auditReasonPrefix) {
+				recorded[detail.Name].Insert(rej.RuleName)
+			}
+		}
+	}
+	for podName, detail := range details {
+		for _, rej := range detail.RejectInfo {
+			if !strings.HasPrefix(rej.Reason, auditReasonPrefix) || recorded[podName].Has(rej.RuleName) {
+				continue
+			}
+			auditRejections.WithLabelValues(instance.Namespace, instance.Name, rej.RuleName).Inc()
+			if pod, ok := pods[podName]; ok {
+				r.Recorder.Eventf(pod, corev1.EventTypeNormal, "AuditRejected", "PodTransitionRule %s rule %s in audit mode would reject pod: %s",
+					instance.Name, rej.RuleName, strings.TrimPrefix(rej.Reason, auditReasonPrefix))
+			}
+		}
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podtransitionrule
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	"kusionstack.io/kuperator/pkg/utils/mixin"
+)
+
+func TestRecordAuditRejections(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	recorder := record.NewFakeRecorder(10)
+	r := &PodTransitionRuleReconciler{ReconcilerMixin: &mixin.ReconcilerMixin{Recorder: recorder}}
+	instance := &appsv1alpha1.PodTransitionRule{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"}}
+	pods := map[string]*corev1.Pod{"pod-a": {ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-a"}}}
+	details := func(reason string) map[string]*appsv1alpha1.PodTransitionDetail {
+		return map[string]*appsv1alpha1.PodTransitionDetail{"pod-a": {
+			Name:       "pod-a",
+			RejectInfo: []appsv1alpha1.RejectInfo{{RuleName: "available", Reason: auditReasonPrefix + reason}},
+		}}
+	}
+
+	r.recordAuditRejections(instance, pods, details("[current unavailable]=2/5"))
+	g.Expect(recorder.Events).Should(gomega.HaveLen(1))
+	<-recorder.Events
+
+	// the reason changes, but the pod is still rejected by the same rule
+	instance.Status.Details = []*appsv1alpha1.PodTransitionDetail{details("[current unavailable]=2/5")["pod-a"]}
+	r.recordAuditRejections(instance, pods, details("[current unavailable]=3/5"))
+	g.Expect(recorder.Events).Should(gomega.BeEmpty())
+}
@@ -23,6 +23,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/workqueue"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
@@ -32,12 +33,15 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
 
 	processorrules "kusionstack.io/kuperator/pkg/controllers/podtransitionrule/processor/rules"
+	podtransitionruleutils "kusionstack.io/kuperator/pkg/controllers/podtransitionrule/utils"
 	commonutils "kusionstack.io/kuperator/pkg/utils"
 )
 
 var (
 	_ inject.Client = &EventHandler{}
 	_ inject.Logger = &EventHandler{}
+	_ inject.Client = &DependencyEventHandler{}
+	_ inject.Logger = &DependencyEventHandler{}
 )
 
 func NewWebhookGenericEventChannel() <-chan event.GenericEvent {
@@ -147,6 +151,7 @@ func (p *PodTransitionRuleEventHandler) Update(e event.UpdateEvent, q workqueue.
 	oldPodTransitionRule := e.ObjectOld.(*appsv1alpha1.PodTransitionRule)
 	newPodTransitionRule := e.ObjectNew.(*appsv1alpha1.PodTransitionRule)
 	if equality.Semantic.DeepEqual(oldPodTransitionRule.Spec, newPodTransitionRule.Spec) &&
+		equality.Semantic.DeepEqual(oldPodTransitionRule.Annotations, newPodTransitionRule.Annotations) &&
 		equality.Semantic.DeepEqual(oldPodTransitionRule.Status, newPodTransitionRule.Status) &&
 		newPodTransitionRule.DeletionTimestamp == nil {
 		return
@@ -169,3 +174,76 @@ func (p *PodTransitionRuleEventHandler) Delete(e event.DeleteEvent, q workqueue.
 
 func (p *PodTransitionRuleEventHandler) Generic(e event.GenericEvent, q workqueue.RateLimitingInterface) {
 }
+
+// DependencyEventHandler enqueues PodTransitionRules whose dependency rules depend on the changed workload
+// of GroupKind, so that pods rejected by them are processed again once the workload changes
+type DependencyEventHandler struct {
+	GroupKind schema.GroupKind
+
+	// client and logger will be injected
+	client client.Client
+	logger logr.Logger
+}
+
+func (d *DependencyEventHandler) InjectClient(c client.Client) error {
+	d.client = c
+	return nil
+}
+
+func (d *DependencyEventHandler) InjectLogger(l logr.Logger) error {
+	d.logger = l.WithName("podtransitionrule").WithName("dependencyEventHandler")
+	return nil
+}
+
+func (d *DependencyEventHandler) Create(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	d.enqueueDependents(e.Object, q)
+}
+
+func (d *DependencyEventHandler) Update(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	d.enqueueDependents(e.ObjectNew, q)
+}
+
+func (d *DependencyEventHandler) Delete(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	d.enqueueDependents(e.Object, q)
+}
+
+func (d *DependencyEventHandler) Generic(e event.GenericEvent, q workqueue.RateLimitingInterface) {
+}
+
+func (d *DependencyEventHandler) enqueueDependents(obj client.Object, q workqueue.RateLimitingInterface) {
+	if obj == nil {
+		return
+	}
+	podTransitionRuleList := &appsv1alpha1.PodTransitionRuleList{}
+	if err := d.client.List(context.TODO(), podTransitionRuleList, client.InNamespace(obj.GetNamespace())); err != nil {
+		d.logger.Error(err, "failed to list podtransitionrules for dependency", "obj", commonutils.ObjectKeyString(obj))
+		return
+	}
+	for i := range podTransitionRuleList.Items {
+		rs := &podTransitionRuleList.Items[i]
+		if dependsOn(rs, d.GroupKind, obj.GetName()) {
+			q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+				Name:      rs.Name,
+				Namespace: rs.Namespace,
+			}})
+		}
+	}
+}
+
+func dependsOn(rs *appsv1alpha1.PodTransitionRule, gk schema.GroupKind, name string) bool {
+	extendedRules, err := podtransitionruleutils.GetExtendedRules(rs)
+	if err != nil {
+		return false
+	}
+	for _, extended := range extendedRules {
+		dep := extended.Dependency
+		if dep == nil || dep.Name != name || dep.Kind != gk.Kind {
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(dep.APIVersion)
+		if err == nil && gv.Group == gk.Group {
+			return true
+		}
+	}
+	return false
+}
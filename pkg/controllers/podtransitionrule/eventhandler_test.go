@@ -0,0 +1,43 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podtransitionrule
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+func TestDependsOn(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	rs := &appsv1alpha1.PodTransitionRule{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default",
+		Name:      "foo",
+		Annotations: map[string]string{
+			kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules: `{"dependency": {"dependency": {"apiVersion": "apps/v1", "kind": "Deployment", "name": "db", "updated": true}}}`,
+		},
+	}}
+	g.Expect(dependsOn(rs, schema.GroupKind{Group: "apps", Kind: "Deployment"}, "db")).Should(gomega.BeTrue())
+	g.Expect(dependsOn(rs, schema.GroupKind{Group: "apps", Kind: "Deployment"}, "cache")).Should(gomega.BeFalse())
+	g.Expect(dependsOn(rs, schema.GroupKind{Group: "apps", Kind: "StatefulSet"}, "db")).Should(gomega.BeFalse())
+	g.Expect(dependsOn(rs, schema.GroupKind{Group: appsv1alpha1.SchemeGroupVersion.Group, Kind: "Deployment"}, "db")).Should(gomega.BeFalse())
+}
@@ -40,6 +40,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"kusionstack.io/kuperator/pkg/controllers/podtransitionrule/processor"
+	processorrules "kusionstack.io/kuperator/pkg/controllers/podtransitionrule/processor/rules"
 	"kusionstack.io/kuperator/pkg/controllers/podtransitionrule/register"
 	podtransitionruleutils "kusionstack.io/kuperator/pkg/controllers/podtransitionrule/utils"
 	controllerutils "kusionstack.io/kuperator/pkg/controllers/utils"
@@ -82,6 +83,14 @@ func addToMgr(mgr manager.Manager, r reconcile.Reconciler) (controller.Controlle
 		return c, err
 	}
 
+	// Watch for changes to workloads which PodTransitionRules depend on
+	for gk, obj := range processorrules.WatchedDependencyKinds {
+		err = c.Watch(&source.Kind{Type: obj}, &DependencyEventHandler{GroupKind: gk})
+		if err != nil {
+			return c, err
+		}
+	}
+
 	err = c.Watch(&source.Channel{Source: NewWebhookGenericEventChannel()}, &handler.EnqueueRequestForObject{})
 	if err != nil {
 		return c, err
@@ -99,6 +108,10 @@ type PodTransitionRuleReconciler struct {
 // +kubebuilder:rbac:groups=apps.kusionstack.io,resources=podtransitionrules/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=apps.kusionstack.io,resources=podtransitionrules/finalizers,verbs=update
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=apps.kusionstack.io,resources=collasets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;update;patch
 
 func (r *PodTransitionRuleReconciler) Reconcile(ctx context.Context, request reconcile.Request) (result reconcile.Result, reconcileErr error) {
@@ -186,6 +199,8 @@ func (r *PodTransitionRuleReconciler) Reconcile(ctx context.Context, request rec
 
 	// TODO: Sync WebhookStates in Details
 
+	r.recordAuditRejections(instance, targetPods, details)
+
 	detailList := make([]*appsv1alpha1.PodTransitionDetail, 0, len(details))
 	keys := make([]string, 0, len(details))
 	for key := range details {
@@ -264,7 +279,7 @@ func (r *PodTransitionRuleReconciler) process(
 		currentStage := stage
 		go func() {
 			defer wg.Done()
-			res := processor.NewRuleProcessor(r.Client, currentStage, rs, r.Logger).Process(pods)
+			res := processor.NewRuleProcessor(r.Client, r.APIReader, currentStage, rs, r.Logger).Process(pods)
 			mu.Lock()
 			defer mu.Unlock()
 			if res.Interval != nil {
@@ -282,6 +297,10 @@ func (r *PodTransitionRuleReconciler) process(
 		}()
 	}
 	wg.Wait()
+	// stages are processed concurrently, sort rule states to keep the status stable
+	sort.Slice(ruleStates, func(i, j int) bool {
+		return ruleStates[i].Name < ruleStates[j].Name
+	})
 	return shouldRetry, interval, details, ruleStates
 }
 
@@ -322,15 +341,23 @@ func updateDetail(details map[string]*appsv1alpha1.PodTransitionDetail, passRule
 		detail, ok := details[po]
 		if !ok {
 			detail = &appsv1alpha1.PodTransitionDetail{
-				Name:  po,
-				Stage: stage,
+				Name:   po,
+				Stage:  stage,
+				Passed: true,
 			}
 		}
 		detail.PassedRules = append(detail.PassedRules, rules.List()...)
 		if rejectInfo != nil {
 			detail.RejectInfo = append(detail.RejectInfo, *rejectInfo)
+			detail.Passed = false
+		}
+		// rejections of rules in audit mode are recorded without blocking the pod
+		for _, rej := range passRules.Audited[po] {
+			detail.RejectInfo = append(detail.RejectInfo, appsv1alpha1.RejectInfo{
+				RuleName: rej.RuleName,
+				Reason:   auditReasonPrefix + rej.Reason,
+			})
 		}
-		detail.Passed = detail.RejectInfo == nil || len(detail.RejectInfo) == 0
 		details[po] = detail
 	}
 }
@@ -22,6 +22,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"kusionstack.io/kuperator/pkg/controllers/podtransitionrule/checker"
+	"kusionstack.io/kuperator/pkg/controllers/podtransitionrule/processor/rules"
 	"kusionstack.io/kuperator/pkg/controllers/podtransitionrule/register"
 )
 
@@ -47,6 +48,12 @@ func AddUnAvailableFunc(f func(pod *corev1.Pod) (bool, *int64)) {
 	register.UnAvailableFuncList = append(register.UnAvailableFuncList, f)
 }
 
+// AddRuler registers the factory of custom rules with the kind, custom rules are configured by
+// "custom" definitions in the extended rules annotation of PodTransitionRule
+func AddRuler(kind string, factory rules.RulerFactory) {
+	rules.RegisterRuler(kind, factory)
+}
+
 func newPodTransitionRuleManager() ManagerInterface {
 	return &rsManager{
 		Register: register.DefaultRegister(),
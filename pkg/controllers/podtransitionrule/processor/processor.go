@@ -32,14 +32,16 @@ import (
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
 	"kusionstack.io/kuperator/pkg/controllers/podtransitionrule/processor/rules"
 	"kusionstack.io/kuperator/pkg/controllers/podtransitionrule/register"
 	"kusionstack.io/kuperator/pkg/controllers/podtransitionrule/utils"
 )
 
-func NewRuleProcessor(client client.Client, stage string, podTransitionRule *appsv1alpha1.PodTransitionRule, log logr.Logger) *Processor {
+func NewRuleProcessor(client client.Client, reader client.Reader, stage string, podTransitionRule *appsv1alpha1.PodTransitionRule, log logr.Logger) *Processor {
 	processor := &Processor{
 		client:            client,
+		reader:            reader,
 		stage:             stage,
 		podTransitionRule: podTransitionRule,
 		Logger:            log,
@@ -51,6 +53,7 @@ func NewRuleProcessor(client client.Client, stage string, podTransitionRule *app
 type Processor struct {
 	podTransitionRule *appsv1alpha1.PodTransitionRule
 	client            client.Client
+	reader            client.Reader
 	stage             string
 	register.Policy
 	logr.Logger
@@ -59,9 +62,25 @@ type Processor struct {
 func (p *Processor) Process(targets map[string]*corev1.Pod) *ProcessResult {
 	// some pods on check stage
 
+	extendedRules, err := utils.GetExtendedRules(p.podTransitionRule)
+	if err != nil {
+		p.Error(err, "fail to get extended rules", "PodTransitionRule", p.podTransitionRule.Name)
+	}
+
+	// member rules of groups are only evaluated in groups
+	groupMembers := sets.NewString()
+	for _, extended := range extendedRules {
+		if extended != nil && extended.Group != nil {
+			groupMembers.Insert(extended.Group.Rules...)
+		}
+	}
+
 	var effectiveRules utils.Rules
 	for i := range p.podTransitionRule.Spec.Rules {
-		if p.podTransitionRule.Spec.Rules[i].Disabled || needSkip(&p.podTransitionRule.Spec.Rules[i]) {
+		if groupMembers.Has(p.podTransitionRule.Spec.Rules[i].Name) {
+			continue
+		}
+		if p.podTransitionRule.Spec.Rules[i].Disabled || needSkip(&p.podTransitionRule.Spec.Rules[i], extendedRules[p.podTransitionRule.Spec.Rules[i].Name]) {
 			continue
 		}
 		if p.podTransitionRule.Spec.Rules[i].Stage == nil && register.GetRuleStage(&p.podTransitionRule.Spec.Rules[i].TransitionRuleDefinition) == p.stage {
@@ -85,11 +104,12 @@ func (p *Processor) Process(targets map[string]*corev1.Pod) *ProcessResult {
 	}
 
 	if processingPods.Len() == 0 {
-		return &ProcessResult{}
+		return &ProcessResult{RuleStates: p.idleRuleStates(effectiveRules, extendedRules)}
 	}
 
 	passInfo := map[string]sets.String{}
 	rejected := map[string]RejectInfo{}
+	audited := map[string][]RejectInfo{}
 	var ruleStates []*appsv1alpha1.RuleState
 
 	minInterval := time.Duration(math.MaxInt32) * time.Second
@@ -101,7 +121,12 @@ func (p *Processor) Process(targets map[string]*corev1.Pod) *ProcessResult {
 
 	for _, rule := range effectiveRules {
 		// get rule processor
-		ruler := rules.GetRuler(rule, p.client)
+		var ruler rules.Ruler
+		if extended := extendedRules[rule.Name]; extended != nil && extended.Group != nil {
+			ruler = p.groupRuler(rule.Name, extended.Group, extendedRules)
+		} else {
+			ruler = rules.GetRuler(rule, extended, p.client, p.reader)
+		}
 		if ruler == nil {
 			continue
 		}
@@ -135,11 +160,22 @@ func (p *Processor) Process(targets map[string]*corev1.Pod) *ProcessResult {
 		}
 
 		// do rule processor
-		result := ruler.Filter(p.podTransitionRule, targets, processingPods)
+		subjects := processingPods
+		result := ruler.Filter(p.podTransitionRule, targets, subjects)
 
 		if result.RuleState != nil {
 			ruleStates = append(ruleStates, result.RuleState)
 		}
+		for _, memberName := range sets.StringKeySet(result.Members).List() {
+			memberResult := result.Members[memberName]
+			if memberResult.RuleState != nil {
+				ruleStates = append(ruleStates, memberResult.RuleState)
+			}
+			// record passed members, so that they are not evaluated again, e.g. by webhooks
+			for passPodName := range memberResult.Passed {
+				passInfo[passPodName].Insert(memberName)
+			}
+		}
 
 		if result.Err != nil {
 			retry = true
@@ -156,6 +192,16 @@ func (p *Processor) Process(targets map[string]*corev1.Pod) *ProcessResult {
 			passInfo[passPodName].Insert(rule.Name)
 		}
 
+		if utils.GetRuleMode(p.podTransitionRule, extendedRules[rule.Name]) == kuperatorv1alpha1.RuleModeAudit {
+			// rejected pods go on to the next rules as passed, but the rule is not recorded as passed,
+			// so that they are evaluated again and rejections keep visible
+			for podName, reason := range result.Rejected {
+				audited[podName] = append(audited[podName], RejectInfo{Reason: reason, RuleName: rule.Name})
+			}
+			processingPods = subjects.Union(skipPods)
+			continue
+		}
+
 		for podName, reason := range result.Rejected {
 			rejected[podName] = RejectInfo{Reason: reason, RuleName: rule.Name}
 		}
@@ -169,6 +215,7 @@ func (p *Processor) Process(targets map[string]*corev1.Pod) *ProcessResult {
 
 	res := &ProcessResult{
 		Rejected:   rejected,
+		Audited:    audited,
 		PassRules:  passInfo,
 		Retry:      retry,
 		RuleStates: ruleStates,
@@ -180,8 +227,53 @@ func (p *Processor) Process(targets map[string]*corev1.Pod) *ProcessResult {
 	return res
 }
 
+// groupRuler builds the group with member rules in spec, disabled or skipped members are taken as
+// rules without definition
+func (p *Processor) groupRuler(name string, group *kuperatorv1alpha1.RuleGroup, extendedRules map[string]*kuperatorv1alpha1.ExtendedRuleDefinition) rules.Ruler {
+	ruler := &rules.GroupRuler{Name: name, MinPassed: int(group.MinPassed)}
+	if ruler.MinPassed <= 0 {
+		ruler.MinPassed = 1
+	}
+	for _, memberName := range group.Rules {
+		member := rules.GroupMember{Name: memberName}
+		for i := range p.podTransitionRule.Spec.Rules {
+			rule := &p.podTransitionRule.Spec.Rules[i]
+			if rule.Name != memberName || rule.Disabled || needSkip(rule, extendedRules[memberName]) {
+				continue
+			}
+			member.Ruler = rules.GetRuler(rule, extendedRules[memberName], p.client, p.reader)
+		}
+		ruler.Members = append(ruler.Members, member)
+	}
+	return ruler
+}
+
+// idleRuleStates returns states which should be kept even if no pod is in stage
+func (p *Processor) idleRuleStates(effectiveRules utils.Rules, extendedRules map[string]*kuperatorv1alpha1.ExtendedRuleDefinition) []*appsv1alpha1.RuleState {
+	var ruleStates []*appsv1alpha1.RuleState
+	for _, rule := range effectiveRules {
+		extended := extendedRules[rule.Name]
+		if extended == nil {
+			continue
+		}
+		var state *appsv1alpha1.RuleState
+		switch {
+		case extended.RateLimit != nil:
+			state = rules.RateLimitState(p.podTransitionRule, rule.Name, extended.RateLimit)
+		case extended.ManualApproval != nil:
+			state = rules.ManualApprovalState(p.podTransitionRule, rule.Name)
+		}
+		if state != nil {
+			ruleStates = append(ruleStates, state)
+		}
+	}
+	return ruleStates
+}
+
 type ProcessResult struct {
 	Rejected map[string]RejectInfo
+	// pod:rejections of rules in audit mode, pods are treated as passed
+	Audited map[string][]RejectInfo
 	// pod:rules
 	PassRules map[string]sets.String
 	Retry     bool
@@ -199,12 +291,26 @@ const EnvSkipTransitionRules = "SKIP_POD_TRANSITION_RULES"
 
 var SkipTransitionRules = sets.NewString()
 
-func needSkip(rule *appsv1alpha1.TransitionRule) bool {
-	typRule := reflect.TypeOf(rule.TransitionRuleDefinition)
-	valRule := reflect.ValueOf(rule.TransitionRuleDefinition)
+func needSkip(rule *appsv1alpha1.TransitionRule, extended *kuperatorv1alpha1.ExtendedRuleDefinition) bool {
+	if hasSkipDefinition(rule.TransitionRuleDefinition) {
+		return true
+	}
+	if extended == nil {
+		return false
+	}
+	// custom rules can be skipped by kind too
+	if extended.Custom != nil && SkipTransitionRules.Has(extended.Custom.Kind) {
+		return true
+	}
+	return hasSkipDefinition(*extended)
+}
+
+func hasSkipDefinition(def interface{}) bool {
+	typRule := reflect.TypeOf(def)
+	valRule := reflect.ValueOf(def)
 	fCount := valRule.NumField()
 	for i := 0; i < fCount; i++ {
-		if valRule.Field(i).IsNil() {
+		if valRule.Field(i).Kind() != reflect.Ptr || valRule.Field(i).IsNil() {
 			continue
 		}
 
@@ -0,0 +1,208 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+const testStage = "PreCheck"
+
+// allInStagePolicy puts all pods in testStage
+type allInStagePolicy struct{}
+
+func (allInStagePolicy) Stage(obj client.Object) string                          { return testStage }
+func (allInStagePolicy) InStage(obj client.Object, key string) bool              { return key == testStage }
+func (allInStagePolicy) GetStages() []string                                     { return []string{testStage} }
+func (allInStagePolicy) Conditions(obj client.Object) []string                   { return nil }
+func (allInStagePolicy) MatchConditions(obj client.Object, c ...string) []string { return nil }
+
+func TestProcessAuditMode(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	stage := testStage
+	labelCheck := func(name, key string) appsv1alpha1.TransitionRule {
+		return appsv1alpha1.TransitionRule{
+			Name:  name,
+			Stage: &stage,
+			TransitionRuleDefinition: appsv1alpha1.TransitionRuleDefinition{
+				LabelCheck: &appsv1alpha1.LabelCheckRule{
+					Requires: &metav1.LabelSelector{MatchLabels: map[string]string{key: "true"}},
+				},
+			},
+		}
+	}
+	rs := &appsv1alpha1.PodTransitionRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rule",
+			Namespace: "default",
+			Annotations: map[string]string{
+				kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules: `{"audit": {"mode": "Audit"}}`,
+			},
+		},
+		Spec: appsv1alpha1.PodTransitionRuleSpec{
+			Rules: []appsv1alpha1.TransitionRule{labelCheck("audit", "a"), labelCheck("enforce", "b")},
+		},
+	}
+	newPod := func(name string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels}}
+	}
+	targets := map[string]*corev1.Pod{
+		"both":    newPod("both", map[string]string{"a": "true", "b": "true"}),
+		"none":    newPod("none", nil),
+		"enforce": newPod("enforce", map[string]string{"b": "true"}),
+	}
+
+	p := NewRuleProcessor(nil, nil, testStage, rs, log.Log)
+	p.Policy = allInStagePolicy{}
+	res := p.Process(targets)
+	g.Expect(res.Rejected).Should(gomega.HaveLen(1))
+	g.Expect(res.Rejected["none"].RuleName).Should(gomega.Equal("enforce"))
+	g.Expect(res.Audited).Should(gomega.HaveLen(2))
+	g.Expect(res.Audited["none"][0].RuleName).Should(gomega.Equal("audit"))
+	g.Expect(res.Audited["enforce"][0].RuleName).Should(gomega.Equal("audit"))
+	g.Expect(res.PassRules["both"].List()).Should(gomega.Equal([]string{"audit", "enforce"}))
+	// the audit rule is not recorded as passed to be evaluated again
+	g.Expect(res.PassRules["enforce"].List()).Should(gomega.Equal([]string{"enforce"}))
+
+	// audit all rules by the annotation of PodTransitionRule
+	rs.Annotations = map[string]string{kuperatorv1alpha1.AnnotationPodTransitionRuleMode: string(kuperatorv1alpha1.RuleModeAudit)}
+	res = p.Process(targets)
+	g.Expect(res.Rejected).Should(gomega.BeEmpty())
+	g.Expect(res.Audited["none"]).Should(gomega.HaveLen(2))
+}
+
+func TestProcessManualApproval(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	stage := testStage
+	rs := &appsv1alpha1.PodTransitionRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rule",
+			Namespace: "default",
+			Annotations: map[string]string{
+				kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules: `{"approve": {"manualApproval": {}}}`,
+				kuperatorv1alpha1.AnnotationPodTransitionRuleApprovals:     `{"approve": [{"id": "a", "pods": ["pod-a"]}, {"id": "b", "count": 1}]}`,
+			},
+		},
+		Spec: appsv1alpha1.PodTransitionRuleSpec{
+			Rules: []appsv1alpha1.TransitionRule{{Name: "approve", Stage: &stage}},
+		},
+	}
+	newPod := func(name string, annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: annotations}}
+	}
+	targets := map[string]*corev1.Pod{
+		"pod-a": newPod("pod-a", nil),
+		"pod-b": newPod("pod-b", nil),
+		"pod-c": newPod("pod-c", nil),
+		// skipping the rule by annotation needs no approval
+		"pod-d": newPod("pod-d", map[string]string{appsv1alpha1.AnnotationPodSkipRuleConditions: `{"skipRules": ["approve"]}`}),
+	}
+
+	p := NewRuleProcessor(nil, nil, testStage, rs, log.Log)
+	p.Policy = allInStagePolicy{}
+	res := p.Process(targets)
+	g.Expect(res.Rejected).Should(gomega.HaveLen(1))
+	g.Expect(res.Rejected).Should(gomega.HaveKey("pod-c"))
+	g.Expect(res.PassRules["pod-a"].List()).Should(gomega.Equal([]string{"approve"}))
+	g.Expect(res.PassRules["pod-b"].List()).Should(gomega.Equal([]string{"approve"}))
+	g.Expect(res.RuleStates).Should(gomega.HaveLen(1))
+	state := res.RuleStates[0]
+	g.Expect(state.WebhookStatus.TaskStates).Should(gomega.HaveLen(1))
+	g.Expect(state.WebhookStatus.TaskStates[0].Processing).Should(gomega.Equal([]string{"pod-c"}))
+	g.Expect(state.WebhookStatus.History[1].Approved).Should(gomega.Equal([]string{"pod-b"}))
+
+	// pods bound to the approval by count are kept, even if no pod is in stage
+	rs.Status.RuleStates = res.RuleStates
+	res = p.Process(map[string]*corev1.Pod{})
+	g.Expect(res.RuleStates).Should(gomega.HaveLen(1))
+	g.Expect(res.RuleStates[0].WebhookStatus.History[1].Approved).Should(gomega.Equal([]string{"pod-b"}))
+	g.Expect(res.RuleStates[0].WebhookStatus.TaskStates).Should(gomega.BeEmpty())
+
+	// the count is used up
+	res = p.Process(targets)
+	g.Expect(res.Rejected).Should(gomega.HaveKey("pod-c"))
+	g.Expect(res.PassRules["pod-b"].List()).Should(gomega.Equal([]string{"approve"}))
+}
+
+func TestProcessRuleGroup(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	stage := testStage
+	labelRule := func(name, label string) appsv1alpha1.TransitionRule {
+		return appsv1alpha1.TransitionRule{
+			Name: name,
+			TransitionRuleDefinition: appsv1alpha1.TransitionRuleDefinition{
+				LabelCheck: &appsv1alpha1.LabelCheckRule{
+					Requires: &metav1.LabelSelector{MatchLabels: map[string]string{label: "true"}},
+				},
+			},
+		}
+	}
+	rs := &appsv1alpha1.PodTransitionRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rule",
+			Namespace: "default",
+			Annotations: map[string]string{
+				kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules: `{"approval-or-emergency": {"group": {"rules": ["approval", "emergency"]}}}`,
+			},
+		},
+		Spec: appsv1alpha1.PodTransitionRuleSpec{
+			Rules: []appsv1alpha1.TransitionRule{
+				labelRule("approval", "approved"),
+				labelRule("emergency", "emergency"),
+				{Name: "approval-or-emergency", Stage: &stage},
+			},
+		},
+	}
+	newPod := func(name string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels}}
+	}
+	targets := map[string]*corev1.Pod{
+		"pod-a": newPod("pod-a", map[string]string{"approved": "true"}),
+		"pod-b": newPod("pod-b", map[string]string{"emergency": "true"}),
+		"pod-c": newPod("pod-c", map[string]string{"approved": "true", "emergency": "true"}),
+		"pod-d": newPod("pod-d", nil),
+	}
+
+	p := NewRuleProcessor(nil, nil, testStage, rs, log.Log)
+	p.Policy = allInStagePolicy{}
+	res := p.Process(targets)
+	g.Expect(res.Rejected).Should(gomega.HaveLen(1))
+	g.Expect(res.Rejected["pod-d"].RuleName).Should(gomega.Equal("approval-or-emergency"))
+	g.Expect(res.Rejected["pod-d"].Reason).Should(gomega.ContainSubstring("passed 0 of 2 rules"))
+	g.Expect(res.Rejected["pod-d"].Reason).Should(gomega.ContainSubstring("approval: "))
+	g.Expect(res.Rejected["pod-d"].Reason).Should(gomega.ContainSubstring("emergency: "))
+	g.Expect(res.PassRules["pod-a"].List()).Should(gomega.Equal([]string{"approval", "approval-or-emergency"}))
+	g.Expect(res.PassRules["pod-b"].List()).Should(gomega.Equal([]string{"approval-or-emergency", "emergency"}))
+	// pods passing the group are not evaluated by the rest members
+	g.Expect(res.PassRules["pod-c"].List()).Should(gomega.Equal([]string{"approval", "approval-or-emergency"}))
+
+	// both members are required
+	rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"approval-or-emergency": {"group": {"rules": ["approval", "emergency"], "minPassed": 2}}}`
+	res = p.Process(targets)
+	g.Expect(res.Rejected).Should(gomega.HaveLen(3))
+	g.Expect(res.Rejected["pod-a"].Reason).Should(gomega.ContainSubstring("passed 1 of 2 rules, 2 required, emergency: "))
+	g.Expect(res.PassRules["pod-c"].List()).Should(gomega.Equal([]string{"approval", "approval-or-emergency", "emergency"}))
+}
@@ -17,12 +17,19 @@ limitations under the License.
 package rules
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -35,23 +42,75 @@ type AvailableRuler struct {
 
 	MinAvailableValue   *intstr.IntOrString
 	MaxUnavailableValue *intstr.IntOrString
+	// TopologyKey applies the policy to each topology domain of targets if set
+	TopologyKey string
 
 	Client client.Client
 }
 
+// unknownTopologyDomain is the domain of pods whose node and labels have no topology key, e.g. unscheduled pods
+const unknownTopologyDomain = "<unknown>"
+
 // Filter unavailable pods and try approve available pods as much as possible
 func (r *AvailableRuler) Filter(podTransitionRule *appsv1alpha1.PodTransitionRule, targets map[string]*corev1.Pod, subjects sets.String) *FilterResult {
-	effectiveTargets := sets.NewString()
 	pass := sets.NewString()
 	rejects := map[string]string{}
-	for _, t := range targets {
-		effectiveTargets.Insert(t.Name)
+	// replicas of owners not created yet are unavailable too. In topology mode they are split across
+	// domains by the number of targets, since we can not know where they will be scheduled
+	uncreatedReplicas := r.uncreatedReplicas(targets)
+
+	if r.TopologyKey == "" {
+		effectiveTargets := sets.NewString()
+		for _, t := range targets {
+			effectiveTargets.Insert(t.Name)
+		}
+		return r.filterDomain(podTransitionRule, targets, effectiveTargets, subjects, uncreatedReplicas, "", pass, rejects)
 	}
-	maxUnavailableQuota := len(effectiveTargets)
+
+	domainTargets, domainSubjects := map[string]sets.String{}, map[string]sets.String{}
+	for podName, domain := range r.topologyDomains(targets) {
+		if _, ok := domainTargets[domain]; !ok {
+			domainTargets[domain] = sets.NewString()
+			domainSubjects[domain] = sets.NewString()
+		}
+		domainTargets[domain].Insert(podName)
+		if subjects.Has(podName) {
+			domainSubjects[domain].Insert(podName)
+		}
+	}
+
+	domainUncreated := splitUncreated(uncreatedReplicas, domainTargets, len(targets))
+	var interval *time.Duration
+	var errs []error
+	for _, domain := range sets.StringKeySet(domainTargets).List() {
+		if domainSubjects[domain].Len() == 0 {
+			continue
+		}
+		res := r.filterDomain(podTransitionRule, targets, domainTargets[domain], domainSubjects[domain], domainUncreated[domain], domain, pass, rejects)
+		if res.Err != nil {
+			errs = append(errs, res.Err)
+		}
+		if res.Interval != nil && (interval == nil || *res.Interval < *interval) {
+			interval = res.Interval
+		}
+	}
+	return &FilterResult{Passed: pass, Rejected: rejects, Interval: interval, Err: utilerrors.NewAggregate(errs)}
+}
+
+// filterDomain applies the policy to effectiveTargets, which are all targets or targets in the topology domain
+func (r *AvailableRuler) filterDomain(podTransitionRule *appsv1alpha1.PodTransitionRule, targets map[string]*corev1.Pod, effectiveTargets, subjects sets.String,
+	uncreatedReplicas int, domain string, pass sets.String, rejects map[string]string) *FilterResult {
+	scope := ""
+	if domain != "" {
+		scope = fmt.Sprintf(" in topology domain %s=%s", r.TopologyKey, domain)
+	}
+	// quotas are scaled on desired replicas, and uncreated replicas are counted as unavailable
+	desiredReplicas := len(effectiveTargets) + uncreatedReplicas
+	maxUnavailableQuota := desiredReplicas
 	allowUnavailable := maxUnavailableQuota
 	minAvailableQuota := 0
 	if r.MaxUnavailableValue != nil {
-		quota, err := intstr.GetScaledValueFromIntOrPercent(r.MaxUnavailableValue, len(effectiveTargets), true)
+		quota, err := intstr.GetScaledValueFromIntOrPercent(r.MaxUnavailableValue, desiredReplicas, true)
 		if err != nil {
 			return rejectAllWithErr(subjects, pass, rejects, "[%s] fail to get int value from raw max unavailable value(%s), error: %v", r.Name, r.MaxUnavailableValue.String(), err)
 		}
@@ -60,14 +119,13 @@ func (r *AvailableRuler) Filter(podTransitionRule *appsv1alpha1.PodTransitionRul
 	}
 
 	if r.MinAvailableValue != nil {
-		quota, err := intstr.GetScaledValueFromIntOrPercent(r.MinAvailableValue, len(effectiveTargets), false)
+		quota, err := intstr.GetScaledValueFromIntOrPercent(r.MinAvailableValue, desiredReplicas, false)
 		if err != nil {
-			return rejectAllWithErr(subjects, pass, rejects, "[%s] fail to get int value from raw min available value(%s), error: %v", r.Name, r.MaxUnavailableValue.String(), err)
+			return rejectAllWithErr(subjects, pass, rejects, "[%s] fail to get int value from raw min available value(%s), error: %v", r.Name, r.MinAvailableValue.String(), err)
 		}
 		minAvailableQuota = quota
 	}
-	// TODO: UncreatedReplicas
-	// allowUnavailable -= uncreatedReplicas
+	allowUnavailable -= uncreatedReplicas
 	allAvailableSize := 0
 	var minTimeLeft *int64
 	// filter unavailable pods
@@ -117,20 +175,103 @@ func (r *AvailableRuler) Filter(podTransitionRule *appsv1alpha1.PodTransitionRul
 	}
 
 	for podName := range keepMinAvailablePods {
-		rejects[podName] = fmt.Sprintf("blocked by min available policy: [min available]=%d/%d, [current keep available]=%d/%d", minAvailableQuota, len(effectiveTargets), allAvailableSize, len(effectiveTargets))
+		rejects[podName] = fmt.Sprintf("blocked by min available policy%s: [min available]=%d/%d, [current keep available]=%d/%d", scope, minAvailableQuota, desiredReplicas, allAvailableSize, desiredReplicas)
 	}
 	for podName := range rejectByMaxUnavailablePods {
-		rejects[podName] = fmt.Sprintf("[%s] blocked by max unavailable policy: [max unavailable]=%d/%d, [current unavailable]=%d/%d", r.Name, maxUnavailableQuota, len(effectiveTargets), len(effectiveTargets)-allAvailableSize, len(effectiveTargets))
+		rejects[podName] = fmt.Sprintf("[%s] blocked by max unavailable policy%s: [max unavailable]=%d/%d, [current unavailable]=%d/%d", r.Name, scope, maxUnavailableQuota, desiredReplicas, desiredReplicas-allAvailableSize, desiredReplicas)
 	}
 
 	if minTimeLeft != nil {
 		interval := time.Duration(*minTimeLeft) * time.Second
-		return &FilterResult{Passed: pass, Rejected: rejects, Interval: &interval, Err: fmt.Errorf("[%s] pods not finish warm up until %d seconds later", r.Name, minTimeLeft)}
+		return &FilterResult{Passed: pass, Rejected: rejects, Interval: &interval, Err: fmt.Errorf("[%s] pods not finish warm up until %d seconds later", r.Name, *minTimeLeft)}
 	}
 
 	return &FilterResult{Passed: pass, Rejected: rejects}
 }
 
+// topologyDomains returns the topology domain of each target. The domain is read from labels of the node
+// first, and then labels of the pod
+func (r *AvailableRuler) topologyDomains(targets map[string]*corev1.Pod) map[string]string {
+	domains := map[string]string{}
+	nodeDomains := map[string]string{}
+	for podName, pod := range targets {
+		domain, ok := "", false
+		if nodeName := pod.Spec.NodeName; nodeName != "" && r.Client != nil {
+			if domain, ok = nodeDomains[nodeName]; !ok {
+				node := &corev1.Node{}
+				if err := r.Client.Get(context.TODO(), types.NamespacedName{Name: nodeName}, node); err != nil {
+					klog.Warningf("[%s] fail to get node %s of pod %s/%s: %v", r.Name, nodeName, pod.Namespace, pod.Name, err)
+				}
+				domain = node.Labels[r.TopologyKey]
+				nodeDomains[nodeName] = domain
+			}
+		}
+		if domain == "" {
+			domain = pod.Labels[r.TopologyKey]
+		}
+		if domain == "" {
+			domain = unknownTopologyDomain
+		}
+		domains[podName] = domain
+	}
+	return domains
+}
+
+// splitUncreated splits uncreated replicas across topology domains in proportion to their targets. The remainder
+// goes to domains with more targets first.
+func splitUncreated(uncreated int, domainTargets map[string]sets.String, total int) map[string]int {
+	shares := map[string]int{}
+	if uncreated == 0 || total == 0 {
+		return shares
+	}
+	domains := sets.StringKeySet(domainTargets).List()
+	assigned := 0
+	for _, domain := range domains {
+		shares[domain] = uncreated * domainTargets[domain].Len() / total
+		assigned += shares[domain]
+	}
+	sort.SliceStable(domains, func(i, j int) bool {
+		return domainTargets[domains[i]].Len() > domainTargets[domains[j]].Len()
+	})
+	for i := 0; assigned < uncreated; i++ {
+		shares[domains[i%len(domains)]]++
+		assigned++
+	}
+	return shares
+}
+
+// uncreatedReplicas returns the number of replicas not created yet of CollaSets controlling targets, by replicas
+// in their spec and status
+func (r *AvailableRuler) uncreatedReplicas(targets map[string]*corev1.Pod) int {
+	if r.Client == nil {
+		return 0
+	}
+	owners := map[types.UID]types.NamespacedName{}
+	for _, pod := range targets {
+		ref := metav1.GetControllerOf(pod)
+		if ref == nil || ref.Kind != "CollaSet" || !strings.HasPrefix(ref.APIVersion, appsv1alpha1.SchemeGroupVersion.Group+"/") {
+			continue
+		}
+		owners[ref.UID] = types.NamespacedName{Namespace: pod.Namespace, Name: ref.Name}
+	}
+
+	uncreated := 0
+	for uid, key := range owners {
+		cls := &appsv1alpha1.CollaSet{}
+		if err := r.Client.Get(context.TODO(), key, cls); err != nil {
+			klog.Warningf("[%s] fail to get CollaSet %s, ignore its uncreated replicas: %v", r.Name, key, err)
+			continue
+		}
+		if cls.UID != uid || cls.Spec.Replicas == nil {
+			continue
+		}
+		if *cls.Spec.Replicas > cls.Status.Replicas {
+			uncreated += int(*cls.Spec.Replicas - cls.Status.Replicas)
+		}
+	}
+	return uncreated
+}
+
 func processUnavailableFunc(pod *corev1.Pod) (bool, *int64) {
 	isUnavailable := false
 	var minInterval *int64
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kusionstack.io/kuperator/pkg/controllers/podtransitionrule/register"
+)
+
+func TestAvailableTopology(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	unavailableFuncs := register.UnAvailableFuncList
+	defer func() { register.UnAvailableFuncList = unavailableFuncs }()
+	register.UnAvailableFuncList = []register.UnAvailableFunc{func(pod *corev1.Pod) (bool, *int64) {
+		return pod.Labels["unavailable"] == "true", nil
+	}}
+
+	scheme := runtime.NewScheme()
+	g.Expect(corev1.AddToScheme(scheme)).ShouldNot(gomega.HaveOccurred())
+	g.Expect(appsv1alpha1.AddToScheme(scheme)).ShouldNot(gomega.HaveOccurred())
+
+	replicas := int32(6)
+	cls := &appsv1alpha1.CollaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", UID: "foo-uid"},
+		Spec:       appsv1alpha1.CollaSetSpec{Replicas: &replicas},
+		Status:     appsv1alpha1.CollaSetStatus{Replicas: 6},
+	}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: map[string]string{"zone": "a"}}}
+	objs := []client.Object{cls, node}
+	targets := map[string]*corev1.Pod{}
+	newPod := func(name, nodeName string, labels map[string]string) {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       "default",
+				Labels:          labels,
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(cls, appsv1alpha1.SchemeGroupVersion.WithKind("CollaSet"))},
+			},
+			Spec: corev1.PodSpec{NodeName: nodeName},
+		}
+		targets[name] = pod
+		objs = append(objs, pod)
+	}
+	// zone of pods in zone a comes from the node, and zone b from labels of pods
+	newPod("a-0", "node-a", map[string]string{"unavailable": "true"})
+	newPod("a-1", "node-a", nil)
+	newPod("a-2", "node-a", nil)
+	newPod("b-0", "", map[string]string{"zone": "b"})
+	newPod("b-1", "", map[string]string{"zone": "b"})
+	newPod("b-2", "", map[string]string{"zone": "b"})
+	subjects := sets.StringKeySet(targets)
+
+	maxUnavailable := intstr.FromInt(1)
+	ruler := &AvailableRuler{
+		Name:                "available",
+		MaxUnavailableValue: &maxUnavailable,
+		TopologyKey:         "zone",
+		Client:              fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+	}
+	res := ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Err).ShouldNot(gomega.HaveOccurred())
+	// a-0 is unavailable and uses up the budget of zone a
+	g.Expect(res.Passed.Has("a-0")).Should(gomega.BeTrue())
+	g.Expect(res.Passed.HasAny("a-1", "a-2")).Should(gomega.BeFalse())
+	g.Expect(res.Rejected["a-1"]).Should(gomega.ContainSubstring("zone=a"))
+	// zone b has its own budget
+	g.Expect(res.Passed.Len()).Should(gomega.Equal(2))
+	g.Expect(res.Rejected).Should(gomega.HaveLen(4))
+
+	// without topology key, the budget is shared by all targets
+	maxUnavailable = intstr.FromInt(3)
+	ruler.TopologyKey = ""
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Passed.Len()).Should(gomega.Equal(3))
+
+	// uncreated replicas count against the budget
+	replicas = 8
+	ruler.Client = fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Passed.List()).Should(gomega.Equal([]string{"a-0"}))
+	g.Expect(res.Rejected["a-1"]).Should(gomega.ContainSubstring("[current unavailable]=3/8"))
+
+	// uncreated replicas are split across topology domains, instead of counted against each of them
+	maxUnavailable = intstr.FromInt(2)
+	ruler.TopologyKey = "zone"
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Passed.Len()).Should(gomega.Equal(2))
+	g.Expect(res.Passed.Has("a-0")).Should(gomega.BeTrue())
+	g.Expect(res.Passed.HasAny("a-1", "a-2")).Should(gomega.BeFalse())
+	g.Expect(res.Passed.HasAny("b-0", "b-1", "b-2")).Should(gomega.BeTrue())
+
+	// min available is scaled on desired replicas, and uncreated replicas are not available
+	replicas = 11
+	minAvailable := intstr.FromString("80%")
+	ruler = &AvailableRuler{
+		Name:              "available",
+		MinAvailableValue: &minAvailable,
+		Client:            fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+	}
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Passed.List()).Should(gomega.Equal([]string{"a-0"}))
+	g.Expect(res.Rejected["a-1"]).Should(gomega.ContainSubstring("[min available]=8/11, [current keep available]=5/11"))
+}
+
+func TestSplitUncreated(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	domainTargets := map[string]sets.String{
+		"a": sets.NewString("a-0", "a-1", "a-2"),
+		"b": sets.NewString("b-0"),
+	}
+	g.Expect(splitUncreated(0, domainTargets, 4)).Should(gomega.BeEmpty())
+	g.Expect(splitUncreated(2, domainTargets, 4)).Should(gomega.Equal(map[string]int{"a": 2, "b": 0}))
+	g.Expect(splitUncreated(5, domainTargets, 4)).Should(gomega.Equal(map[string]int{"a": 4, "b": 1}))
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/google/cel-go/cel"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+const (
+	celVarPod     = "pod"
+	celVarOwner   = "owner"
+	celVarTargets = "targets"
+	celVarRule    = "rule"
+
+	// celCostLimit bounds the evaluation of one expression on one pod
+	celCostLimit = 1000000
+)
+
+type CelRuler struct {
+	Name string
+	Cel  *kuperatorv1alpha1.CelRule
+}
+
+// Filter passes pods on which the expression evaluates to true
+func (c *CelRuler) Filter(podTransitionRule *appsv1alpha1.PodTransitionRule, targets map[string]*corev1.Pod, subjects sets.String) *FilterResult {
+	passed := sets.NewString()
+	rejected := map[string]string{}
+	if subjects.Len() == 0 {
+		return &FilterResult{Passed: passed, Rejected: rejected}
+	}
+
+	program, err := CompileCelExpression(c.Cel.Expression)
+	if err != nil {
+		return rejectAllWithErr(subjects, passed, rejected, "[%s] invalid cel expression: %v", c.Name, err)
+	}
+	reason, err := ParseCelReason(c.Cel.Reason)
+	if err != nil {
+		return rejectAllWithErr(subjects, passed, rejected, "[%s] invalid cel reason: %v", c.Name, err)
+	}
+
+	targetObjs := make([]interface{}, 0, len(targets))
+	for _, pod := range targets {
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+		if err != nil {
+			return rejectAllWithErr(subjects, passed, rejected, "[%s] fail to convert pod %s: %v", c.Name, pod.Name, err)
+		}
+		targetObjs = append(targetObjs, obj)
+	}
+
+	for podName := range subjects {
+		pod := targets[podName]
+		vars, err := celVariables(pod, targetObjs)
+		if err != nil {
+			rejected[podName] = fmt.Sprintf("blocked by cel policy, fail to convert pod: %v", err)
+			continue
+		}
+		out, _, err := program.Eval(vars)
+		if err != nil {
+			rejected[podName] = fmt.Sprintf("blocked by cel policy, fail to evaluate %q: %v", c.Cel.Expression, err)
+			continue
+		}
+		if ok, isBool := out.Value().(bool); isBool && ok {
+			passed.Insert(podName)
+			continue
+		}
+
+		vars[celVarRule] = c.Name
+		var buf bytes.Buffer
+		if reason == nil || reason.Execute(&buf, vars) != nil {
+			rejected[podName] = fmt.Sprintf("blocked by cel policy, %q is false", c.Cel.Expression)
+		} else {
+			rejected[podName] = buf.String()
+		}
+	}
+	klog.Infof("finish do cel check %s, passed: %d, rejected: %d", c.Name, len(passed), len(rejected))
+	return &FilterResult{Passed: passed, Rejected: rejected}
+}
+
+// CompileCelExpression compiles the expression of cel rule, which should return bool
+func CompileCelExpression(expression string) (cel.Program, error) {
+	env, err := cel.NewEnv(
+		cel.Variable(celVarPod, cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable(celVarOwner, cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable(celVarTargets, cel.ListType(cel.MapType(cel.StringType, cel.DynType))),
+	)
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("expression should return bool, but returns %s", ast.OutputType())
+	}
+	return env.Program(ast, cel.CostLimit(celCostLimit))
+}
+
+// ParseCelReason parses the reason template of cel rule, returns nil if it is empty
+func ParseCelReason(reason string) (*template.Template, error) {
+	if reason == "" {
+		return nil, nil
+	}
+	return template.New("reason").Option("missingkey=zero").Parse(reason)
+}
+
+func celVariables(pod *corev1.Pod, targets []interface{}) (map[string]interface{}, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+	if err != nil {
+		return nil, err
+	}
+	owner := map[string]interface{}{}
+	for i := range pod.OwnerReferences {
+		if pod.OwnerReferences[i].Controller != nil && *pod.OwnerReferences[i].Controller {
+			owner = map[string]interface{}{
+				"apiVersion": pod.OwnerReferences[i].APIVersion,
+				"kind":       pod.OwnerReferences[i].Kind,
+				"name":       pod.OwnerReferences[i].Name,
+				"uid":        string(pod.OwnerReferences[i].UID),
+			}
+			break
+		}
+	}
+	return map[string]interface{}{
+		celVarPod:     obj,
+		celVarOwner:   owner,
+		celVarTargets: targets,
+	}, nil
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+func TestCel(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	controller := true
+	newPod := func(name, ready string, restarts int32) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				Annotations: map[string]string{"app.kusionstack.io/ready": ready},
+				OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: "apps.kusionstack.io/v1alpha1", Kind: "CollaSet", Name: "foo", Controller: &controller},
+				},
+			},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "main", RestartCount: restarts}},
+			},
+		}
+	}
+	targets := map[string]*corev1.Pod{
+		"pod-a": newPod("pod-a", "true", 0),
+		"pod-b": newPod("pod-b", "false", 0),
+		"pod-c": newPod("pod-c", "true", 5),
+	}
+	subjects := sets.NewString("pod-a", "pod-b", "pod-c")
+
+	ruler := &CelRuler{
+		Name: "ready",
+		Cel: &kuperatorv1alpha1.CelRule{
+			Expression: `pod.metadata.annotations["app.kusionstack.io/ready"] == "true" && ` +
+				`pod.status.containerStatuses.all(c, c.restartCount < 3) && owner.kind == "CollaSet" && size(targets) == 3`,
+			Reason: "{{ .pod.metadata.name }} is blocked by {{ .rule }}",
+		},
+	}
+	res := ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(res.Passed.List()).Should(gomega.Equal([]string{"pod-a"}))
+	g.Expect(res.Rejected).Should(gomega.HaveKeyWithValue("pod-b", "pod-b is blocked by ready"))
+	g.Expect(res.Rejected).Should(gomega.HaveKeyWithValue("pod-c", "pod-c is blocked by ready"))
+
+	// expression not returning bool is invalid
+	_, err := CompileCelExpression(`pod.metadata.name`)
+	g.Expect(err).Should(gomega.HaveOccurred())
+	ruler.Cel.Expression = `pod.metadata.name +`
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Err).Should(gomega.HaveOccurred())
+	g.Expect(res.Rejected).Should(gomega.HaveLen(3))
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+// dependencyCheckInterval is the interval to check unhealthy dependencies whose changes are not watched
+const dependencyCheckInterval = 30 * time.Second
+
+// WatchedDependencyKinds are kinds of workloads watched by controller, whose changes trigger dependency rules
+// at once. Workloads of other kinds are checked periodically.
+var WatchedDependencyKinds = map[schema.GroupKind]client.Object{
+	{Group: appsv1alpha1.SchemeGroupVersion.Group, Kind: "CollaSet"}: &appsv1alpha1.CollaSet{},
+	{Group: appsv1.GroupName, Kind: "Deployment"}:                    &appsv1.Deployment{},
+	{Group: appsv1.GroupName, Kind: "StatefulSet"}:                   &appsv1.StatefulSet{},
+}
+
+type DependencyRuler struct {
+	Name       string
+	Dependency *kuperatorv1alpha1.DependencyRule
+
+	Client client.Client
+}
+
+// Filter passes all pods if the dependency is healthy, otherwise rejects them
+func (d *DependencyRuler) Filter(podTransitionRule *appsv1alpha1.PodTransitionRule, targets map[string]*corev1.Pod, subjects sets.String) *FilterResult {
+	passed := sets.NewString()
+	rejected := map[string]string{}
+	if subjects.Len() == 0 {
+		return &FilterResult{Passed: passed, Rejected: rejected}
+	}
+
+	dep := d.Dependency
+	obj, err := d.getWorkload(podTransitionRule.Namespace)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			interval := dependencyCheckInterval
+			reject(subjects, passed, rejected, fmt.Sprintf("blocked by dependency policy, %s %s not found", dep.Kind, dep.Name))
+			return &FilterResult{Passed: passed, Rejected: rejected, Interval: &interval}
+		}
+		return rejectAllWithErr(subjects, passed, rejected, "[%s] fail to get dependency %s %s: %v", d.Name, dep.Kind, dep.Name, err)
+	}
+
+	healthy, reason, err := EvaluateDependency(obj, dep)
+	if err != nil {
+		return rejectAllWithErr(subjects, passed, rejected, "[%s] fail to evaluate dependency %s %s: %v", d.Name, dep.Kind, dep.Name, err)
+	}
+	if healthy {
+		passed.Insert(subjects.List()...)
+		return &FilterResult{Passed: passed, Rejected: rejected}
+	}
+	interval := dependencyCheckInterval
+	reject(subjects, passed, rejected, fmt.Sprintf("blocked by dependency policy, %s %s is not healthy: %s", dep.Kind, dep.Name, reason))
+	klog.Infof("dependency %s of rule %s is not healthy: %s", dep.Name, d.Name, reason)
+	return &FilterResult{Passed: passed, Rejected: rejected, Interval: &interval}
+}
+
+// getWorkload gets the workload as typed object if it is of watched kinds, so that it is read from the cache
+// of client. Workloads of other kinds are read as unstructured objects from the API server, which requires
+// get permission on them granted to controller by operators.
+func (d *DependencyRuler) getWorkload(namespace string) (map[string]interface{}, error) {
+	gvk := schema.FromAPIVersionAndKind(d.Dependency.APIVersion, d.Dependency.Kind)
+	key := types.NamespacedName{Namespace: namespace, Name: d.Dependency.Name}
+	if _, watched := WatchedDependencyKinds[gvk.GroupKind()]; watched && d.Client.Scheme().Recognizes(gvk) {
+		typed, err := d.Client.Scheme().New(gvk)
+		if err != nil {
+			return nil, err
+		}
+		obj, ok := typed.(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("%s is not an object", gvk)
+		}
+		if err := d.Client.Get(context.TODO(), key, obj); err != nil {
+			return nil, err
+		}
+		return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	if err := d.Client.Get(context.TODO(), key, obj); err != nil {
+		return nil, err
+	}
+	return obj.Object, nil
+}
+
+// EvaluateDependency checks whether the workload satisfies the dependency, and returns the reason if not
+func EvaluateDependency(obj map[string]interface{}, dep *kuperatorv1alpha1.DependencyRule) (bool, string, error) {
+	replicas, found, err := unstructured.NestedInt64(obj, "spec", "replicas")
+	if err != nil {
+		return false, "", err
+	}
+	if !found {
+		replicas = 1
+	}
+
+	if dep.Updated {
+		generation, _, _ := unstructured.NestedInt64(obj, "metadata", "generation")
+		observed, _, _ := unstructured.NestedInt64(obj, "status", "observedGeneration")
+		if observed < generation {
+			return false, fmt.Sprintf("observed generation %d is older than %d", observed, generation), nil
+		}
+		updated, _, _ := unstructured.NestedInt64(obj, "status", "updatedReplicas")
+		if updated < replicas {
+			return false, fmt.Sprintf("updated replicas %d/%d", updated, replicas), nil
+		}
+	}
+
+	if dep.MinReadyValue != nil {
+		minReady, err := intstr.GetScaledValueFromIntOrPercent(dep.MinReadyValue, int(replicas), true)
+		if err != nil {
+			return false, "", err
+		}
+		ready, _, _ := unstructured.NestedInt64(obj, "status", "readyReplicas")
+		if ready < int64(minReady) {
+			return false, fmt.Sprintf("ready replicas %d/%d, at least %d required", ready, replicas, minReady), nil
+		}
+	}
+
+	if len(dep.Conditions) > 0 {
+		conditions, _, err := unstructured.NestedSlice(obj, "status", "conditions")
+		if err != nil {
+			return false, "", err
+		}
+		trueConditions := sets.NewString()
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if condition["status"] == string(corev1.ConditionTrue) {
+				trueConditions.Insert(fmt.Sprint(condition["type"]))
+			}
+		}
+		for _, c := range dep.Conditions {
+			if !trueConditions.Has(c) {
+				return false, fmt.Sprintf("condition %s is not True", c), nil
+			}
+		}
+	}
+	return true, "", nil
+}
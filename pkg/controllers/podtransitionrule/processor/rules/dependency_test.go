@@ -0,0 +1,156 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+func TestDependency(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(appsv1.AddToScheme(scheme)).ShouldNot(gomega.HaveOccurred())
+	g.Expect(appsv1alpha1.AddToScheme(scheme)).ShouldNot(gomega.HaveOccurred())
+
+	replicas := int32(5)
+	cls := &appsv1alpha1.CollaSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Generation: 2},
+		Spec:       appsv1alpha1.CollaSetSpec{Replicas: &replicas},
+		Status: appsv1alpha1.CollaSetStatus{
+			ObservedGeneration: 2,
+			ReadyReplicas:      3,
+			UpdatedReplicas:    5,
+		},
+	}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status: appsv1.DeploymentStatus{
+			ReadyReplicas: 5,
+			Conditions: []appsv1.DeploymentCondition{
+				{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionFalse},
+			},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cls, deploy).Build()
+
+	targets := map[string]*corev1.Pod{
+		"pod-a": {ObjectMeta: metav1.ObjectMeta{Name: "pod-a", Namespace: "default"}},
+		"pod-b": {ObjectMeta: metav1.ObjectMeta{Name: "pod-b", Namespace: "default"}},
+	}
+	subjects := sets.NewString("pod-a", "pod-b")
+
+	minReady := intstr.FromString("80%")
+	ruler := &DependencyRuler{
+		Name: "dependency",
+		Dependency: &kuperatorv1alpha1.DependencyRule{
+			APIVersion:    "apps.kusionstack.io/v1alpha1",
+			Kind:          "CollaSet",
+			Name:          "db",
+			MinReadyValue: &minReady,
+			Updated:       true,
+		},
+		Client: c,
+	}
+
+	// 3 of 5 replicas ready, 4 required
+	res := ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(res.Passed.Len()).Should(gomega.Equal(0))
+	g.Expect(res.Rejected["pod-a"]).Should(gomega.ContainSubstring("ready replicas 3/5"))
+	g.Expect(*res.Interval).Should(gomega.Equal(dependencyCheckInterval))
+
+	cls.Status.ReadyReplicas = 4
+	g.Expect(c.Status().Update(context.TODO(), cls)).ShouldNot(gomega.HaveOccurred())
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Passed.Len()).Should(gomega.Equal(2))
+	g.Expect(res.Interval).Should(gomega.BeNil())
+
+	// conditions of deployment
+	ruler.Dependency = &kuperatorv1alpha1.DependencyRule{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Name:       "cache",
+		Conditions: []string{string(appsv1.DeploymentAvailable)},
+	}
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Rejected["pod-b"]).Should(gomega.ContainSubstring("condition Available is not True"))
+
+	deploy.Status.Conditions[0].Status = corev1.ConditionTrue
+	g.Expect(c.Status().Update(context.TODO(), deploy)).ShouldNot(gomega.HaveOccurred())
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Passed.Len()).Should(gomega.Equal(2))
+
+	// workloads of kinds not watched are read as unstructured objects
+	ruler.Dependency = &kuperatorv1alpha1.DependencyRule{
+		APIVersion: "apps/v1",
+		Kind:       "DaemonSet",
+		Name:       "agent",
+		Conditions: []string{"Ready"},
+	}
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+		Status: appsv1.DaemonSetStatus{
+			Conditions: []appsv1.DaemonSetCondition{{Type: "Ready", Status: corev1.ConditionTrue}},
+		},
+	}
+	g.Expect(c.Create(context.TODO(), ds)).ShouldNot(gomega.HaveOccurred())
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Passed.Len()).Should(gomega.Equal(2))
+
+	// missing workload blocks pods
+	ruler.Dependency.Name = "missing"
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(res.Rejected["pod-a"]).Should(gomega.ContainSubstring("not found"))
+}
+
+func TestEvaluateDependency(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	obj := map[string]interface{}{
+		"metadata": map[string]interface{}{"generation": int64(3)},
+		"status": map[string]interface{}{
+			"observedGeneration": int64(2),
+			"updatedReplicas":    int64(1),
+			"readyReplicas":      int64(1),
+		},
+	}
+	// spec.replicas defaults to 1
+	healthy, reason, err := EvaluateDependency(obj, &kuperatorv1alpha1.DependencyRule{Updated: true})
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(healthy).Should(gomega.BeFalse())
+	g.Expect(reason).Should(gomega.ContainSubstring("observed generation"))
+
+	obj["status"].(map[string]interface{})["observedGeneration"] = int64(3)
+	minReady := intstr.FromInt(1)
+	healthy, _, err = EvaluateDependency(obj, &kuperatorv1alpha1.DependencyRule{Updated: true, MinReadyValue: &minReady})
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(healthy).Should(gomega.BeTrue())
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	"kusionstack.io/kuperator/pkg/controllers/podtransitionrule/utils"
+)
+
+type GroupMember struct {
+	Name string
+	// Ruler is nil if the member rule has no definition
+	Ruler Ruler
+}
+
+type GroupRuler struct {
+	Name      string
+	MinPassed int
+	Members   []GroupMember
+}
+
+// Filter evaluates members in order, and passes pods passing at least MinPassed of them. Pods already
+// passing MinPassed members are not evaluated by the rest members.
+func (g *GroupRuler) Filter(podTransitionRule *appsv1alpha1.PodTransitionRule, targets map[string]*corev1.Pod, subjects sets.String) *FilterResult {
+	res := &FilterResult{
+		Passed:   sets.NewString(),
+		Rejected: map[string]string{},
+		Members:  map[string]*FilterResult{},
+	}
+	passCount := map[string]int{}
+	reasons := map[string][]string{}
+	var errs []error
+	for _, member := range g.Members {
+		pending := sets.NewString()
+		for podName := range subjects {
+			if passCount[podName] >= g.MinPassed {
+				continue
+			}
+			if ok, _ := utils.HasSkipRule(targets[podName], member.Name); ok {
+				passCount[podName]++
+				continue
+			}
+			pending.Insert(podName)
+		}
+		if pending.Len() == 0 {
+			continue
+		}
+		if member.Ruler == nil {
+			for podName := range pending {
+				reasons[podName] = append(reasons[podName], fmt.Sprintf("%s: no rule definition", member.Name))
+			}
+			continue
+		}
+
+		memberRes := member.Ruler.Filter(podTransitionRule, targets, pending)
+		res.Members[member.Name] = memberRes
+		for podName := range memberRes.Passed {
+			passCount[podName]++
+		}
+		for podName, reason := range memberRes.Rejected {
+			reasons[podName] = append(reasons[podName], fmt.Sprintf("%s: %s", member.Name, reason))
+		}
+		if memberRes.Err != nil {
+			errs = append(errs, fmt.Errorf("[%s] %w", member.Name, memberRes.Err))
+		}
+		if memberRes.Interval != nil && (res.Interval == nil || *memberRes.Interval < *res.Interval) {
+			res.Interval = memberRes.Interval
+		}
+	}
+
+	for podName := range subjects {
+		if passCount[podName] >= g.MinPassed {
+			res.Passed.Insert(podName)
+			continue
+		}
+		res.Rejected[podName] = fmt.Sprintf("blocked by rule group, passed %d of %d rules, %d required, %s",
+			passCount[podName], len(g.Members), g.MinPassed, strings.Join(reasons[podName], "; "))
+	}
+	res.Err = utilerrors.NewAggregate(errs)
+	return res
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"time"
+	// time zones of maintenance windows should not depend on the image of controller
+	_ "time/tzdata"
+
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+const (
+	// maxNextAllowedSearch bounds the search of next allowed time across windows and freezes
+	maxNextAllowedSearch = 100
+	// noWindowInterval is the interval to check again if no window opens in the foreseeable future
+	noWindowInterval = time.Hour
+)
+
+var timeNow = time.Now
+
+type MaintenanceWindowRuler struct {
+	Name              string
+	MaintenanceWindow *kuperatorv1alpha1.MaintenanceWindowRule
+}
+
+// Filter passes all pods in maintenance windows, otherwise rejects them until the next window opens
+func (m *MaintenanceWindowRuler) Filter(podTransitionRule *appsv1alpha1.PodTransitionRule, targets map[string]*corev1.Pod, subjects sets.String) *FilterResult {
+	passed := sets.NewString()
+	rejected := map[string]string{}
+	if subjects.Len() == 0 {
+		return &FilterResult{Passed: passed, Rejected: rejected}
+	}
+
+	schedule, err := ParseMaintenanceWindow(m.MaintenanceWindow)
+	if err != nil {
+		return rejectAllWithErr(subjects, passed, rejected, "[%s] invalid maintenance window: %v", m.Name, err)
+	}
+
+	now := timeNow()
+	allowed, reason := schedule.Allowed(now)
+	if allowed {
+		passed.Insert(subjects.List()...)
+		return &FilterResult{Passed: passed, Rejected: rejected}
+	}
+
+	interval := noWindowInterval
+	next, ok := schedule.NextAllowed(now)
+	if ok {
+		interval = next.Sub(now)
+		reason = fmt.Sprintf("%s, next window opens at %s", reason, next.In(schedule.location).Format(time.RFC3339))
+	} else {
+		reason = fmt.Sprintf("%s, no window opens in the foreseeable future", reason)
+	}
+	reject(subjects, passed, rejected, fmt.Sprintf("blocked by maintenance window policy, %s", reason))
+	return &FilterResult{Passed: passed, Rejected: rejected, Interval: &interval}
+}
+
+// MaintenanceSchedule is the parsed MaintenanceWindowRule
+type MaintenanceSchedule struct {
+	location *time.Location
+	windows  []recurringWindow
+	freezes  []kuperatorv1alpha1.FreezePeriod
+}
+
+type recurringWindow struct {
+	schedule cron.Schedule
+	duration time.Duration
+}
+
+// ParseMaintenanceWindow parses the time zone and schedules of the rule
+func ParseMaintenanceWindow(rule *kuperatorv1alpha1.MaintenanceWindowRule) (*MaintenanceSchedule, error) {
+	location := time.UTC
+	if rule.TimeZone != "" {
+		loc, err := time.LoadLocation(rule.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone %s: %w", rule.TimeZone, err)
+		}
+		location = loc
+	}
+
+	s := &MaintenanceSchedule{location: location, freezes: rule.Freezes}
+	for i, w := range rule.Windows {
+		schedule, err := cron.ParseStandard(w.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q of window %d: %w", w.Schedule, i, err)
+		}
+		if w.Duration.Duration <= 0 {
+			return nil, fmt.Errorf("invalid duration %s of window %d, it should be positive", w.Duration.Duration, i)
+		}
+		s.windows = append(s.windows, recurringWindow{schedule: schedule, duration: w.Duration.Duration})
+	}
+	for i, f := range rule.Freezes {
+		if !f.End.After(f.Start.Time) {
+			return nil, fmt.Errorf("invalid freeze period %d %s, end should be after start", i, f.Name)
+		}
+	}
+	return s, nil
+}
+
+// Allowed returns whether transitions are allowed at the time, and the reason if not
+func (s *MaintenanceSchedule) Allowed(t time.Time) (bool, string) {
+	if f := s.freezeAt(t); f != nil {
+		name := f.Name
+		if name == "" {
+			name = fmt.Sprintf("%s~%s", f.Start.Format(time.RFC3339), f.End.Format(time.RFC3339))
+		}
+		return false, fmt.Sprintf("in freeze period %s", name)
+	}
+	if len(s.windows) == 0 || s.inWindow(t) {
+		return true, ""
+	}
+	return false, "out of maintenance windows"
+}
+
+// NextAllowed returns the earliest time not before t allowing transitions
+func (s *MaintenanceSchedule) NextAllowed(t time.Time) (time.Time, bool) {
+	for i := 0; i < maxNextAllowedSearch; i++ {
+		if f := s.freezeAt(t); f != nil {
+			t = f.End.Time
+			continue
+		}
+		if len(s.windows) == 0 || s.inWindow(t) {
+			return t, true
+		}
+		t = s.nextOpening(t)
+		if t.IsZero() {
+			return t, false
+		}
+	}
+	return time.Time{}, false
+}
+
+func (s *MaintenanceSchedule) freezeAt(t time.Time) *kuperatorv1alpha1.FreezePeriod {
+	for i := range s.freezes {
+		if !t.Before(s.freezes[i].Start.Time) && t.Before(s.freezes[i].End.Time) {
+			return &s.freezes[i]
+		}
+	}
+	return nil
+}
+
+// inWindow checks whether any window opens in (t-duration, t]
+func (s *MaintenanceSchedule) inWindow(t time.Time) bool {
+	t = t.In(s.location)
+	for _, w := range s.windows {
+		opening := w.schedule.Next(t.Add(-w.duration))
+		if !opening.IsZero() && !opening.After(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextOpening returns the earliest opening of windows after t, zero if none
+func (s *MaintenanceSchedule) nextOpening(t time.Time) time.Time {
+	t = t.In(s.location)
+	var next time.Time
+	for _, w := range s.windows {
+		opening := w.schedule.Next(t)
+		if opening.IsZero() {
+			continue
+		}
+		if next.IsZero() || opening.Before(next) {
+			next = opening
+		}
+	}
+	return next
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+func TestMaintenanceWindow(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer func() { timeNow = time.Now }()
+
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+
+	targets := map[string]*corev1.Pod{
+		"pod-a": {ObjectMeta: metav1.ObjectMeta{Name: "pod-a", Namespace: "default"}},
+		"pod-b": {ObjectMeta: metav1.ObjectMeta{Name: "pod-b", Namespace: "default"}},
+	}
+	subjects := sets.NewString("pod-a", "pod-b")
+
+	ruler := &MaintenanceWindowRuler{
+		Name: "window",
+		MaintenanceWindow: &kuperatorv1alpha1.MaintenanceWindowRule{
+			TimeZone: "Asia/Shanghai",
+			Windows: []kuperatorv1alpha1.RecurringWindow{
+				{Schedule: "0 2 * * 1-5", Duration: metav1.Duration{Duration: 3 * time.Hour}},
+			},
+			Freezes: []kuperatorv1alpha1.FreezePeriod{
+				{
+					Name:  "release-freeze",
+					Start: metav1.NewTime(time.Date(2024, 1, 10, 0, 0, 0, 0, shanghai)),
+					End:   metav1.NewTime(time.Date(2024, 1, 12, 0, 0, 0, 0, shanghai)),
+				},
+			},
+		},
+	}
+
+	// Monday 03:00, in window
+	timeNow = func() time.Time { return time.Date(2024, 1, 8, 3, 0, 0, 0, shanghai) }
+	res := ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(res.Passed.Len()).Should(gomega.Equal(2))
+	g.Expect(res.Interval).Should(gomega.BeNil())
+
+	// Monday 05:00, window closed, opens again on Tuesday 02:00
+	timeNow = func() time.Time { return time.Date(2024, 1, 8, 5, 0, 0, 0, shanghai) }
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Passed.Len()).Should(gomega.Equal(0))
+	g.Expect(res.Rejected).Should(gomega.HaveLen(2))
+	g.Expect(res.Interval).ShouldNot(gomega.BeNil())
+	g.Expect(*res.Interval).Should(gomega.Equal(21 * time.Hour))
+
+	// Friday 23:00, next window is on Monday 02:00
+	timeNow = func() time.Time { return time.Date(2024, 1, 5, 23, 0, 0, 0, shanghai) }
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(*res.Interval).Should(gomega.Equal(51 * time.Hour))
+
+	// Wednesday 03:00 in freeze, the window on Thursday is frozen too, next window is on Friday 02:00
+	timeNow = func() time.Time { return time.Date(2024, 1, 10, 3, 0, 0, 0, shanghai) }
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Rejected["pod-a"]).Should(gomega.ContainSubstring("release-freeze"))
+	g.Expect(*res.Interval).Should(gomega.Equal(47 * time.Hour))
+
+	// only freezes
+	ruler.MaintenanceWindow.Windows = nil
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(*res.Interval).Should(gomega.Equal(45 * time.Hour))
+	timeNow = func() time.Time { return time.Date(2024, 1, 12, 0, 0, 0, 0, shanghai) }
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Passed.Len()).Should(gomega.Equal(2))
+
+	// invalid schedule
+	ruler.MaintenanceWindow.Windows = []kuperatorv1alpha1.RecurringWindow{{Schedule: "0 2 * *", Duration: metav1.Duration{Duration: time.Hour}}}
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Err).Should(gomega.HaveOccurred())
+}
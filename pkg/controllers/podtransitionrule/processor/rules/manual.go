@@ -17,11 +17,20 @@ limitations under the License.
 package rules
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+	"kusionstack.io/kuperator/pkg/controllers/podtransitionrule/utils"
 )
 
+// PendingApprovalTaskId is the TaskId of the task listing pods waiting for approval in RuleState
+const PendingApprovalTaskId = "pending-approval"
+
 type ManualRuler struct {
 	Name string
 	Pass bool
@@ -37,3 +46,117 @@ func (r *ManualRuler) Filter(podTransitionRule *appsv1alpha1.PodTransitionRule,
 	}
 	return &FilterResult{Passed: sets.NewString(), Rejected: rejectDetails}
 }
+
+type ManualApprovalRuler struct {
+	Name           string
+	ManualApproval *kuperatorv1alpha1.ManualApprovalRule
+}
+
+// Filter passes pods approved in the approval record, and binds pods waiting for approval to approvals
+// by count until their counts are used up
+func (r *ManualApprovalRuler) Filter(podTransitionRule *appsv1alpha1.PodTransitionRule, targets map[string]*corev1.Pod, subjects sets.String) *FilterResult {
+	passed := sets.NewString()
+	rejected := map[string]string{}
+	approvals, err := utils.GetApprovals(podTransitionRule)
+	if err != nil {
+		return rejectAllWithErr(subjects, passed, rejected, "[%s] invalid approvals: %v", r.Name, err)
+	}
+
+	history := approvalHistory(podTransitionRule, r.Name, approvals[r.Name])
+	approved := sets.NewString()
+	for _, h := range history {
+		approved.Insert(h.Approved...)
+	}
+	var pending []string
+	for _, podName := range subjects.List() {
+		if approved.Has(podName) {
+			passed.Insert(podName)
+			continue
+		}
+		pending = append(pending, podName)
+	}
+
+	// bind pods waiting for approval to approvals by count
+	for i, approval := range approvals[r.Name] {
+		for approval.Count > int32(len(history[i].Approved)) && len(pending) > 0 {
+			history[i].Approved = append(history[i].Approved, pending[0])
+			passed.Insert(pending[0])
+			pending = pending[1:]
+		}
+	}
+
+	message := "blocked by manual approval policy, waiting for approval"
+	if r.ManualApproval.Message != "" {
+		message = r.ManualApproval.Message
+	}
+	for _, podName := range pending {
+		rejected[podName] = message
+	}
+
+	state := newApprovalState(r.Name, history)
+	if len(pending) > 0 {
+		state.WebhookStatus.TaskStates = []appsv1alpha1.TaskInfo{{
+			TaskId:     PendingApprovalTaskId,
+			Processing: pending,
+			Message:    message,
+		}}
+	}
+	klog.Infof("finish do manual approval %s, passed: %d, pending: %d", r.Name, len(passed), len(pending))
+	return &FilterResult{Passed: passed, Rejected: rejected, RuleState: state}
+}
+
+// ManualApprovalState returns the RuleState keeping pods bound to approvals, it is used to keep the
+// bindings when no pod is processed by the rule
+func ManualApprovalState(podTransitionRule *appsv1alpha1.PodTransitionRule, ruleName string) *appsv1alpha1.RuleState {
+	approvals, err := utils.GetApprovals(podTransitionRule)
+	if err != nil || len(approvals[ruleName]) == 0 {
+		return nil
+	}
+	return newApprovalState(ruleName, approvalHistory(podTransitionRule, ruleName, approvals[ruleName]))
+}
+
+// approvalHistory returns one TaskInfo for each approval in the same order. Pods bound to approvals by
+// count are read from the History of current RuleState, and bindings of removed approvals are dropped.
+func approvalHistory(podTransitionRule *appsv1alpha1.PodTransitionRule, ruleName string, approvals []kuperatorv1alpha1.Approval) []appsv1alpha1.TaskInfo {
+	bound := map[string][]string{}
+	for _, state := range podTransitionRule.Status.RuleStates {
+		if state.Name != ruleName || state.WebhookStatus == nil {
+			continue
+		}
+		for _, h := range state.WebhookStatus.History {
+			bound[h.TaskId] = h.Approved
+		}
+	}
+
+	history := make([]appsv1alpha1.TaskInfo, len(approvals))
+	for i, approval := range approvals {
+		id := approvalID(i, approval)
+		history[i] = appsv1alpha1.TaskInfo{
+			TaskId:    id,
+			BeginTime: approval.ApprovedAt,
+			Message:   fmt.Sprintf("approved by %s", approval.ApprovedBy),
+		}
+		if approval.Count > 0 {
+			history[i].Approved = append([]string{}, bound[id]...)
+		} else {
+			history[i].Approved = approval.Pods
+		}
+	}
+	return history
+}
+
+func approvalID(index int, approval kuperatorv1alpha1.Approval) string {
+	if approval.ID != "" {
+		return approval.ID
+	}
+	return fmt.Sprintf("approval-%d", index)
+}
+
+func newApprovalState(ruleName string, history []appsv1alpha1.TaskInfo) *appsv1alpha1.RuleState {
+	return &appsv1alpha1.RuleState{
+		Name: ruleName,
+		WebhookStatus: &appsv1alpha1.WebhookStatus{
+			History: history,
+		},
+	}
+}
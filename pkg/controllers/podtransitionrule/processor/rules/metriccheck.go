@@ -0,0 +1,184 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	promapi "github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+const defaultMetricPodLabel = "pod"
+
+type MetricCheckRuler struct {
+	Name        string
+	MetricCheck *kuperatorv1alpha1.MetricCheckRule
+}
+
+// Filter queries the metric and passes pods whose samples satisfy the threshold
+func (m *MetricCheckRuler) Filter(podTransitionRule *appsv1alpha1.PodTransitionRule, targets map[string]*corev1.Pod, subjects sets.String) *FilterResult {
+	passed := sets.NewString()
+	rejected := map[string]string{}
+	if subjects.Len() == 0 {
+		return &FilterResult{Passed: passed, Rejected: rejected}
+	}
+
+	threshold, err := strconv.ParseFloat(m.MetricCheck.Threshold.Value, 64)
+	if err != nil {
+		return rejectAllWithErr(subjects, passed, rejected, "[%s] invalid metric threshold value %s: %v", m.Name, m.MetricCheck.Threshold.Value, err)
+	}
+	samples, err := m.query()
+	if err != nil {
+		return rejectAllWithErr(subjects, passed, rejected, "[%s] fail to query metric: %v", m.Name, err)
+	}
+
+	if m.MetricCheck.Scope == kuperatorv1alpha1.MetricCheckScopeAggregate {
+		if len(samples) != 1 {
+			if len(samples) == 0 && m.passNoData() {
+				return &FilterResult{Passed: sets.NewString(subjects.List()...), Rejected: rejected}
+			}
+			reject(subjects, passed, rejected, fmt.Sprintf("blocked by metric check policy, expect 1 sample in aggregate scope, got %d", len(samples)))
+		} else {
+			ok, err := compareMetric(m.MetricCheck.Threshold.Operator, float64(samples[0].Value), threshold)
+			if err != nil {
+				return rejectAllWithErr(subjects, passed, rejected, "[%s] %v", m.Name, err)
+			}
+			if ok {
+				passed.Insert(subjects.List()...)
+			} else {
+				reject(subjects, passed, rejected, fmt.Sprintf("blocked by metric check policy, %s %s %s not satisfied", samples[0].Value.String(), m.MetricCheck.Threshold.Operator, m.MetricCheck.Threshold.Value))
+			}
+		}
+		return m.result(passed, rejected)
+	}
+
+	podLabel := m.MetricCheck.PodLabel
+	if podLabel == "" {
+		podLabel = defaultMetricPodLabel
+	}
+	podSamples := map[string]model.SampleValue{}
+	for _, sample := range samples {
+		if podName, ok := sample.Metric[model.LabelName(podLabel)]; ok {
+			podSamples[string(podName)] = sample.Value
+		}
+	}
+
+	for podName := range subjects {
+		value, ok := podSamples[podName]
+		if !ok {
+			if m.passNoData() {
+				passed.Insert(podName)
+			} else {
+				rejected[podName] = fmt.Sprintf("blocked by metric check policy, no sample of pod %s", podName)
+			}
+			continue
+		}
+		ok, err := compareMetric(m.MetricCheck.Threshold.Operator, float64(value), threshold)
+		if err != nil {
+			return rejectAllWithErr(subjects, passed, rejected, "[%s] %v", m.Name, err)
+		}
+		if ok {
+			passed.Insert(podName)
+		} else {
+			rejected[podName] = fmt.Sprintf("blocked by metric check policy, %s %s %s not satisfied", value.String(), m.MetricCheck.Threshold.Operator, m.MetricCheck.Threshold.Value)
+		}
+	}
+	klog.Infof("finish do metric check %s, passed: %d, rejected: %d", m.Name, len(passed), len(rejected))
+	return m.result(passed, rejected)
+}
+
+func (m *MetricCheckRuler) result(passed sets.String, rejected map[string]string) *FilterResult {
+	res := &FilterResult{Passed: passed, Rejected: rejected}
+	if len(rejected) > 0 {
+		// check rejected pods again later, the metric may change
+		intervalSeconds := kuperatorv1alpha1.DefaultMetricCheckIntervalSeconds
+		if m.MetricCheck.IntervalSeconds != nil {
+			intervalSeconds = *m.MetricCheck.IntervalSeconds
+		}
+		interval := time.Duration(intervalSeconds) * time.Second
+		res.Interval = &interval
+	}
+	return res
+}
+
+func (m *MetricCheckRuler) passNoData() bool {
+	return m.MetricCheck.NoDataPolicy != nil && *m.MetricCheck.NoDataPolicy == kuperatorv1alpha1.NoDataPolicyPass
+}
+
+// query runs the instant query, and returns a scalar result as a vector with one sample
+func (m *MetricCheckRuler) query() (model.Vector, error) {
+	client, err := promapi.NewClient(promapi.Config{Address: m.MetricCheck.Address})
+	if err != nil {
+		return nil, err
+	}
+	timeoutSeconds := kuperatorv1alpha1.DefaultMetricCheckTimeoutSeconds
+	if m.MetricCheck.TimeoutSeconds != nil {
+		timeoutSeconds = *m.MetricCheck.TimeoutSeconds
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutSeconds)*time.Second)
+	defer cancel()
+
+	value, warnings, err := promv1.NewAPI(client).Query(ctx, m.MetricCheck.Query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if len(warnings) > 0 {
+		klog.Warningf("metric check %s query warnings: %v", m.Name, warnings)
+	}
+
+	switch v := value.(type) {
+	case model.Vector:
+		return v, nil
+	case *model.Scalar:
+		return model.Vector{{Value: v.Value, Timestamp: v.Timestamp}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported query result type %s", value.Type())
+	}
+}
+
+func compareMetric(operator kuperatorv1alpha1.MetricOperator, value, threshold float64) (bool, error) {
+	if math.IsNaN(value) {
+		return false, nil
+	}
+	switch operator {
+	case kuperatorv1alpha1.MetricOperatorLessThan:
+		return value < threshold, nil
+	case kuperatorv1alpha1.MetricOperatorLessOrEqual:
+		return value <= threshold, nil
+	case kuperatorv1alpha1.MetricOperatorGreaterThan:
+		return value > threshold, nil
+	case kuperatorv1alpha1.MetricOperatorGreaterOrEqual:
+		return value >= threshold, nil
+	case kuperatorv1alpha1.MetricOperatorEqual:
+		return value == threshold, nil
+	case kuperatorv1alpha1.MetricOperatorNotEqual:
+		return value != threshold, nil
+	}
+	return false, fmt.Errorf("unsupported metric operator %s", operator)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+// fakePrometheus serves instant queries with the result of the query
+func fakePrometheus(results map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if err := req.ParseForm(); err != nil {
+			http.Error(resp, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, ok := results[req.Form.Get("query")]
+		if !ok {
+			resp.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(resp, `{"status":"error","errorType":"bad_data","error":"unknown query"}`)
+			return
+		}
+		resp.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(resp, `{"status":"success","data":%s}`, result)
+	}))
+}
+
+func TestMetricCheck(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	server := fakePrometheus(map[string]string{
+		"error_rate": `{"resultType":"vector","result":[` +
+			`{"metric":{"pod":"pod-a"},"value":[1704865098,"0.001"]},` +
+			`{"metric":{"pod":"pod-b"},"value":[1704865098,"0.05"]}]}`,
+		"total_error_rate": `{"resultType":"scalar","result":[1704865098,"0.002"]}`,
+	})
+	defer server.Close()
+
+	targets := map[string]*corev1.Pod{}
+	for _, name := range []string{"pod-a", "pod-b", "pod-c"} {
+		targets[name] = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	}
+	subjects := sets.NewString("pod-a", "pod-b", "pod-c")
+
+	ruler := &MetricCheckRuler{
+		Name: "error-rate",
+		MetricCheck: &kuperatorv1alpha1.MetricCheckRule{
+			Address:   server.URL,
+			Query:     "error_rate",
+			Threshold: kuperatorv1alpha1.MetricThreshold{Operator: kuperatorv1alpha1.MetricOperatorLessThan, Value: "0.01"},
+		},
+	}
+	res := ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(res.Passed.List()).Should(gomega.Equal([]string{"pod-a"}))
+	g.Expect(res.Rejected).Should(gomega.HaveKey("pod-b"))
+	g.Expect(res.Rejected).Should(gomega.HaveKey("pod-c"))
+	g.Expect(res.Interval).ShouldNot(gomega.BeNil())
+	g.Expect(*res.Interval).Should(gomega.Equal(30 * time.Second))
+
+	// pods without samples pass
+	pass := kuperatorv1alpha1.NoDataPolicyPass
+	ruler.MetricCheck.NoDataPolicy = &pass
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Passed.List()).Should(gomega.Equal([]string{"pod-a", "pod-c"}))
+
+	// aggregate scope passes or rejects all
+	ruler.MetricCheck.Scope = kuperatorv1alpha1.MetricCheckScopeAggregate
+	ruler.MetricCheck.Query = "total_error_rate"
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(res.Passed.Len()).Should(gomega.Equal(3))
+	g.Expect(res.Interval).Should(gomega.BeNil())
+
+	ruler.MetricCheck.Threshold.Value = "0.001"
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Passed.Len()).Should(gomega.Equal(0))
+	g.Expect(res.Rejected).Should(gomega.HaveLen(3))
+
+	// query error is retried
+	ruler.MetricCheck.Query = "unknown"
+	res = ruler.Filter(normalRS, targets, subjects)
+	g.Expect(res.Err).Should(gomega.HaveOccurred())
+	g.Expect(res.Rejected).Should(gomega.HaveLen(3))
+}
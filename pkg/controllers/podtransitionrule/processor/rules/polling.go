@@ -36,13 +36,25 @@ import (
 const (
 	MaxConcurrentTasks  = 16
 	TaskDeadLineSeconds = 60
+
+	// throttledRequeueInterval is the interval to requeue tasks throttled by the concurrency of their webhook
+	throttledRequeueInterval = time.Second
 )
 
 var PollingManager = newPollingManager(context.TODO())
 
+// PollingLimits limits polling tasks of a webhook, zero values mean the defaults
+type PollingLimits struct {
+	// MaxConcurrentTasks is the max number of tasks of the webhook querying at the same time,
+	// it is bounded by the global MaxConcurrentTasks
+	MaxConcurrentTasks int
+	// Deadline is how long the task and its result are kept after timeout, defaults to TaskDeadLineSeconds
+	Deadline time.Duration
+}
+
 type PollingManagerInterface interface {
 	Delete(id string)
-	Add(id, url, caBundle, resourceKey string, timeout, interval time.Duration)
+	Add(id, url, caBundle, resourceKey string, auth *WebhookAuth, beginTime time.Time, timeout, interval time.Duration, limits PollingLimits)
 	GetResult(id string) *PollResult
 	Start(ctx context.Context)
 	AddListener(chan<- event.GenericEvent)
@@ -52,6 +64,7 @@ func newPollingManager(ctx context.Context) PollingManagerInterface {
 	p := &pollingRunner{
 		q:        workqueue.New(),
 		tasks:    make(map[string]*task),
+		running:  make(map[string]int),
 		ch:       make(chan struct{}, MaxConcurrentTasks),
 		toDelete: make(chan string, 5),
 	}
@@ -62,6 +75,7 @@ func newPollingManager(ctx context.Context) PollingManagerInterface {
 type pollingRunner struct {
 	mu       sync.RWMutex
 	tasks    map[string]*task
+	running  map[string]int
 	q        workqueue.Interface
 	toDelete chan string
 	ch       chan struct{}
@@ -124,7 +138,13 @@ func (r *pollingRunner) doTask(id string) {
 		r.q.Done(id)
 		return
 	}
+	if !r.start(t) {
+		r.q.Done(id)
+		r.addAfter(id, throttledRequeueInterval)
+		return
+	}
 	finish := t.do()
+	r.finish(t)
 	r.broadcast(t.resourceKey)
 	r.q.Done(id)
 	if !finish {
@@ -147,18 +167,32 @@ func (r *pollingRunner) addAfter(id string, d time.Duration) {
 	})
 }
 
-func (r *pollingRunner) Add(id, url, caBundle, resourceKey string, timeout, interval time.Duration) {
+// Add adds a polling task. Tasks resumed from RuleState after restart keep their beginTime, so that they
+// time out as if the controller had not restarted.
+func (r *pollingRunner) Add(id, url, caBundle, resourceKey string, auth *WebhookAuth, beginTime time.Time, timeout, interval time.Duration, limits PollingLimits) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	tm := time.Now()
+	deadline := limits.Deadline
+	if deadline <= 0 {
+		deadline = TaskDeadLineSeconds * time.Second
+	}
+	timeoutTime := beginTime.Add(timeout)
+	// the result of timeout task should be kept long enough to be read
+	deadlineTime := timeoutTime
+	if now := time.Now(); now.After(deadlineTime) {
+		deadlineTime = now
+	}
+	deadlineTime = deadlineTime.Add(deadline - time.Second)
 	t := &task{
-		id:           id,
-		url:          url,
-		caBundle:     caBundle,
-		resourceKey:  resourceKey,
-		timeoutTime:  tm.Add(timeout),
-		deadlineTime: tm.Add(timeout + (TaskDeadLineSeconds-1)*time.Second),
-		interval:     interval,
+		id:            id,
+		url:           url,
+		caBundle:      caBundle,
+		auth:          auth,
+		resourceKey:   resourceKey,
+		maxConcurrent: limits.MaxConcurrentTasks,
+		timeoutTime:   timeoutTime,
+		deadlineTime:  deadlineTime,
+		interval:      interval,
 		result: &PollResult{
 			Approved:    sets.NewString(),
 			LastMessage: "Waiting for first query...",
@@ -166,13 +200,17 @@ func (r *pollingRunner) Add(id, url, caBundle, resourceKey string, timeout, inte
 	}
 	r.tasks[id] = t
 	r.addAfter(id, interval)
-	r.addAfter(id, timeout+TaskDeadLineSeconds*time.Second)
+	r.addAfter(id, time.Until(deadlineTime)+time.Second)
 }
 
 func (r *pollingRunner) GetResult(id string) *PollResult {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	return r.tasks[id].getResult()
+	t, ok := r.tasks[id]
+	if !ok {
+		return nil
+	}
+	return t.getResult()
 }
 
 func (r *pollingRunner) Delete(id string) {
@@ -181,6 +219,31 @@ func (r *pollingRunner) Delete(id string) {
 	delete(r.tasks, id)
 }
 
+// start checks the concurrency of the webhook of task, and counts the task as running if allowed
+func (r *pollingRunner) start(t *task) bool {
+	if t.maxConcurrent <= 0 {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running[t.resourceKey] >= t.maxConcurrent {
+		return false
+	}
+	r.running[t.resourceKey]++
+	return true
+}
+
+func (r *pollingRunner) finish(t *task) {
+	if t.maxConcurrent <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running[t.resourceKey]--; r.running[t.resourceKey] <= 0 {
+		delete(r.running, t.resourceKey)
+	}
+}
+
 func (r *pollingRunner) acquire() {
 	r.ch <- struct{}{}
 }
@@ -193,8 +256,11 @@ type task struct {
 	id          string
 	url         string
 	caBundle    string
+	auth        *WebhookAuth
 	resourceKey string
 
+	maxConcurrent int
+
 	timeoutTime  time.Time
 	deadlineTime time.Time
 	interval     time.Duration
@@ -239,7 +305,10 @@ func (t *task) do() bool {
 }
 
 func (t *task) query() (*appsv1alpha1.PollResponse, error) {
-	httpResp, err := utilshttp.DoHttpAndHttpsRequestWithCa(http.MethodGet, t.url, nil, nil, t.caBundle)
+	if t.auth.isGRPC() {
+		return t.auth.pollGRPC(t.url, t.caBundle, t.id)
+	}
+	httpResp, err := t.auth.do(http.MethodGet, t.url, nil, t.caBundle)
 	defer func() {
 		if httpResp != nil {
 			_ = httpResp.Body.Close()
@@ -0,0 +1,130 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+)
+
+// fakePollingWebhook counts first requests, and approves pods of polling tasks
+func fakePollingWebhook(requests *int32, pods []string) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPost {
+			atomic.AddInt32(requests, 1)
+			_ = json.NewEncoder(resp).Encode(&appsv1alpha1.WebhookResponse{Success: true, Poll: true, TaskId: "new-task"})
+			return
+		}
+		_ = json.NewEncoder(resp).Encode(&appsv1alpha1.PollResponse{Success: true, Finished: true, FinishedNames: pods})
+	}
+}
+
+func TestPollingResume(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	var requests int32
+	server := httptest.NewServer(fakePollingWebhook(&requests, []string{"pod-a", "pod-b"}))
+	defer server.Close()
+
+	pollInterval, pollTimeout := int64(1), int64(60)
+	rs := webhookRS("resume", server.URL, "")
+	rs.Spec.Rules[0].Webhook.ClientConfig.Poll = &appsv1alpha1.Poll{
+		URL:             server.URL,
+		IntervalSeconds: &pollInterval,
+		TimeoutSeconds:  &pollTimeout,
+	}
+	// the task persisted by the last leader
+	begin := metav1.NewTime(time.Now().Add(-10 * time.Second))
+	rs.Status.RuleStates = []*appsv1alpha1.RuleState{{
+		Name: "webhook",
+		WebhookStatus: &appsv1alpha1.WebhookStatus{
+			TaskStates: []appsv1alpha1.TaskInfo{{TaskId: "resume-task", BeginTime: &begin, Processing: []string{"pod-a", "pod-b"}}},
+		},
+	}}
+	targets := map[string]*corev1.Pod{
+		"pod-a": {ObjectMeta: metav1.ObjectMeta{Name: "pod-a", Namespace: "default"}},
+		"pod-b": {ObjectMeta: metav1.ObjectMeta{Name: "pod-b", Namespace: "default"}},
+	}
+	subjects := sets.NewString("pod-a", "pod-b")
+
+	res := GetWebhook(rs)[0].Do(targets, subjects)
+	g.Expect(res.Rejected).Should(gomega.HaveLen(2))
+	g.Expect(res.RuleState.WebhookStatus.TaskStates).Should(gomega.HaveLen(1))
+	g.Expect(res.RuleState.WebhookStatus.TaskStates[0].TaskId).Should(gomega.Equal("resume-task"))
+
+	// pods are approved by polling the resumed task without requesting the webhook again
+	rs.Status.RuleStates = []*appsv1alpha1.RuleState{res.RuleState}
+	g.Eventually(func() int {
+		res = GetWebhook(rs)[0].Do(targets, subjects)
+		return res.Passed.Len()
+	}, 5*time.Second, 500*time.Millisecond).Should(gomega.Equal(2))
+	g.Expect(atomic.LoadInt32(&requests)).Should(gomega.BeEquivalentTo(0))
+}
+
+func TestPollingLimits(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	var running, maxRunning int32
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			last := atomic.LoadInt32(&maxRunning)
+			if current <= last || atomic.CompareAndSwapInt32(&maxRunning, last, current) {
+				break
+			}
+		}
+		time.Sleep(200 * time.Millisecond)
+		_ = json.NewEncoder(resp).Encode(&appsv1alpha1.PollResponse{Success: true, Finished: true})
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager := newPollingManager(ctx)
+	limits := PollingLimits{MaxConcurrentTasks: 1, Deadline: 10 * time.Second}
+	ids := []string{"task-a", "task-b", "task-c"}
+	for _, id := range ids {
+		manager.Add(id, server.URL, "", "default/limits/webhook", nil, time.Now(), time.Minute, 10*time.Millisecond, limits)
+	}
+	g.Eventually(func() bool {
+		for _, id := range ids {
+			if res := manager.GetResult(id); res == nil || !res.ApproveAll {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 100*time.Millisecond).Should(gomega.BeTrue())
+	g.Expect(atomic.LoadInt32(&maxRunning)).Should(gomega.BeEquivalentTo(1))
+
+	// the task resumed after timeout stops without query
+	manager.Add("task-timeout", server.URL, "", "default/limits/webhook", nil, time.Now().Add(-2*time.Minute), time.Minute, 10*time.Millisecond, limits)
+	g.Eventually(func() bool {
+		res := manager.GetResult("task-timeout")
+		return res != nil && res.Stopped && res.Count == 0
+	}, 5*time.Second, 100*time.Millisecond).Should(gomega.BeTrue())
+	g.Expect(manager.GetResult("unknown")).Should(gomega.BeNil())
+}
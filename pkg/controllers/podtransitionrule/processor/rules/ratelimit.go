@@ -0,0 +1,132 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+	"kusionstack.io/kuperator/pkg/controllers/podtransitionrule/utils"
+)
+
+type RateLimitRuler struct {
+	Name      string
+	RateLimit *kuperatorv1alpha1.RateLimitRule
+}
+
+// Filter admits pods until the budget of current window is used up. Pods already passed the rule
+// keep passing without consuming the budget again.
+func (r *RateLimitRuler) Filter(podTransitionRule *appsv1alpha1.PodTransitionRule, targets map[string]*corev1.Pod, subjects sets.String) *FilterResult {
+	passed := sets.NewString()
+	rejected := map[string]string{}
+	now := timeNow()
+	admissions := recentAdmissions(podTransitionRule, r.Name, r.RateLimit.Window.Duration, now)
+	admitted := sets.NewString()
+	for _, admission := range admissions {
+		admitted.Insert(admission.Approved...)
+	}
+
+	budget := int(r.RateLimit.Limit) - len(admissions)
+	// admit in order of name to make the result stable
+	for _, podName := range subjects.List() {
+		if admitted.Has(podName) || utils.IsPodPassRule(podName, podTransitionRule, r.Name) {
+			passed.Insert(podName)
+			continue
+		}
+		if budget > 0 {
+			budget--
+			passed.Insert(podName)
+			admissions = append(admissions, newAdmission(podName, now))
+			continue
+		}
+		rejected[podName] = fmt.Sprintf("blocked by rate limit policy, %d pods admitted in last %s", r.RateLimit.Limit, r.RateLimit.Window.Duration)
+	}
+
+	res := &FilterResult{
+		Passed:    passed,
+		Rejected:  rejected,
+		RuleState: newRateLimitState(r.Name, admissions),
+	}
+	if len(rejected) > 0 && len(admissions) > 0 {
+		// the budget refills when the earliest admission slides out of the window
+		interval := admissions[0].BeginTime.Add(r.RateLimit.Window.Duration).Sub(now)
+		if interval < time.Second {
+			interval = time.Second
+		}
+		res.Interval = &interval
+	}
+	klog.Infof("finish do rate limit %s, passed: %d, rejected: %d, admissions in window: %d", r.Name, len(passed), len(rejected), len(admissions))
+	return res
+}
+
+// RateLimitState returns the RuleState keeping admissions in current window, it is used to keep
+// the budget when no pod is processed by the rule
+func RateLimitState(podTransitionRule *appsv1alpha1.PodTransitionRule, ruleName string, rateLimit *kuperatorv1alpha1.RateLimitRule) *appsv1alpha1.RuleState {
+	admissions := recentAdmissions(podTransitionRule, ruleName, rateLimit.Window.Duration, timeNow())
+	if len(admissions) == 0 {
+		return nil
+	}
+	return newRateLimitState(ruleName, admissions)
+}
+
+// recentAdmissions returns admissions in the window ordered by time. RuleState has no dedicated field
+// for rate limit, so each admission is recorded as a TaskInfo in WebhookStatus.History, with the
+// pod in Approved and the admission time in BeginTime.
+func recentAdmissions(podTransitionRule *appsv1alpha1.PodTransitionRule, ruleName string, window time.Duration, now time.Time) []appsv1alpha1.TaskInfo {
+	var admissions []appsv1alpha1.TaskInfo
+	for _, state := range podTransitionRule.Status.RuleStates {
+		if state.Name != ruleName || state.WebhookStatus == nil {
+			continue
+		}
+		for _, admission := range state.WebhookStatus.History {
+			if admission.BeginTime == nil || now.Sub(admission.BeginTime.Time) >= window {
+				continue
+			}
+			admissions = append(admissions, admission)
+		}
+	}
+	sort.SliceStable(admissions, func(i, j int) bool {
+		return admissions[i].BeginTime.Before(admissions[j].BeginTime)
+	})
+	return admissions
+}
+
+func newAdmission(podName string, now time.Time) appsv1alpha1.TaskInfo {
+	tm := metav1.NewTime(now)
+	return appsv1alpha1.TaskInfo{
+		TaskId:    podName,
+		Approved:  []string{podName},
+		BeginTime: &tm,
+	}
+}
+
+func newRateLimitState(ruleName string, admissions []appsv1alpha1.TaskInfo) *appsv1alpha1.RuleState {
+	return &appsv1alpha1.RuleState{
+		Name: ruleName,
+		WebhookStatus: &appsv1alpha1.WebhookStatus{
+			History: admissions,
+		},
+	}
+}
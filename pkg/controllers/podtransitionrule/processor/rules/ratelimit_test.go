@@ -0,0 +1,79 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+func TestRateLimit(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer func() { timeNow = time.Now }()
+	start := time.Date(2024, 1, 8, 3, 0, 0, 0, time.UTC)
+
+	targets := map[string]*corev1.Pod{}
+	subjects := sets.NewString()
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("pod-%d", i)
+		targets[name] = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+		subjects.Insert(name)
+	}
+	rs := normalRS.DeepCopy()
+	ruler := &RateLimitRuler{
+		Name: "rate",
+		RateLimit: &kuperatorv1alpha1.RateLimitRule{
+			Limit:  2,
+			Window: metav1.Duration{Duration: 10 * time.Minute},
+		},
+	}
+
+	timeNow = func() time.Time { return start }
+	res := ruler.Filter(rs, targets, subjects)
+	g.Expect(res.Passed.List()).Should(gomega.Equal([]string{"pod-0", "pod-1"}))
+	g.Expect(res.Rejected).Should(gomega.HaveLen(3))
+	g.Expect(*res.Interval).Should(gomega.Equal(10 * time.Minute))
+	g.Expect(res.RuleState.WebhookStatus.History).Should(gomega.HaveLen(2))
+
+	// admitted pods keep passing, and the budget is still used up
+	rs.Status.RuleStates = []*appsv1alpha1.RuleState{res.RuleState}
+	timeNow = func() time.Time { return start.Add(4 * time.Minute) }
+	res = ruler.Filter(rs, targets, subjects)
+	g.Expect(res.Passed.List()).Should(gomega.Equal([]string{"pod-0", "pod-1"}))
+	g.Expect(*res.Interval).Should(gomega.Equal(6 * time.Minute))
+
+	// the state is kept when no pod is processed
+	g.Expect(RateLimitState(rs, "rate", ruler.RateLimit)).ShouldNot(gomega.BeNil())
+
+	// budget refills after the window
+	rs.Status.RuleStates = []*appsv1alpha1.RuleState{res.RuleState}
+	timeNow = func() time.Time { return start.Add(10 * time.Minute) }
+	g.Expect(RateLimitState(rs, "rate", ruler.RateLimit)).Should(gomega.BeNil())
+	res = ruler.Filter(rs, targets, sets.NewString("pod-2", "pod-3", "pod-4"))
+	g.Expect(res.Passed.List()).Should(gomega.Equal([]string{"pod-2", "pod-3"}))
+	g.Expect(res.Rejected).Should(gomega.HaveKey("pod-4"))
+	g.Expect(res.RuleState.WebhookStatus.History).Should(gomega.HaveLen(2))
+}
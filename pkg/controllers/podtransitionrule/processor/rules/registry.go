@@ -0,0 +1,74 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RulerFactory builds the Ruler of a custom rule from the rule and its params. It is also called by the
+// validating webhook with a nil client to check params, so it should only build the Ruler without side effects.
+type RulerFactory func(rule *appsv1alpha1.TransitionRule, params *runtime.RawExtension, client client.Client) (Ruler, error)
+
+var rulerRegistry = &registry{factories: map[string]RulerFactory{}}
+
+type registry struct {
+	factories map[string]RulerFactory
+	mu        sync.RWMutex
+}
+
+// RegisterRuler registers the factory of custom rules with the kind, the former one of the kind is replaced
+func RegisterRuler(kind string, factory RulerFactory) {
+	rulerRegistry.mu.Lock()
+	defer rulerRegistry.mu.Unlock()
+	rulerRegistry.factories[kind] = factory
+}
+
+// GetRulerFactory returns the factory registered with the kind
+func GetRulerFactory(kind string) (RulerFactory, bool) {
+	rulerRegistry.mu.RLock()
+	defer rulerRegistry.mu.RUnlock()
+	factory, ok := rulerRegistry.factories[kind]
+	return factory, ok
+}
+
+// RegisteredRulerKinds returns kinds of registered custom rules in order
+func RegisteredRulerKinds() []string {
+	rulerRegistry.mu.RLock()
+	defer rulerRegistry.mu.RUnlock()
+	kinds := sets.NewString()
+	for kind := range rulerRegistry.factories {
+		kinds.Insert(kind)
+	}
+	return kinds.List()
+}
+
+// invalidRuler rejects all pods, it is used when the custom rule can not be built
+type invalidRuler struct {
+	name string
+	err  error
+}
+
+func (r *invalidRuler) Filter(podTransitionRule *appsv1alpha1.PodTransitionRule, targets map[string]*corev1.Pod, subjects sets.String) *FilterResult {
+	return rejectAllWithErr(subjects, sets.NewString(), map[string]string{}, "[%s] invalid custom rule: %v", r.name, r.err)
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+type prefixRuler struct {
+	prefix string
+}
+
+func (r *prefixRuler) Filter(podTransitionRule *appsv1alpha1.PodTransitionRule, targets map[string]*corev1.Pod, subjects sets.String) *FilterResult {
+	passed := sets.NewString()
+	rejected := map[string]string{}
+	for podName := range subjects {
+		if len(podName) >= len(r.prefix) && podName[:len(r.prefix)] == r.prefix {
+			passed.Insert(podName)
+			continue
+		}
+		rejected[podName] = "prefix not matched"
+	}
+	return &FilterResult{Passed: passed, Rejected: rejected}
+}
+
+func TestRegisterRuler(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	RegisterRuler("prefix", func(rule *appsv1alpha1.TransitionRule, params *runtime.RawExtension, client client.Client) (Ruler, error) {
+		p := struct {
+			Prefix string `json:"prefix"`
+		}{}
+		if params == nil {
+			return nil, fmt.Errorf("params is required")
+		}
+		if err := json.Unmarshal(params.Raw, &p); err != nil {
+			return nil, err
+		}
+		return &prefixRuler{prefix: p.Prefix}, nil
+	})
+	g.Expect(RegisteredRulerKinds()).Should(gomega.ContainElement("prefix"))
+
+	targets := map[string]*corev1.Pod{
+		"foo-0": {ObjectMeta: metav1.ObjectMeta{Name: "foo-0"}},
+		"bar-0": {ObjectMeta: metav1.ObjectMeta{Name: "bar-0"}},
+	}
+	subjects := sets.NewString("foo-0", "bar-0")
+	rule := &appsv1alpha1.TransitionRule{Name: "custom"}
+	extended := &kuperatorv1alpha1.ExtendedRuleDefinition{}
+	g.Expect(json.Unmarshal([]byte(`{"custom": {"kind": "prefix", "params": {"prefix": "foo"}}}`), extended)).ShouldNot(gomega.HaveOccurred())
+
+	res := GetRuler(rule, extended, nil, nil).Filter(normalRS, targets, subjects)
+	g.Expect(res.Err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(res.Passed.List()).Should(gomega.Equal([]string{"foo-0"}))
+	g.Expect(res.Rejected).Should(gomega.HaveKey("bar-0"))
+
+	// invalid params
+	extended.Custom.Params = nil
+	res = GetRuler(rule, extended, nil, nil).Filter(normalRS, targets, subjects)
+	g.Expect(res.Err).Should(gomega.HaveOccurred())
+	g.Expect(res.Rejected).Should(gomega.HaveLen(2))
+
+	// kind not registered
+	extended.Custom.Kind = "unknown"
+	res = GetRuler(rule, extended, nil, nil).Filter(normalRS, targets, subjects)
+	g.Expect(res.Err).Should(gomega.HaveOccurred())
+	g.Expect(res.Rejected["foo-0"]).Should(gomega.ContainSubstring("not registered"))
+}
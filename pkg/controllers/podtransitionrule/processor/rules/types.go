@@ -24,6 +24,8 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
 )
 
 type Ruler interface {
@@ -37,16 +39,25 @@ type FilterResult struct {
 	Err      error
 
 	RuleState *appsv1alpha1.RuleState
+
+	// Members are results of member rules keyed by rule name, if the rule is a group
+	Members map[string]*FilterResult
 }
 
-func GetRuler(rule *appsv1alpha1.TransitionRule, client client.Client) Ruler {
+// GetRuler returns the Ruler of the rule, the extended definition is used if the rule has no definition in spec.
+// The reader reads objects not cached by client, e.g. Secrets.
+func GetRuler(rule *appsv1alpha1.TransitionRule, extended *kuperatorv1alpha1.ExtendedRuleDefinition, client client.Client, reader client.Reader) Ruler {
 	if rule.AvailablePolicy != nil {
-		return &AvailableRuler{
+		ruler := &AvailableRuler{
 			Client:              client,
 			MinAvailableValue:   rule.AvailablePolicy.MinAvailableValue,
 			MaxUnavailableValue: rule.AvailablePolicy.MaxUnavailableValue,
 			Name:                rule.Name,
 		}
+		if extended != nil && extended.AvailablePolicy != nil {
+			ruler.TopologyKey = extended.AvailablePolicy.TopologyKey
+		}
+		return ruler
 	}
 	if rule.LabelCheck != nil {
 		return &LabelCheckRuler{
@@ -55,7 +66,62 @@ func GetRuler(rule *appsv1alpha1.TransitionRule, client client.Client) Ruler {
 		}
 	}
 	if rule.Webhook != nil {
-		return &WebhookRuler{Name: rule.Name}
+		ruler := &WebhookRuler{Name: rule.Name, Reader: reader}
+		if extended != nil {
+			ruler.Extension = extended.Webhook
+		}
+		return ruler
+	}
+	if extended == nil {
+		return nil
+	}
+	if extended.MetricCheck != nil {
+		return &MetricCheckRuler{
+			Name:        rule.Name,
+			MetricCheck: extended.MetricCheck,
+		}
+	}
+	if extended.Cel != nil {
+		return &CelRuler{
+			Name: rule.Name,
+			Cel:  extended.Cel,
+		}
+	}
+	if extended.MaintenanceWindow != nil {
+		return &MaintenanceWindowRuler{
+			Name:              rule.Name,
+			MaintenanceWindow: extended.MaintenanceWindow,
+		}
+	}
+	if extended.RateLimit != nil {
+		return &RateLimitRuler{
+			Name:      rule.Name,
+			RateLimit: extended.RateLimit,
+		}
+	}
+	if extended.Dependency != nil {
+		return &DependencyRuler{
+			Name:       rule.Name,
+			Dependency: extended.Dependency,
+			Client:     client,
+		}
+	}
+	if extended.ManualApproval != nil {
+		return &ManualApprovalRuler{
+			Name:           rule.Name,
+			ManualApproval: extended.ManualApproval,
+		}
+	}
+	if extended.Custom != nil {
+		factory, ok := GetRulerFactory(extended.Custom.Kind)
+		if !ok {
+			return &invalidRuler{name: rule.Name, err: fmt.Errorf("kind %q is not registered", extended.Custom.Kind)}
+		}
+		ruler, err := factory(rule, extended.Custom.Params, client)
+		if err != nil {
+			return &invalidRuler{name: rule.Name, err: err}
+		}
+		return ruler
 	}
 	return nil
 }
@@ -26,7 +26,9 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
 	controllerutils "kusionstack.io/kuperator/pkg/controllers/podtransitionrule/utils"
 	"kusionstack.io/kuperator/pkg/utils"
 	utilshttp "kusionstack.io/kuperator/pkg/utils/http"
@@ -34,6 +36,10 @@ import (
 
 type WebhookRuler struct {
 	Name string
+
+	Extension *kuperatorv1alpha1.WebhookExtension
+	// Reader reads Secrets of the extension from API server, so that Secrets are not cached by controller
+	Reader client.Reader
 }
 
 func (r *WebhookRuler) Filter(
@@ -41,7 +47,17 @@ func (r *WebhookRuler) Filter(
 	targets map[string]*corev1.Pod,
 	subjects sets.String,
 ) *FilterResult {
-	return GetWebhook(podTransitionRule, r.Name)[0].Do(targets, subjects)
+	web := GetWebhook(podTransitionRule, r.Name)[0]
+	if r.Extension != nil {
+		auth, err := ResolveWebhookAuth(r.Reader, podTransitionRule.Namespace, r.Extension)
+		if err != nil {
+			return rejectAllWithErr(subjects, sets.NewString(), map[string]string{}, "[%s] fail to resolve webhook auth: %v", r.Name, err)
+		}
+		auth.Key = web.Key
+		web.Extension = r.Extension
+		web.Auth = auth
+	}
+	return web.Do(targets, subjects)
 }
 
 const (
@@ -96,11 +112,21 @@ type Webhook struct {
 
 	Approved func(string) bool
 
+	// Extension and Auth are set if the rule has an extended webhook definition
+	Extension *kuperatorv1alpha1.WebhookExtension
+	Auth      *WebhookAuth
+
 	retryInterval *time.Duration
 	taskInfo      map[string]*appsv1alpha1.TaskInfo
 }
 
 func (w *Webhook) Do(targets map[string]*corev1.Pod, subjects sets.String) *FilterResult {
+	res := w.do(targets, subjects)
+	w.recordApprovals(targets, res.Passed)
+	return res
+}
+
+func (w *Webhook) do(targets map[string]*corev1.Pod, subjects sets.String) *FilterResult {
 	w.taskInfo = map[string]*appsv1alpha1.TaskInfo{}
 	effectiveSubjects := sets.NewString(subjects.List()...)
 	checked := sets.NewString()
@@ -112,6 +138,7 @@ func (w *Webhook) Do(targets map[string]*corev1.Pod, subjects sets.String) *Filt
 			checked.Insert(sub)
 		}
 	}
+	w.approveCached(targets, effectiveSubjects, checked)
 
 	newWebhookState := &appsv1alpha1.WebhookStatus{
 		TaskStates: []appsv1alpha1.TaskInfo{},
@@ -144,17 +171,18 @@ func (w *Webhook) Do(targets map[string]*corev1.Pod, subjects sets.String) *Filt
 		// get latest polling result
 		pollingResult := PollingManager.GetResult(taskId)
 
-		// restart case
+		// restart case, resume polling the task instead of requesting the webhook again
 		if pollingResult == nil {
-			pollUrl, _ := w.getPollingUrl(taskId)
-			PollingManager.Add(
-				taskId,
-				pollUrl,
-				w.Webhook.ClientConfig.Poll.CABundle,
-				w.Key,
-				time.Duration(*w.Webhook.ClientConfig.Poll.TimeoutSeconds)*time.Second,
-				time.Duration(*w.Webhook.ClientConfig.Poll.IntervalSeconds)*time.Second,
-			)
+			pollUrl, err := w.getPollingUrl(taskId)
+			if err == nil {
+				beginTime := time.Now()
+				if state.BeginTime != nil {
+					beginTime = state.BeginTime.Time
+				}
+				w.addPollingTask(taskId, pollUrl, beginTime)
+			}
+			// keep the task in TaskStates, otherwise pods are requested again in next reconcile
+			w.taskInfo[taskId] = w.State.WebhookStatus.TaskStates[i].DeepCopy()
 			rejectMsg := fmt.Sprintf(
 				"Task %s polling result not found, try polling again, %s",
 				w.Key,
@@ -218,6 +246,7 @@ func (w *Webhook) Do(targets map[string]*corev1.Pod, subjects sets.String) *Filt
 
 	effectiveSubjects.Delete(allTracingPods.List()...)
 	effectiveSubjects.Delete(checked.List()...)
+	w.filterBackoff(targets, effectiveSubjects, rejectedPods)
 
 	if effectiveSubjects.Len() == 0 {
 		return &FilterResult{
@@ -246,9 +275,11 @@ func (w *Webhook) Do(targets map[string]*corev1.Pod, subjects sets.String) *Filt
 			selfTraceId,
 			utils.DumpJSON(res),
 		)
+		w.backoffFailed(targets, effectiveSubjects.List())
 		return &FilterResult{
 			Passed:    checked,
 			Rejected:  rejectedPods,
+			Interval:  w.retryInterval,
 			Err:       err,
 			RuleState: &appsv1alpha1.RuleState{Name: w.RuleName, WebhookStatus: newWebhookState},
 		}
@@ -279,7 +310,9 @@ func (w *Webhook) Do(targets map[string]*corev1.Pod, subjects sets.String) *Filt
 			)
 		}
 		// requeue
-		w.updateInterval(defaultInterval)
+		if !w.backoffFailed(targets, processing) {
+			w.updateInterval(defaultInterval)
+		}
 	} else if !shouldPoll(res) {
 		// success, All passed
 		checked.Insert(effectiveSubjects.List()...)
@@ -312,14 +345,7 @@ func (w *Webhook) Do(targets map[string]*corev1.Pod, subjects sets.String) *Filt
 			}
 		}
 		// add to polling manager
-		PollingManager.Add(
-			taskId,
-			pollUrl,
-			w.Webhook.ClientConfig.Poll.CABundle,
-			w.Key,
-			time.Duration(*w.Webhook.ClientConfig.Poll.TimeoutSeconds)*time.Second,
-			time.Duration(*w.Webhook.ClientConfig.Poll.IntervalSeconds)*time.Second,
-		)
+		w.addPollingTask(taskId, pollUrl, time.Now())
 		klog.Infof("%s, polling task %s initialized.", w.Key, taskId)
 		w.newTaskInfo(taskId, res.Message, processing, approved)
 		checked.Insert(approved...)
@@ -341,6 +367,25 @@ func (w *Webhook) Do(targets map[string]*corev1.Pod, subjects sets.String) *Filt
 	}
 }
 
+func (w *Webhook) addPollingTask(taskId, pollUrl string, beginTime time.Time) {
+	var limits PollingLimits
+	if w.Extension != nil && w.Extension.Polling != nil {
+		limits.MaxConcurrentTasks = int(w.Extension.Polling.MaxConcurrentTasks)
+		limits.Deadline = time.Duration(w.Extension.Polling.DeadlineSeconds) * time.Second
+	}
+	PollingManager.Add(
+		taskId,
+		pollUrl,
+		w.Webhook.ClientConfig.Poll.CABundle,
+		w.Key,
+		w.Auth,
+		beginTime,
+		time.Duration(*w.Webhook.ClientConfig.Poll.TimeoutSeconds)*time.Second,
+		time.Duration(*w.Webhook.ClientConfig.Poll.IntervalSeconds)*time.Second,
+		limits,
+	)
+}
+
 func (w *Webhook) convTaskInfo(infoMap map[string]*appsv1alpha1.TaskInfo) []appsv1alpha1.TaskInfo {
 	states := make([]appsv1alpha1.TaskInfo, 0, len(infoMap))
 	for _, v := range infoMap {
@@ -393,6 +438,10 @@ func (w *Webhook) getPollingUrl(taskId string) (string, error) {
 	if w.Webhook.ClientConfig.Poll == nil {
 		return "", fmt.Errorf("null polling config in rule %s", w.Key)
 	}
+	if w.Auth.isGRPC() {
+		// the task id is sent in the poll request
+		return w.Webhook.ClientConfig.Poll.URL, nil
+	}
 	pollUrl := fmt.Sprintf("%s?task-id=%s", w.Webhook.ClientConfig.Poll.URL, taskId)
 	if w.Webhook.ClientConfig.Poll.RawQueryKey != "" {
 		pollUrl = fmt.Sprintf("%s?%s=%s", w.Webhook.ClientConfig.Poll.URL, w.Webhook.ClientConfig.Poll.RawQueryKey, taskId)
@@ -410,9 +459,14 @@ func (w *Webhook) query(podSet sets.String, targets map[string]*corev1.Pod) (str
 }
 
 func (w *Webhook) doHttp(req *appsv1alpha1.WebhookRequest) (*appsv1alpha1.WebhookResponse, error) {
-	httpResp, err := utilshttp.DoHttpAndHttpsRequestWithCa(http.MethodPost, w.Webhook.ClientConfig.URL, *req, nil, w.Webhook.ClientConfig.CABundle)
+	if w.Auth.isGRPC() {
+		return w.Auth.checkGRPC(w.Webhook.ClientConfig.URL, w.Webhook.ClientConfig.CABundle, req)
+	}
+	httpResp, err := w.Auth.do(http.MethodPost, w.Webhook.ClientConfig.URL, *req, w.Webhook.ClientConfig.CABundle)
 	defer func() {
-		_ = httpResp.Body.Close()
+		if httpResp != nil {
+			_ = httpResp.Body.Close()
+		}
 	}()
 	if err != nil {
 		return nil, err
@@ -0,0 +1,273 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+	utilshttp "kusionstack.io/kuperator/pkg/utils/http"
+)
+
+// idleEntryTTL is the time after which entries of pods not seen are dropped from webhook caches
+const idleEntryTTL = time.Hour
+
+// WebhookAuth is the protocol and authentication of webhook and polling requests resolved from WebhookExtension
+type WebhookAuth struct {
+	// Key identifies the webhook, whose previous clients are dropped once the certificate is rotated
+	Key string
+	// Protocol of requests, http if empty
+	Protocol   kuperatorv1alpha1.WebhookProtocol
+	Headers    map[string]string
+	ClientCert []byte
+	ClientKey  []byte
+}
+
+// ResolveWebhookAuth reads the bearer token and client certificate of the webhook from Secrets
+func ResolveWebhookAuth(c client.Reader, namespace string, ext *kuperatorv1alpha1.WebhookExtension) (*WebhookAuth, error) {
+	auth := &WebhookAuth{Protocol: ext.Protocol, Headers: map[string]string{}}
+	for k, v := range ext.Headers {
+		auth.Headers[k] = v
+	}
+	if ext.BearerTokenSecretRef != nil {
+		secret := &corev1.Secret{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: ext.BearerTokenSecretRef.Name}, secret); err != nil {
+			return nil, fmt.Errorf("fail to get bearer token secret %s: %w", ext.BearerTokenSecretRef.Name, err)
+		}
+		token, ok := secret.Data[ext.BearerTokenSecretRef.Key]
+		if !ok {
+			return nil, fmt.Errorf("key %s not found in bearer token secret %s", ext.BearerTokenSecretRef.Key, ext.BearerTokenSecretRef.Name)
+		}
+		auth.Headers["Authorization"] = "Bearer " + string(token)
+	}
+	if ext.ClientCertSecretRef != nil {
+		secret := &corev1.Secret{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: ext.ClientCertSecretRef.Name}, secret); err != nil {
+			return nil, fmt.Errorf("fail to get client certificate secret %s: %w", ext.ClientCertSecretRef.Name, err)
+		}
+		auth.ClientCert, auth.ClientKey = secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]
+		if len(auth.ClientCert) == 0 || len(auth.ClientKey) == 0 {
+			return nil, fmt.Errorf("%s or %s not found in client certificate secret %s", corev1.TLSCertKey, corev1.TLSPrivateKeyKey, ext.ClientCertSecretRef.Name)
+		}
+	}
+	return auth, nil
+}
+
+func (a *WebhookAuth) do(method, url string, body interface{}, ca string) (*http.Response, error) {
+	if a == nil {
+		return utilshttp.DoHttpAndHttpsRequestWithCa(method, url, body, nil, ca)
+	}
+	if len(a.ClientCert) > 0 {
+		return utilshttp.DoHttpAndHttpsRequestWithClientCert(method, url, body, a.Headers, ca, a.ClientCert, a.ClientKey, a.Key)
+	}
+	return utilshttp.DoHttpAndHttpsRequestWithCa(method, url, body, a.Headers, ca)
+}
+
+// podRevisionKey identifies the pod with its revision, so that caches are invalid once the pod is updated
+func podRevisionKey(webhookKey string, pod *corev1.Pod) string {
+	return fmt.Sprintf("%s/%s/%s/%s", webhookKey, pod.Name, pod.UID, pod.Labels[appsv1.ControllerRevisionHashLabelKey])
+}
+
+// webhookBackoffs and webhookApprovals are kept in memory, they are rebuilt after restart of controller
+var (
+	webhookBackoffs  = &backoffTracker{states: map[string]*backoffState{}}
+	webhookApprovals = &approvalCache{expires: map[string]time.Time{}}
+)
+
+type backoffState struct {
+	failures int32
+	next     time.Time
+	lastSeen time.Time
+}
+
+type backoffTracker struct {
+	states map[string]*backoffState
+	mu     sync.Mutex
+}
+
+// check returns whether the pod exceeds max retries, and how long to wait for the next retry
+func (b *backoffTracker) check(key string, backoff *kuperatorv1alpha1.WebhookBackoff) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.states[key]
+	if !ok {
+		return false, 0
+	}
+	now := timeNow()
+	state.lastSeen = now
+	if backoff.MaxRetries > 0 && state.failures >= backoff.MaxRetries {
+		return true, 0
+	}
+	return false, state.next.Sub(now)
+}
+
+// fail records a failure of the pod and returns the interval before the next retry
+func (b *backoffTracker) fail(key string, backoff *kuperatorv1alpha1.WebhookBackoff) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := timeNow()
+	b.gc(now)
+	state, ok := b.states[key]
+	if !ok {
+		state = &backoffState{}
+		b.states[key] = state
+	}
+	state.failures++
+	interval, maxInterval := backoffSeconds(backoff)
+	for i := int32(1); i < state.failures && interval < maxInterval; i++ {
+		interval *= 2
+	}
+	if interval > maxInterval {
+		interval = maxInterval
+	}
+	state.next = now.Add(interval)
+	state.lastSeen = now
+	return interval
+}
+
+func (b *backoffTracker) failures(key string) int32 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if state, ok := b.states[key]; ok {
+		return state.failures
+	}
+	return 0
+}
+
+func (b *backoffTracker) reset(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.states, key)
+}
+
+func (b *backoffTracker) gc(now time.Time) {
+	for key, state := range b.states {
+		if now.Sub(state.lastSeen) > idleEntryTTL {
+			delete(b.states, key)
+		}
+	}
+}
+
+func backoffSeconds(backoff *kuperatorv1alpha1.WebhookBackoff) (time.Duration, time.Duration) {
+	initial, max := kuperatorv1alpha1.DefaultWebhookBackoffInitialIntervalSeconds, kuperatorv1alpha1.DefaultWebhookBackoffMaxIntervalSeconds
+	if backoff.InitialIntervalSeconds > 0 {
+		initial = backoff.InitialIntervalSeconds
+	}
+	if backoff.MaxIntervalSeconds > 0 {
+		max = backoff.MaxIntervalSeconds
+	}
+	return time.Duration(initial) * time.Second, time.Duration(max) * time.Second
+}
+
+type approvalCache struct {
+	expires map[string]time.Time
+	mu      sync.Mutex
+}
+
+func (c *approvalCache) add(key string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := timeNow()
+	for k, expire := range c.expires {
+		if now.After(expire) {
+			delete(c.expires, k)
+		}
+	}
+	c.expires[key] = now.Add(ttl)
+}
+
+func (c *approvalCache) has(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expire, ok := c.expires[key]
+	return ok && timeNow().Before(expire)
+}
+
+// approveCached passes pods approved recently with the same revision without calling the webhook
+func (w *Webhook) approveCached(targets map[string]*corev1.Pod, effectiveSubjects, checked sets.String) {
+	if w.Extension == nil || w.Extension.ApprovalCacheSeconds <= 0 {
+		return
+	}
+	for _, sub := range effectiveSubjects.List() {
+		if webhookApprovals.has(podRevisionKey(w.Key, targets[sub])) {
+			effectiveSubjects.Delete(sub)
+			checked.Insert(sub)
+		}
+	}
+}
+
+// recordApprovals caches approvals of pods, and resets their backoff
+func (w *Webhook) recordApprovals(targets map[string]*corev1.Pod, passed sets.String) {
+	if w.Extension == nil {
+		return
+	}
+	for sub := range passed {
+		key := podRevisionKey(w.Key, targets[sub])
+		if w.Extension.ApprovalCacheSeconds > 0 {
+			webhookApprovals.add(key, time.Duration(w.Extension.ApprovalCacheSeconds)*time.Second)
+		}
+		if w.Extension.Backoff != nil {
+			webhookBackoffs.reset(key)
+		}
+	}
+}
+
+// filterBackoff rejects pods waiting for the next retry or exceeding max retries, so that they are not requested
+func (w *Webhook) filterBackoff(targets map[string]*corev1.Pod, effectiveSubjects sets.String, rejectedPods map[string]string) {
+	if w.Extension == nil || w.Extension.Backoff == nil {
+		return
+	}
+	for _, sub := range effectiveSubjects.List() {
+		key := podRevisionKey(w.Key, targets[sub])
+		exceeded, wait := webhookBackoffs.check(key, w.Extension.Backoff)
+		if exceeded {
+			effectiveSubjects.Delete(sub)
+			rejectedPods[sub] = fmt.Sprintf("Rejected by webhook %s permanently after %d retries", w.Key, webhookBackoffs.failures(key))
+			continue
+		}
+		if wait > 0 {
+			effectiveSubjects.Delete(sub)
+			rejectedPods[sub] = fmt.Sprintf("Webhook %s backing off after %d failures, retry in %s", w.Key, webhookBackoffs.failures(key), wait.Round(time.Second))
+			w.updateInterval(wait)
+		}
+	}
+}
+
+// backoffFailed records failures of pods and updates the retry interval. It returns false if backoff is not configured.
+func (w *Webhook) backoffFailed(targets map[string]*corev1.Pod, pods []string) bool {
+	if w.Extension == nil || w.Extension.Backoff == nil {
+		return false
+	}
+	for _, po := range pods {
+		key := podRevisionKey(w.Key, targets[po])
+		interval := webhookBackoffs.fail(key, w.Extension.Backoff)
+		if exceeded, _ := webhookBackoffs.check(key, w.Extension.Backoff); !exceeded {
+			w.updateInterval(interval)
+		}
+	}
+	return true
+}
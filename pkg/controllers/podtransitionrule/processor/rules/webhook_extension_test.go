@@ -0,0 +1,224 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+// fakeWebhook approves pods if check passes, and counts requests
+func fakeWebhook(requests *int32, check func(req *http.Request) bool) http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(requests, 1)
+		webReq := &appsv1alpha1.WebhookRequest{}
+		if err := json.NewDecoder(req.Body).Decode(webReq); err != nil {
+			http.Error(resp, err.Error(), http.StatusBadRequest)
+			return
+		}
+		webResp := &appsv1alpha1.WebhookResponse{Success: check(req), Message: "checked"}
+		if webResp.Success {
+			for _, res := range webReq.Resources {
+				webResp.FinishedNames = append(webResp.FinishedNames, res.Name)
+			}
+		}
+		_ = json.NewEncoder(resp).Encode(webResp)
+	}
+}
+
+func webhookRS(name, url, ca string) *appsv1alpha1.PodTransitionRule {
+	return &appsv1alpha1.PodTransitionRule{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: appsv1alpha1.PodTransitionRuleSpec{
+			Rules: []appsv1alpha1.TransitionRule{
+				{
+					Name:  "webhook",
+					Stage: &stage,
+					TransitionRuleDefinition: appsv1alpha1.TransitionRuleDefinition{
+						Webhook: &appsv1alpha1.TransitionRuleWebhook{
+							ClientConfig: appsv1alpha1.ClientConfigBeta1{URL: url, CABundle: ca},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestWebhookAuth(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	var requests int32
+	server := httptest.NewServer(fakeWebhook(&requests, func(req *http.Request) bool {
+		return req.Header.Get("Authorization") == "Bearer token" && req.Header.Get("X-Team") == "foo"
+	}))
+	defer server.Close()
+
+	token := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("token")},
+	}
+	ruler := &WebhookRuler{
+		Name:   "webhook",
+		Reader: fake.NewClientBuilder().WithObjects(token).Build(),
+		Extension: &kuperatorv1alpha1.WebhookExtension{
+			BearerTokenSecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "token"}, Key: "token"},
+			Headers:              map[string]string{"X-Team": "foo"},
+		},
+	}
+	targets := map[string]*corev1.Pod{"test-pod-a": (&podTemplate{Name: "test-pod-a"}).GetPod()}
+	res := ruler.Filter(webhookRS("webhook-auth", server.URL, ""), targets, sets.NewString("test-pod-a"))
+	g.Expect(res.Err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(res.Passed.List()).Should(gomega.Equal([]string{"test-pod-a"}))
+
+	// secret not found
+	ruler.Extension.BearerTokenSecretRef.Name = "not-found"
+	res = ruler.Filter(webhookRS("webhook-auth", server.URL, ""), targets, sets.NewString("test-pod-a"))
+	g.Expect(res.Err).Should(gomega.HaveOccurred())
+	g.Expect(res.Rejected).Should(gomega.HaveKey("test-pod-a"))
+	g.Expect(requests).Should(gomega.BeEquivalentTo(1))
+}
+
+func TestWebhookClientCert(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	var requests int32
+	server := httptest.NewUnstartedServer(fakeWebhook(&requests, func(req *http.Request) bool {
+		return req.TLS != nil && len(req.TLS.PeerCertificates) == 1 && req.TLS.PeerCertificates[0].Subject.CommonName == "kuperator"
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+	ca := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	cert, key := newClientCert(g, "kuperator")
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "client-cert", Namespace: "default"},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{corev1.TLSCertKey: cert, corev1.TLSPrivateKeyKey: key},
+	}
+	ruler := &WebhookRuler{
+		Name:   "webhook",
+		Reader: fake.NewClientBuilder().WithObjects(secret).Build(),
+		Extension: &kuperatorv1alpha1.WebhookExtension{
+			ClientCertSecretRef: &corev1.LocalObjectReference{Name: "client-cert"},
+		},
+	}
+	targets := map[string]*corev1.Pod{"test-pod-a": (&podTemplate{Name: "test-pod-a"}).GetPod()}
+	res := ruler.Filter(webhookRS("webhook-mtls", server.URL, ca), targets, sets.NewString("test-pod-a"))
+	g.Expect(res.Err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(res.Passed.List()).Should(gomega.Equal([]string{"test-pod-a"}))
+}
+
+func TestWebhookBackoffAndApprovalCache(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer func() { timeNow = time.Now }()
+	now := time.Now()
+	timeNow = func() time.Time { return now }
+
+	var requests int32
+	approve := false
+	server := httptest.NewServer(fakeWebhook(&requests, func(req *http.Request) bool { return approve }))
+	defer server.Close()
+
+	ruler := &WebhookRuler{
+		Name: "webhook",
+		Extension: &kuperatorv1alpha1.WebhookExtension{
+			Backoff:              &kuperatorv1alpha1.WebhookBackoff{InitialIntervalSeconds: 10, MaxIntervalSeconds: 15, MaxRetries: 3},
+			ApprovalCacheSeconds: 60,
+		},
+	}
+	targets := map[string]*corev1.Pod{
+		"test-pod-a": (&podTemplate{Name: "test-pod-a"}).GetPod(),
+		"test-pod-b": (&podTemplate{Name: "test-pod-b"}).GetPod(),
+	}
+	rs := webhookRS("webhook-backoff", server.URL, "")
+
+	// first failure
+	res := ruler.Filter(rs, targets, sets.NewString("test-pod-a"))
+	g.Expect(res.Rejected).Should(gomega.HaveKey("test-pod-a"))
+	g.Expect(*res.Interval).Should(gomega.Equal(10 * time.Second))
+
+	// backing off without request
+	res = ruler.Filter(rs, targets, sets.NewString("test-pod-a"))
+	g.Expect(res.Rejected["test-pod-a"]).Should(gomega.ContainSubstring("backing off"))
+	g.Expect(requests).Should(gomega.BeEquivalentTo(1))
+
+	// interval is doubled and capped
+	now = now.Add(10 * time.Second)
+	res = ruler.Filter(rs, targets, sets.NewString("test-pod-a"))
+	g.Expect(*res.Interval).Should(gomega.Equal(15 * time.Second))
+
+	// rejected permanently after max retries
+	now = now.Add(15 * time.Second)
+	res = ruler.Filter(rs, targets, sets.NewString("test-pod-a"))
+	g.Expect(res.Interval).Should(gomega.BeNil())
+	res = ruler.Filter(rs, targets, sets.NewString("test-pod-a"))
+	g.Expect(res.Rejected["test-pod-a"]).Should(gomega.ContainSubstring("permanently"))
+	g.Expect(requests).Should(gomega.BeEquivalentTo(3))
+
+	// approvals are cached
+	approve = true
+	res = ruler.Filter(rs, targets, sets.NewString("test-pod-b"))
+	g.Expect(res.Passed.List()).Should(gomega.Equal([]string{"test-pod-b"}))
+	res = ruler.Filter(rs, targets, sets.NewString("test-pod-b"))
+	g.Expect(res.Passed.List()).Should(gomega.Equal([]string{"test-pod-b"}))
+	g.Expect(requests).Should(gomega.BeEquivalentTo(4))
+
+	// a new revision invalidates the cache
+	targets["test-pod-b"].Labels["controller-revision-hash"] = "v2"
+	res = ruler.Filter(rs, targets, sets.NewString("test-pod-b"))
+	g.Expect(res.Passed.List()).Should(gomega.Equal([]string{"test-pod-b"}))
+	g.Expect(requests).Should(gomega.BeEquivalentTo(5))
+}
+
+func newClientCert(g *gomega.WithT, commonName string) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+	webhookv1alpha1 "kusionstack.io/kuperator/apis/webhook/v1alpha1"
+	utilsgrpc "kusionstack.io/kuperator/pkg/utils/grpc"
+)
+
+func (a *WebhookAuth) isGRPC() bool {
+	return a != nil && a.Protocol == kuperatorv1alpha1.WebhookProtocolGRPC
+}
+
+// checkGRPC sends the webhook request by the Check call of grpc protocol
+func (a *WebhookAuth) checkGRPC(url, ca string, req *appsv1alpha1.WebhookRequest) (*appsv1alpha1.WebhookResponse, error) {
+	conn, err := utilsgrpc.DefaultConns.GetConn(a.Key, url, ca, a.ClientCert, a.ClientKey)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := a.grpcContext()
+	defer cancel()
+	resp, err := webhookv1alpha1.NewPodTransitionRuleWebhookClient(conn).Check(ctx, toWebhookRequestPB(req))
+	if err != nil {
+		return nil, err
+	}
+	return &appsv1alpha1.WebhookResponse{
+		Success:       resp.Success,
+		Message:       resp.Message,
+		TraceId:       resp.TraceId,
+		FinishedNames: resp.FinishedNames,
+		Async:         resp.Async,
+		Poll:          resp.Poll,
+		TaskId:        resp.TaskId,
+	}, nil
+}
+
+// pollGRPC queries the task by the Poll call of grpc protocol
+func (a *WebhookAuth) pollGRPC(url, ca, taskId string) (*appsv1alpha1.PollResponse, error) {
+	conn, err := utilsgrpc.DefaultConns.GetConn(a.Key, url, ca, a.ClientCert, a.ClientKey)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := a.grpcContext()
+	defer cancel()
+	resp, err := webhookv1alpha1.NewPodTransitionRuleWebhookClient(conn).Poll(ctx, &webhookv1alpha1.PollRequest{TaskId: taskId})
+	if err != nil {
+		return nil, err
+	}
+	return &appsv1alpha1.PollResponse{
+		Success:       resp.Success,
+		Message:       resp.Message,
+		Finished:      resp.Finished,
+		FinishedNames: resp.FinishedNames,
+		Stop:          resp.Stop,
+	}, nil
+}
+
+// grpcContext sends headers as metadata, and limits the call by the timeout of requests
+func (a *WebhookAuth) grpcContext() (context.Context, context.CancelFunc) {
+	ctx := context.Background()
+	if len(a.Headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(a.Headers))
+	}
+	return context.WithTimeout(ctx, utilsgrpc.Timeout)
+}
+
+func toWebhookRequestPB(req *appsv1alpha1.WebhookRequest) *webhookv1alpha1.WebhookRequest {
+	pb := &webhookv1alpha1.WebhookRequest{
+		RuleName: req.RuleName,
+		TraceId:  req.TraceId,
+	}
+	if req.Stage != nil {
+		pb.Stage = *req.Stage
+	}
+	for _, res := range req.Resources {
+		pb.Resources = append(pb.Resources, &webhookv1alpha1.ResourceParameter{
+			ApiVersion: res.ApiVersion,
+			Kind:       res.Kind,
+			Name:       res.Name,
+			Parameters: res.Parameters,
+		})
+	}
+	return pb
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+	webhookv1alpha1 "kusionstack.io/kuperator/apis/webhook/v1alpha1"
+)
+
+// fakeGRPCWebhook approves pods with name in approved at once, and others by polling
+type fakeGRPCWebhook struct {
+	webhookv1alpha1.UnimplementedPodTransitionRuleWebhookServer
+	approved sets.String
+	polling  []string
+}
+
+func (s *fakeGRPCWebhook) Check(ctx context.Context, req *webhookv1alpha1.WebhookRequest) (*webhookv1alpha1.WebhookResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md.Get("x-team")) == 0 || md.Get("x-team")[0] != "foo" {
+		return nil, status.Error(codes.Unauthenticated, "unknown team")
+	}
+	resp := &webhookv1alpha1.WebhookResponse{Success: true, TraceId: req.TraceId, TaskId: "grpc-task"}
+	for _, res := range req.Resources {
+		if s.approved.Has(res.Name) {
+			resp.FinishedNames = append(resp.FinishedNames, res.Name)
+		} else {
+			s.polling = append(s.polling, res.Name)
+			resp.Poll = true
+		}
+	}
+	return resp, nil
+}
+
+func (s *fakeGRPCWebhook) Poll(ctx context.Context, req *webhookv1alpha1.PollRequest) (*webhookv1alpha1.PollResponse, error) {
+	if req.TaskId != "grpc-task" {
+		return nil, status.Error(codes.NotFound, "task not found")
+	}
+	return &webhookv1alpha1.PollResponse{Success: true, Finished: true, FinishedNames: s.polling}, nil
+}
+
+func TestWebhookGRPC(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	server := grpc.NewServer()
+	webhookv1alpha1.RegisterPodTransitionRuleWebhookServer(server, &fakeGRPCWebhook{approved: sets.NewString("test-pod-a")})
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	url := "grpc://" + lis.Addr().String()
+	pollInterval, pollTimeout := int64(1), int64(60)
+	rs := webhookRS("webhook-grpc", url, "")
+	rs.Spec.Rules[0].Webhook.ClientConfig.Poll = &appsv1alpha1.Poll{
+		URL:             url,
+		IntervalSeconds: &pollInterval,
+		TimeoutSeconds:  &pollTimeout,
+	}
+	ruler := &WebhookRuler{
+		Name:   "webhook",
+		Reader: fake.NewClientBuilder().Build(),
+		Extension: &kuperatorv1alpha1.WebhookExtension{
+			Protocol: kuperatorv1alpha1.WebhookProtocolGRPC,
+			Headers:  map[string]string{"X-Team": "foo"},
+		},
+	}
+	targets := map[string]*corev1.Pod{
+		"test-pod-a": (&podTemplate{Name: "test-pod-a"}).GetPod(),
+		"test-pod-b": (&podTemplate{Name: "test-pod-b"}).GetPod(),
+	}
+	subjects := sets.NewString("test-pod-a", "test-pod-b")
+
+	res := ruler.Filter(rs, targets, subjects)
+	g.Expect(res.Err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(res.Passed.List()).Should(gomega.Equal([]string{"test-pod-a"}))
+	g.Expect(res.Rejected).Should(gomega.HaveKey("test-pod-b"))
+
+	// test-pod-b is approved by polling
+	g.Eventually(func() []string {
+		rs.Status.RuleStates = []*appsv1alpha1.RuleState{res.RuleState}
+		res = ruler.Filter(rs, targets, subjects)
+		return res.Passed.List()
+	}, 5*time.Second, 500*time.Millisecond).Should(gomega.Equal([]string{"test-pod-a", "test-pod-b"}))
+
+	// headers are sent as metadata
+	ruler.Extension.Headers = nil
+	res = ruler.Filter(webhookRS("webhook-grpc-unauthenticated", url, ""), targets, sets.NewString("test-pod-b"))
+	g.Expect(res.Err).Should(gomega.HaveOccurred())
+	g.Expect(res.Rejected["test-pod-b"]).Should(gomega.ContainSubstring("unknown team"))
+}
@@ -21,6 +21,8 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
 )
 
 func HasSkipRule(po *corev1.Pod, ruleName string) (bool, error) {
@@ -61,3 +63,38 @@ func MoveDetailAnno(po *corev1.Pod, podtransitionruleName string) bool {
 	}
 	return ok
 }
+
+// GetExtendedRules returns the extended rule definitions of PodTransitionRule keyed by rule name
+func GetExtendedRules(rs *appsv1alpha1.PodTransitionRule) (map[string]*kuperatorv1alpha1.ExtendedRuleDefinition, error) {
+	if rs.Annotations == nil || len(rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules]) == 0 {
+		return nil, nil
+	}
+	extendedRules := map[string]*kuperatorv1alpha1.ExtendedRuleDefinition{}
+	if err := json.Unmarshal([]byte(rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules]), &extendedRules); err != nil {
+		return nil, err
+	}
+	return extendedRules, nil
+}
+
+// GetApprovals returns the approvals of manual approval rules keyed by rule name
+func GetApprovals(rs *appsv1alpha1.PodTransitionRule) (map[string][]kuperatorv1alpha1.Approval, error) {
+	if rs.Annotations == nil || len(rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleApprovals]) == 0 {
+		return nil, nil
+	}
+	approvals := map[string][]kuperatorv1alpha1.Approval{}
+	if err := json.Unmarshal([]byte(rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleApprovals]), &approvals); err != nil {
+		return nil, err
+	}
+	return approvals, nil
+}
+
+// GetRuleMode returns the mode of the rule, which is set by the extended definition or the PodTransitionRule
+func GetRuleMode(rs *appsv1alpha1.PodTransitionRule, extended *kuperatorv1alpha1.ExtendedRuleDefinition) kuperatorv1alpha1.RuleMode {
+	if extended != nil && extended.Mode != "" {
+		return extended.Mode
+	}
+	if mode := rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleMode]; mode != "" {
+		return kuperatorv1alpha1.RuleMode(mode)
+	}
+	return kuperatorv1alpha1.RuleModeEnforce
+}
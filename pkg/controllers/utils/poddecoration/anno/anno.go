@@ -19,14 +19,19 @@ package anno
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog/v2"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
 )
 
 type DecorationRevisionInfo []*DecorationInfo
@@ -153,3 +158,121 @@ func GetPodDecorationFromRevision(revision *appsv1.ControllerRevision) (*appsv1a
 	}
 	return podDecoration, nil
 }
+
+// GetInitContainerPatches returns the injection configs of init containers in annotation of PodDecoration
+func GetInitContainerPatches(pd *appsv1alpha1.PodDecoration) (map[string]*kuperatorv1alpha1.InitContainerPatch, error) {
+	patches := map[string]*kuperatorv1alpha1.InitContainerPatch{}
+	val, ok := pd.Annotations[kuperatorv1alpha1.AnnotationPodDecorationInitContainers]
+	if !ok {
+		return patches, nil
+	}
+	if err := json.Unmarshal([]byte(val), &patches); err != nil {
+		return nil, fmt.Errorf("fail to unmarshal annotation %s of PodDecoration %s/%s: %w",
+			kuperatorv1alpha1.AnnotationPodDecorationInitContainers, pd.Namespace, pd.Name, err)
+	}
+	return patches, nil
+}
+
+// GetPodPatches returns the patches in annotation of PodDecoration
+func GetPodPatches(pd *appsv1alpha1.PodDecoration) ([]kuperatorv1alpha1.PodPatch, error) {
+	var patches []kuperatorv1alpha1.PodPatch
+	val, ok := pd.Annotations[kuperatorv1alpha1.AnnotationPodDecorationPatches]
+	if !ok {
+		return patches, nil
+	}
+	if err := json.Unmarshal([]byte(val), &patches); err != nil {
+		return nil, fmt.Errorf("fail to unmarshal annotation %s of PodDecoration %s/%s: %w",
+			kuperatorv1alpha1.AnnotationPodDecorationPatches, pd.Namespace, pd.Name, err)
+	}
+	return patches, nil
+}
+
+// IsWebhookInjection returns true if pods not controlled by CollaSet are decorated by the pod webhook
+func IsWebhookInjection(pd *appsv1alpha1.PodDecoration) bool {
+	return pd.Annotations[kuperatorv1alpha1.AnnotationPodDecorationWebhookInjection] == "true"
+}
+
+// InjectedByWebhook returns true if the pod not controlled by CollaSet is decorated with the PodDecoration by
+// the pod webhook, both of them have to opt in
+func InjectedByWebhook(pod *corev1.Pod, pd *appsv1alpha1.PodDecoration) bool {
+	return pod.Labels[kuperatorv1alpha1.LabelPodWebhookInjection] == "true" && IsWebhookInjection(pd)
+}
+
+// GetPinnedRevision returns the revision the PodDecoration is pinned to, empty if not pinned
+func GetPinnedRevision(pd *appsv1alpha1.PodDecoration) string {
+	return pd.Annotations[kuperatorv1alpha1.AnnotationPodDecorationPinnedRevision]
+}
+
+// ClusterNamespace is the namespace of kuperator, in which PodDecorations with namespace selector are cluster-wide
+var ClusterNamespace = func() string {
+	if ns := os.Getenv("POD_NAMESPACE"); len(ns) > 0 {
+		return ns
+	}
+	return "kusionstack-system"
+}()
+
+// IsClusterWide returns true if the PodDecoration decorates pods in namespaces selected by its namespace selector
+func IsClusterWide(pd *appsv1alpha1.PodDecoration) bool {
+	_, ok := pd.Annotations[kuperatorv1alpha1.AnnotationPodDecorationNamespaceSelector]
+	return ok && pd.Namespace == ClusterNamespace
+}
+
+// GetNamespaceSelector returns the namespace selector of the PodDecoration, nil if not set
+func GetNamespaceSelector(pd *appsv1alpha1.PodDecoration) (labels.Selector, error) {
+	val, ok := pd.Annotations[kuperatorv1alpha1.AnnotationPodDecorationNamespaceSelector]
+	if !ok {
+		return nil, nil
+	}
+	selector := &metav1.LabelSelector{}
+	if err := json.Unmarshal([]byte(val), selector); err != nil {
+		return nil, fmt.Errorf("fail to unmarshal annotation %s of PodDecoration %s/%s: %w",
+			kuperatorv1alpha1.AnnotationPodDecorationNamespaceSelector, pd.Namespace, pd.Name, err)
+	}
+	return metav1.LabelSelectorAsSelector(selector)
+}
+
+// SelectsNamespace returns true if the PodDecoration decorates pods in the namespace
+func SelectsNamespace(pd *appsv1alpha1.PodDecoration, ns *corev1.Namespace) bool {
+	if !IsClusterWide(pd) {
+		return pd.Namespace == ns.Name
+	}
+	selector, err := GetNamespaceSelector(pd)
+	if err != nil {
+		klog.Errorf("fail to get namespace selector of PodDecoration %s/%s, %v", pd.Namespace, pd.Name, err)
+		return false
+	}
+	return selector.Matches(labels.Set(ns.Labels))
+}
+
+// GetGroup returns the group of the PodDecoration, empty if not in any group
+func GetGroup(pd *appsv1alpha1.PodDecoration) string {
+	return pd.Annotations[kuperatorv1alpha1.AnnotationPodDecorationGroup]
+}
+
+// GetCanary returns the canary steps of the PodDecoration, nil if not set
+func GetCanary(pd *appsv1alpha1.PodDecoration) (*kuperatorv1alpha1.PodDecorationCanary, error) {
+	val, ok := pd.Annotations[kuperatorv1alpha1.AnnotationPodDecorationCanary]
+	if !ok {
+		return nil, nil
+	}
+	canary := &kuperatorv1alpha1.PodDecorationCanary{}
+	if err := json.Unmarshal([]byte(val), canary); err != nil {
+		return nil, fmt.Errorf("fail to unmarshal annotation %s of PodDecoration %s/%s: %w",
+			kuperatorv1alpha1.AnnotationPodDecorationCanary, pd.Namespace, pd.Name, err)
+	}
+	return canary, nil
+}
+
+// GetCanaryStatus returns the progress of canary steps, nil if not set or invalid
+func GetCanaryStatus(pd *appsv1alpha1.PodDecoration) *kuperatorv1alpha1.PodDecorationCanaryStatus {
+	val, ok := pd.Annotations[kuperatorv1alpha1.AnnotationPodDecorationCanaryStatus]
+	if !ok {
+		return nil
+	}
+	status := &kuperatorv1alpha1.PodDecorationCanaryStatus{}
+	if err := json.Unmarshal([]byte(val), status); err != nil {
+		klog.Errorf("fail to unmarshal canary status of PodDecoration %s/%s, %v", pd.Namespace, pd.Name, err)
+		return nil
+	}
+	return status
+}
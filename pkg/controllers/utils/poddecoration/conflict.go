@@ -0,0 +1,206 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poddecoration
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/anno"
+)
+
+// Conflicts returns fields patched by both PodDecorations with different values, whose result on pods
+// depends on the order of PodDecorations. PodDecorations in the same group never conflict, because only one
+// of them decorates a pod.
+func Conflicts(a, b *appsv1alpha1.PodDecoration) []string {
+	if group := anno.GetGroup(a); group != "" && group == anno.GetGroup(b) {
+		return nil
+	}
+	fields := sets.NewString()
+	ta, tb := &a.Spec.Template, &b.Spec.Template
+
+	labelsA, annotationsA := templateMetadata(ta)
+	labelsB, annotationsB := templateMetadata(tb)
+	conflictMaps(fields, "label", labelsA, labelsB)
+	conflictMaps(fields, "annotation", annotationsA, annotationsB)
+
+	initContainers := map[string]*corev1.Container{}
+	for _, c := range ta.InitContainers {
+		initContainers[c.Name] = c
+	}
+	for _, c := range tb.InitContainers {
+		if other, ok := initContainers[c.Name]; ok && !equality.Semantic.DeepEqual(other, c) {
+			fields.Insert("initContainer " + c.Name)
+		}
+	}
+	// containers with the same name are injected twice
+	containers := sets.NewString()
+	for _, c := range ta.Containers {
+		containers.Insert(c.Name)
+	}
+	for _, c := range tb.Containers {
+		if containers.Has(c.Name) {
+			fields.Insert("container " + c.Name)
+		}
+	}
+
+	for _, pa := range ta.PrimaryContainers {
+		for _, pb := range tb.PrimaryContainers {
+			if !primaryTargetsOverlap(pa, pb) {
+				continue
+			}
+			if pa.Image != nil && pb.Image != nil && *pa.Image != *pb.Image {
+				fields.Insert("image of primary container")
+			}
+			envs := map[string]corev1.EnvVar{}
+			for _, env := range pa.Env {
+				envs[env.Name] = env
+			}
+			for _, env := range pb.Env {
+				if other, ok := envs[env.Name]; ok && !equality.Semantic.DeepEqual(other, env) {
+					fields.Insert("env " + env.Name)
+				}
+			}
+			mounts := map[string]corev1.VolumeMount{}
+			for _, mount := range pa.VolumeMounts {
+				mounts[mount.Name] = mount
+			}
+			for _, mount := range pb.VolumeMounts {
+				if other, ok := mounts[mount.Name]; ok && !equality.Semantic.DeepEqual(other, mount) {
+					fields.Insert("volumeMount " + mount.Name)
+				}
+			}
+		}
+	}
+
+	volumes := map[string]corev1.Volume{}
+	for _, v := range ta.Volumes {
+		volumes[v.Name] = v
+	}
+	for _, v := range tb.Volumes {
+		if other, ok := volumes[v.Name]; ok && !equality.Semantic.DeepEqual(other, v) {
+			fields.Insert("volume " + v.Name)
+		}
+	}
+
+	if ta.Affinity != nil && tb.Affinity != nil && ta.Affinity.OverrideAffinity != nil && tb.Affinity.OverrideAffinity != nil &&
+		!equality.Semantic.DeepEqual(ta.Affinity.OverrideAffinity, tb.Affinity.OverrideAffinity) {
+		fields.Insert("affinity")
+	}
+	if ta.RuntimeClassName != nil && tb.RuntimeClassName != nil && *ta.RuntimeClassName != *tb.RuntimeClassName {
+		fields.Insert("runtimeClassName")
+	}
+	return fields.List()
+}
+
+// templateMetadata returns labels and annotations set by the template, annotations merged as JSON are
+// not included
+func templateMetadata(template *appsv1alpha1.PodDecorationPodTemplate) (labels, annotations map[string]string) {
+	labels, annotations = map[string]string{}, map[string]string{}
+	for _, meta := range template.Metadata {
+		for k, v := range meta.Labels {
+			labels[k] = v
+		}
+		if meta.PatchPolicy == appsv1alpha1.MergePatchJsonMetadata {
+			continue
+		}
+		for k, v := range meta.Annotations {
+			annotations[k] = v
+		}
+	}
+	return
+}
+
+func conflictMaps(fields sets.String, kind string, a, b map[string]string) {
+	for k, v := range b {
+		if other, ok := a[k]; ok && other != v {
+			fields.Insert(fmt.Sprintf("%s %s", kind, k))
+		}
+	}
+}
+
+// primaryTargetsOverlap returns true if the patches target the same primary container for sure
+func primaryTargetsOverlap(a, b *appsv1alpha1.PrimaryContainerPatch) bool {
+	policyA, policyB := a.TargetPolicy, b.TargetPolicy
+	if policyA == "" {
+		policyA = appsv1alpha1.InjectByName
+	}
+	if policyB == "" {
+		policyB = appsv1alpha1.InjectByName
+	}
+	if policyA == appsv1alpha1.InjectAllContainers || policyB == appsv1alpha1.InjectAllContainers {
+		return true
+	}
+	if policyA != policyB {
+		return false
+	}
+	if policyA == appsv1alpha1.InjectByName {
+		return a.Name != nil && b.Name != nil && *a.Name == *b.Name
+	}
+	return true
+}
+
+// SelectorsOverlap returns false if no pod can be selected by both selectors. It only checks keys of
+// matchLabels, so it may return true for selectors not overlapping.
+func SelectorsOverlap(a, b *metav1.LabelSelector) bool {
+	if a == nil || b == nil {
+		return true
+	}
+	return !excludes(a, b) && !excludes(b, a)
+}
+
+// excludes returns true if pods matching matchLabels of a can not be selected by b
+func excludes(a, b *metav1.LabelSelector) bool {
+	for k, v := range a.MatchLabels {
+		if other, ok := b.MatchLabels[k]; ok && other != v {
+			return true
+		}
+		for _, req := range b.MatchExpressions {
+			if req.Key != k {
+				continue
+			}
+			values := sets.NewString(req.Values...)
+			switch req.Operator {
+			case metav1.LabelSelectorOpIn:
+				if !values.Has(v) {
+					return true
+				}
+			case metav1.LabelSelectorOpNotIn:
+				if values.Has(v) {
+					return true
+				}
+			case metav1.LabelSelectorOpDoesNotExist:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetConflictPolicy returns the conflict policy in annotation of PodDecoration
+func GetConflictPolicy(pd *appsv1alpha1.PodDecoration) kuperatorv1alpha1.ConflictPolicy {
+	if policy := pd.Annotations[kuperatorv1alpha1.AnnotationPodDecorationConflictPolicy]; policy != "" {
+		return kuperatorv1alpha1.ConflictPolicy(policy)
+	}
+	return kuperatorv1alpha1.ConflictPolicyReport
+}
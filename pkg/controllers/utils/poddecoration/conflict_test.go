@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poddecoration
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+func TestConflicts(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	main := "main"
+	kata := "kata"
+	a := &appsv1alpha1.PodDecoration{
+		Spec: appsv1alpha1.PodDecorationSpec{
+			Template: appsv1alpha1.PodDecorationPodTemplate{
+				Metadata: []*appsv1alpha1.PodDecorationPodTemplateMeta{
+					{Labels: map[string]string{"mesh": "istio", "same": "v"}},
+				},
+				PrimaryContainers: []*appsv1alpha1.PrimaryContainerPatch{
+					{
+						TargetPolicy:                  appsv1alpha1.InjectByName,
+						PodDecorationPrimaryContainer: appsv1alpha1.PodDecorationPrimaryContainer{Name: &main, Env: []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "info"}}},
+					},
+				},
+				Containers: []*appsv1alpha1.ContainerPatch{
+					{Container: corev1.Container{Name: "proxy"}},
+				},
+				Volumes: []corev1.Volume{{Name: "data"}},
+			},
+		},
+	}
+	b := &appsv1alpha1.PodDecoration{
+		Spec: appsv1alpha1.PodDecorationSpec{
+			Template: appsv1alpha1.PodDecorationPodTemplate{
+				Metadata: []*appsv1alpha1.PodDecorationPodTemplateMeta{
+					{Labels: map[string]string{"mesh": "linkerd", "same": "v"}},
+				},
+				PrimaryContainers: []*appsv1alpha1.PrimaryContainerPatch{
+					{
+						TargetPolicy:                  appsv1alpha1.InjectAllContainers,
+						PodDecorationPrimaryContainer: appsv1alpha1.PodDecorationPrimaryContainer{Env: []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}}},
+					},
+				},
+				Containers: []*appsv1alpha1.ContainerPatch{
+					{Container: corev1.Container{Name: "proxy"}},
+				},
+				Volumes:          []corev1.Volume{{Name: "data"}},
+				RuntimeClassName: &kata,
+			},
+		},
+	}
+	g.Expect(Conflicts(a, b)).Should(gomega.Equal([]string{"container proxy", "env LOG_LEVEL", "label mesh"}))
+	g.Expect(Conflicts(a, a)).Should(gomega.Equal([]string{"container proxy"}))
+
+	// only one of PodDecorations in the same group decorates a pod
+	a.Annotations = map[string]string{kuperatorv1alpha1.AnnotationPodDecorationGroup: "mesh"}
+	b.Annotations = map[string]string{kuperatorv1alpha1.AnnotationPodDecorationGroup: "mesh"}
+	g.Expect(Conflicts(a, b)).Should(gomega.BeEmpty())
+	b.Annotations = nil
+
+	// env of different primary containers
+	b.Spec.Template.PrimaryContainers[0].TargetPolicy = appsv1alpha1.InjectFirstContainer
+	b.Spec.Template.Containers = nil
+	b.Spec.Template.Metadata = nil
+	g.Expect(Conflicts(a, b)).Should(gomega.BeEmpty())
+}
+
+func TestSelectorsOverlap(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	g.Expect(SelectorsOverlap(nil, &metav1.LabelSelector{})).Should(gomega.BeTrue())
+	g.Expect(SelectorsOverlap(
+		&metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+		&metav1.LabelSelector{MatchLabels: map[string]string{"zone": "a"}},
+	)).Should(gomega.BeTrue())
+	g.Expect(SelectorsOverlap(
+		&metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+		&metav1.LabelSelector{MatchLabels: map[string]string{"app": "bar"}},
+	)).Should(gomega.BeFalse())
+	g.Expect(SelectorsOverlap(
+		&metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+		&metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"bar", "baz"}},
+		}},
+	)).Should(gomega.BeFalse())
+	g.Expect(SelectorsOverlap(
+		&metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+		&metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "app", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"bar"}},
+		}},
+	)).Should(gomega.BeTrue())
+}
@@ -24,6 +24,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
@@ -47,7 +48,9 @@ type namespacedPodDecorationManager struct {
 	namespace                string
 	latestPodDecorations     []*appsv1alpha1.PodDecoration
 	latestPodDecorationNames sets.String
-	revisions                map[string]*appsv1alpha1.PodDecoration
+	// clusterWideNames are names of cluster-wide PodDecorations, whose revisions are in ClusterNamespace
+	clusterWideNames sets.String
+	revisions        map[string]*appsv1alpha1.PodDecoration
 
 	mu sync.RWMutex
 }
@@ -58,6 +61,7 @@ func NewPodDecorationGetter(c client.Client, namespace string) (Getter, error) {
 		controller:               strategy.SharedStrategyController,
 		namespace:                namespace,
 		latestPodDecorationNames: sets.NewString(),
+		clusterWideNames:         sets.NewString(),
 		revisions:                map[string]*appsv1alpha1.PodDecoration{},
 	}
 	getter.getLatest()
@@ -69,7 +73,11 @@ func (n *namespacedPodDecorationManager) getLatest() {
 	for i := range n.latestPodDecorations {
 		pd := n.latestPodDecorations[i]
 		n.latestPodDecorationNames.Insert(pd.Name)
-		if pd.Status.UpdatedRevision != "" {
+		if pd.Namespace != n.namespace {
+			n.clusterWideNames.Insert(pd.Name)
+		}
+		// the pinned revision is not the current spec, and is got from ControllerRevision
+		if pd.Status.UpdatedRevision != "" && anno.GetPinnedRevision(pd) == "" {
 			n.revisions[pd.Status.UpdatedRevision] = pd
 		}
 	}
@@ -153,12 +161,16 @@ func (n *namespacedPodDecorationManager) getByRevision(ctx context.Context, rev
 	if pd, ok := n.revisions[rev]; ok {
 		return pd, nil
 	}
-	revision := &appsv1.ControllerRevision{}
-	if err := n.c.Get(ctx, types.NamespacedName{Namespace: n.namespace, Name: rev}, revision); err != nil {
-		if errors.IsNotFound(err) {
-			return nil, nil
+	revision, err := n.getRevision(ctx, n.namespace, rev)
+	if err == nil && revision == nil && n.clusterWideNames.Len() > 0 && n.namespace != anno.ClusterNamespace {
+		revision, err = n.getRevision(ctx, anno.ClusterNamespace, rev)
+		// only revisions of cluster-wide PodDecorations selecting the namespace are taken
+		if revision != nil && !n.clusterWideNames.Has(ownerName(revision)) {
+			revision = nil
 		}
-		return nil, fmt.Errorf("fail to get PodDecoration ControllerRevision %s/%s: %w", n.namespace, rev, err)
+	}
+	if err != nil || revision == nil {
+		return nil, err
 	}
 	pd, err := anno.GetPodDecorationFromRevision(revision)
 	if err != nil {
@@ -168,6 +180,24 @@ func (n *namespacedPodDecorationManager) getByRevision(ctx context.Context, rev
 	return pd, nil
 }
 
+func (n *namespacedPodDecorationManager) getRevision(ctx context.Context, namespace, rev string) (*appsv1.ControllerRevision, error) {
+	revision := &appsv1.ControllerRevision{}
+	if err := n.c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: rev}, revision); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("fail to get PodDecoration ControllerRevision %s/%s: %w", namespace, rev, err)
+	}
+	return revision, nil
+}
+
+func ownerName(revision *appsv1.ControllerRevision) string {
+	if ownerRef := metav1.GetControllerOf(revision); ownerRef != nil {
+		return ownerRef.Name
+	}
+	return ""
+}
+
 func BuildInfo(revisionMap map[string]*appsv1alpha1.PodDecoration) (info string) {
 	for k, v := range revisionMap {
 		if info == "" {
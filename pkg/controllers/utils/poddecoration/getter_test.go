@@ -39,6 +39,7 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
 	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/anno"
 	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/revision"
 	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/strategy"
@@ -198,6 +199,69 @@ var _ = Describe("Test PodDecoration getter", func() {
 		Expect(err).Should(BeNil())
 		Expect(len(pds)).Should(Equal(2))
 	})
+
+	It("test getter with pinned revision", func() {
+		testcase := "test-getter-pinned"
+		Expect(createNamespace(testcase)).Should(BeNil())
+		podDecoration := &appsv1alpha1.PodDecoration{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "foo",
+			},
+			Spec: appsv1alpha1.PodDecorationSpec{
+				HistoryLimit: 5,
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				Template: appsv1alpha1.PodDecorationPodTemplate{
+					Containers: []*appsv1alpha1.ContainerPatch{
+						{
+							InjectPolicy: appsv1alpha1.AfterPrimaryContainer,
+							Container: corev1.Container{
+								Name:  "sidecar",
+								Image: "nginx:v1",
+							},
+						},
+					},
+				},
+			},
+		}
+		Expect(c.Create(ctx, podDecoration)).ShouldNot(HaveOccurred())
+		_, revisionV1, _, _, _, err := revisionMgr.ConstructRevisions(ctx, podDecoration)
+		Expect(err).ShouldNot(HaveOccurred())
+		podDecoration.Spec.Template.Containers[0].Image = "nginx:v2"
+		Expect(c.Update(ctx, podDecoration)).Should(BeNil())
+		_, revisionV2, _, _, _, err := revisionMgr.ConstructRevisions(ctx, podDecoration)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(revisionV2.Name).ShouldNot(Equal(revisionV1.Name))
+
+		// roll back to v1 without changing spec
+		podDecoration.Annotations = map[string]string{kuperatorv1alpha1.AnnotationPodDecorationPinnedRevision: revisionV1.Name}
+		podDecoration.Status.CurrentRevision = revisionV2.Name
+		podDecoration.Status.UpdatedRevision = revisionV1.Name
+		inUsed, err := (&revision.RevisionOwnerAdapter{}).GetInUsedRevisions(podDecoration)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(inUsed.Has(revisionV1.Name)).Should(BeTrue())
+		Expect(strategy.SharedStrategyController.UpdateSelectedPods(ctx, podDecoration, nil)).Should(BeNil())
+		strategy.SharedStrategyController.Synced()
+		getter, _ := NewPodDecorationGetter(c, testcase)
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testcase,
+				Name:      "pod",
+				Labels: map[string]string{
+					"app": "foo",
+				},
+			},
+		}
+		pds, err := getter.GetEffective(ctx, pod)
+		Expect(err).Should(BeNil())
+		Expect(len(pds)).Should(Equal(1))
+		Expect(pds[revisionV1.Name]).ShouldNot(BeNil())
+		Expect(pds[revisionV1.Name].Spec.Template.Containers[0].Image).Should(Equal("nginx:v1"))
+	})
 })
 
 var _ = BeforeSuite(func() {
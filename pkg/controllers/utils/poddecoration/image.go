@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poddecoration
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/anno"
+)
+
+// SidecarImageChanges compares the PodDecorations on pod with the effective ones. If they only differ in images of
+// sidecar containers, it returns the updated images keyed by container name and true, so that the pod is able to be
+// updated in-place by setting these images. Otherwise, it returns false.
+func SidecarImageChanges(pod *corev1.Pod, current, updated map[string]*appsv1alpha1.PodDecoration) (map[string]string, bool) {
+	if len(current) != len(updated) {
+		return nil, false
+	}
+	currentByName := map[string]*appsv1alpha1.PodDecoration{}
+	for _, pd := range current {
+		currentByName[pd.Name] = pd
+	}
+
+	images := map[string]string{}
+	for _, updatedPD := range updated {
+		currentPD, ok := currentByName[updatedPD.Name]
+		if !ok {
+			return nil, false
+		}
+		changes, ok := sidecarImageChanges(pod, currentPD, updatedPD)
+		if !ok {
+			return nil, false
+		}
+		for name, image := range changes {
+			images[name] = image
+		}
+	}
+	return images, true
+}
+
+func sidecarImageChanges(pod *corev1.Pod, current, updated *appsv1alpha1.PodDecoration) (map[string]string, bool) {
+	if !sameAnnoPatches(current, updated) {
+		return nil, false
+	}
+	currentTemplate, err := resolveTemplate(pod, &current.Spec.Template)
+	if err != nil {
+		return nil, false
+	}
+	updatedTemplate, err := resolveTemplate(pod, &updated.Spec.Template)
+	if err != nil {
+		return nil, false
+	}
+	if len(currentTemplate.Containers) != len(updatedTemplate.Containers) {
+		return nil, false
+	}
+
+	images := map[string]string{}
+	// sync images of sidecar containers, and the rest of templates are expected to be equal
+	currentTemplate = currentTemplate.DeepCopy()
+	for i := range currentTemplate.Containers {
+		currentContainer, updatedContainer := currentTemplate.Containers[i], updatedTemplate.Containers[i]
+		if currentContainer.Name != updatedContainer.Name {
+			return nil, false
+		}
+		if currentContainer.Image != updatedContainer.Image {
+			images[updatedContainer.Name] = updatedContainer.Image
+			currentContainer.Image = updatedContainer.Image
+		}
+	}
+	if !equality.Semantic.DeepEqual(currentTemplate, updatedTemplate) {
+		return nil, false
+	}
+	return images, true
+}
+
+// sameAnnoPatches checks whether the patches configured in annotations of PodDecorations are equal
+func sameAnnoPatches(current, updated *appsv1alpha1.PodDecoration) bool {
+	currentInitPatches, err := anno.GetInitContainerPatches(current)
+	if err != nil {
+		return false
+	}
+	updatedInitPatches, err := anno.GetInitContainerPatches(updated)
+	if err != nil {
+		return false
+	}
+	currentPodPatches, err := anno.GetPodPatches(current)
+	if err != nil {
+		return false
+	}
+	updatedPodPatches, err := anno.GetPodPatches(updated)
+	if err != nil {
+		return false
+	}
+	return equality.Semantic.DeepEqual(currentInitPatches, updatedInitPatches) &&
+		equality.Semantic.DeepEqual(currentPodPatches, updatedPodPatches)
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poddecoration
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+)
+
+func TestSidecarImageChanges(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo-0", Namespace: "default"}}
+	newPD := func(image string, env ...corev1.EnvVar) *appsv1alpha1.PodDecoration {
+		return &appsv1alpha1.PodDecoration{
+			ObjectMeta: metav1.ObjectMeta{Name: "mesh", Namespace: "default"},
+			Spec: appsv1alpha1.PodDecorationSpec{
+				Template: appsv1alpha1.PodDecorationPodTemplate{
+					Containers: []*appsv1alpha1.ContainerPatch{
+						{Container: corev1.Container{Name: "proxy", Image: image, Env: env}},
+					},
+				},
+			},
+		}
+	}
+	current := map[string]*appsv1alpha1.PodDecoration{"mesh-1": newPD("proxy:v1")}
+
+	images, ok := SidecarImageChanges(pod, current, map[string]*appsv1alpha1.PodDecoration{"mesh-2": newPD("proxy:v2")})
+	g.Expect(ok).Should(gomega.BeTrue())
+	g.Expect(images).Should(gomega.Equal(map[string]string{"proxy": "proxy:v2"}))
+	// the template of current revision is not changed
+	g.Expect(current["mesh-1"].Spec.Template.Containers[0].Image).Should(gomega.Equal("proxy:v1"))
+
+	// changes other than images are not supported
+	_, ok = SidecarImageChanges(pod, current, map[string]*appsv1alpha1.PodDecoration{"mesh-2": newPD("proxy:v2", corev1.EnvVar{Name: "A", Value: "a"})})
+	g.Expect(ok).Should(gomega.BeFalse())
+
+	// added or removed PodDecorations are not supported
+	other := newPD("proxy:v1")
+	other.Name = "other"
+	_, ok = SidecarImageChanges(pod, current, map[string]*appsv1alpha1.PodDecoration{"other-1": other})
+	g.Expect(ok).Should(gomega.BeFalse())
+	_, ok = SidecarImageChanges(pod, current, map[string]*appsv1alpha1.PodDecoration{})
+	g.Expect(ok).Should(gomega.BeFalse())
+
+	// images with variables are compared after they are resolved
+	images, ok = SidecarImageChanges(pod, current, map[string]*appsv1alpha1.PodDecoration{"mesh-2": newPD("proxy:$(POD_NAMESPACE)")})
+	g.Expect(ok).Should(gomega.BeTrue())
+	g.Expect(images).Should(gomega.Equal(map[string]string{"proxy": "proxy:default"}))
+}
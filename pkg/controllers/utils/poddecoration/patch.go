@@ -17,23 +17,54 @@ limitations under the License.
 package poddecoration
 
 import (
+	"fmt"
 	"sort"
 
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
 
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
 	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/anno"
 	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/patch"
 	"kusionstack.io/kuperator/pkg/utils"
 )
 
+// PatchPodDecoration patches pod with the template, in which variables are replaced with values of the pod.
+// Configs in annotations of PodDecoration are not applied.
 func PatchPodDecoration(pod *corev1.Pod, template *appsv1alpha1.PodDecorationPodTemplate) (err error) {
+	return patchPodDecoration(pod, template, nil)
+}
+
+// patchDecoration patches pod with the template and configs in annotations of the PodDecoration
+func patchDecoration(pod *corev1.Pod, pd *appsv1alpha1.PodDecoration) error {
+	initContainerPatches, err := anno.GetInitContainerPatches(pd)
+	if err != nil {
+		return err
+	}
+	podPatches, err := anno.GetPodPatches(pd)
+	if err != nil {
+		return err
+	}
+	if err = patchPodDecoration(pod, &pd.Spec.Template, initContainerPatches); err != nil {
+		return err
+	}
+	for i := range podPatches {
+		podPatches[i].Patch.Raw = resolveVariables(podPatches[i].Patch.Raw, pod)
+	}
+	// patches are applied after the template
+	return patch.PatchPod(pod, podPatches)
+}
+
+func patchPodDecoration(pod *corev1.Pod, template *appsv1alpha1.PodDecorationPodTemplate, initContainerPatches map[string]*kuperatorv1alpha1.InitContainerPatch) (err error) {
+	if template, err = resolveTemplate(pod, template); err != nil {
+		return err
+	}
 	if len(template.Metadata) > 0 {
 		err = patch.PatchMetadata(&pod.ObjectMeta, template.Metadata)
 	}
 	if len(template.InitContainers) > 0 {
-		patch.AddInitContainers(pod, template.InitContainers)
+		patch.AddInitContainers(pod, template.InitContainers, initContainerPatches)
 	}
 
 	if len(template.PrimaryContainers) > 0 {
@@ -55,11 +86,20 @@ func PatchPodDecoration(pod *corev1.Pod, template *appsv1alpha1.PodDecorationPod
 	if template.Tolerations != nil {
 		pod.Spec.Tolerations = patch.MergeWithOverwriteTolerations(pod.Spec.Tolerations, template.Tolerations)
 	}
+
+	if template.RuntimeClassName != nil {
+		err = utils.Join(err, patch.PatchRuntimeClassName(pod, *template.RuntimeClassName))
+	}
 	return
 }
 
+// addPodDecorationOwnerRef adds PodDecorations in the namespace of pod to owners. Cluster-wide PodDecorations
+// are not owners, because owner references across namespaces are invalid.
 func addPodDecorationOwnerRef(pod *corev1.Pod, sortedPds []*appsv1alpha1.PodDecoration) {
 	for _, pd := range sortedPds {
+		if pd.Namespace != "" && pod.Namespace != "" && pd.Namespace != pod.Namespace {
+			continue
+		}
 		pod.OwnerReferences = append(pod.OwnerReferences, v1.OwnerReference{
 			APIVersion: pd.APIVersion,
 			Kind:       pd.Kind,
@@ -79,8 +119,8 @@ func PatchListOfDecorations(pod *corev1.Pod, podDecorations map[string]*appsv1al
 	}
 	sort.Sort(PodDecorations(pds))
 	for i := range pds {
-		if patchErr := PatchPodDecoration(pod, &pds[i].Spec.Template); patchErr != nil {
-			err = utils.Join(err, patchErr)
+		if patchErr := patchDecoration(pod, pds[i]); patchErr != nil {
+			err = utils.Join(err, fmt.Errorf("fail to patch PodDecoration %s: %w", pds[i].Name, patchErr))
 		}
 	}
 	anno.SetDecorationInfo(pod, podDecorations)
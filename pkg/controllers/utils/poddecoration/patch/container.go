@@ -17,24 +17,75 @@ limitations under the License.
 package patch
 
 import (
+	"fmt"
+	"strings"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
 )
 
-func AddInitContainers(pod *corev1.Pod, initContainers []*corev1.Container) {
+// AddInitContainers injects init containers which are not on pod yet, in the positions of their patches.
+// Native sidecars are recorded in pod annotation, so that the pod webhook sets their restartPolicy.
+func AddInitContainers(pod *corev1.Pod, initContainers []*corev1.Container, patches map[string]*kuperatorv1alpha1.InitContainerPatch) {
 	exists := sets.NewString()
 	for _, container := range pod.Spec.InitContainers {
 		exists.Insert(container.Name)
 	}
 
+	var beforeContainers, afterContainers []corev1.Container
+	sidecars := sets.NewString()
 	for i, container := range initContainers {
 		if exists.Has(container.Name) {
 			continue
 		}
-		pod.Spec.InitContainers = append(pod.Spec.InitContainers, *initContainers[i])
 		exists.Insert(container.Name)
+		patch := patches[container.Name]
+		if patch.IsNativeSidecar() {
+			sidecars.Insert(container.Name)
+		}
+		if patch != nil && patch.InjectPolicy == kuperatorv1alpha1.BeforeInitContainers {
+			beforeContainers = append(beforeContainers, *initContainers[i])
+		} else {
+			afterContainers = append(afterContainers, *initContainers[i])
+		}
+	}
+	if len(beforeContainers) > 0 {
+		pod.Spec.InitContainers = append(beforeContainers, pod.Spec.InitContainers...)
+	}
+	if len(afterContainers) > 0 {
+		pod.Spec.InitContainers = append(pod.Spec.InitContainers, afterContainers...)
+	}
+	if sidecars.Len() > 0 {
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		sidecars.Insert(GetNativeSidecars(pod).UnsortedList()...)
+		pod.Annotations[kuperatorv1alpha1.AnnotationPodNativeSidecars] = strings.Join(sidecars.List(), ",")
+	}
+}
+
+// GetNativeSidecars returns names of init containers injected as native sidecars on pod
+func GetNativeSidecars(pod *corev1.Pod) sets.String {
+	sidecars := sets.NewString()
+	val := pod.Annotations[kuperatorv1alpha1.AnnotationPodNativeSidecars]
+	for _, name := range strings.Split(val, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			sidecars.Insert(name)
+		}
+	}
+	return sidecars
+}
+
+// PatchRuntimeClassName sets runtimeClassName of pod, and fails if pod has a different one
+func PatchRuntimeClassName(pod *corev1.Pod, runtimeClassName string) error {
+	if pod.Spec.RuntimeClassName != nil && *pod.Spec.RuntimeClassName != runtimeClassName {
+		return fmt.Errorf("runtimeClassName %s conflicts with %s on pod", runtimeClassName, *pod.Spec.RuntimeClassName)
 	}
+	pod.Spec.RuntimeClassName = &runtimeClassName
+	return nil
 }
 
 func PrimaryContainerPatch(pod *corev1.Pod, patches []*appsv1alpha1.PrimaryContainerPatch) {
@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+// PatchPod applies patches on pod in order, pod is not changed if any patch fails. Fields unknown to corev1.Pod
+// of this version are dropped, which are rejected by LostFields in admission.
+func PatchPod(pod *corev1.Pod, patches []kuperatorv1alpha1.PodPatch) error {
+	if len(patches) == 0 {
+		return nil
+	}
+	podBytes, err := json.Marshal(pod)
+	if err != nil {
+		return err
+	}
+	for i, patch := range patches {
+		if podBytes, err = ApplyPodPatch(podBytes, &patch); err != nil {
+			return fmt.Errorf("fail to apply patch %d: %w", i, err)
+		}
+	}
+	patched := &corev1.Pod{}
+	if err = json.Unmarshal(podBytes, patched); err != nil {
+		return fmt.Errorf("fail to unmarshal patched pod: %w", err)
+	}
+	*pod = *patched
+	return nil
+}
+
+// ApplyPodPatch applies the patch on JSON of pod
+func ApplyPodPatch(podBytes []byte, patch *kuperatorv1alpha1.PodPatch) ([]byte, error) {
+	switch patch.Type {
+	case kuperatorv1alpha1.JSONPatchType:
+		ops, err := jsonpatch.DecodePatch(patch.Patch.Raw)
+		if err != nil {
+			return nil, err
+		}
+		return ops.Apply(podBytes)
+	case kuperatorv1alpha1.StrategicMergePatchType:
+		return strategicpatch.StrategicMergePatch(podBytes, patch.Patch.Raw, &corev1.Pod{})
+	default:
+		return nil, fmt.Errorf("unsupported patch type %q", patch.Type)
+	}
+}
+
+// LostFields returns paths of fields in the pod json, which are lost after unmarshalling to corev1.Pod and
+// marshalling back, e.g. fields unknown to corev1.Pod of this version. Fields with empty values are ignored.
+func LostFields(podBytes []byte) ([]string, error) {
+	var raw interface{}
+	if err := json.Unmarshal(podBytes, &raw); err != nil {
+		return nil, err
+	}
+	pod := &corev1.Pod{}
+	if err := json.Unmarshal(podBytes, pod); err != nil {
+		return nil, err
+	}
+	typedBytes, err := json.Marshal(pod)
+	if err != nil {
+		return nil, err
+	}
+	var typed interface{}
+	if err = json.Unmarshal(typedBytes, &typed); err != nil {
+		return nil, err
+	}
+	var lost []string
+	lostFields(raw, typed, "", &lost)
+	sort.Strings(lost)
+	return lost, nil
+}
+
+func lostFields(raw, typed interface{}, path string, lost *[]string) {
+	switch rawVal := raw.(type) {
+	case map[string]interface{}:
+		typedVal, _ := typed.(map[string]interface{})
+		for key, val := range rawVal {
+			if isEmptyValue(val) {
+				continue
+			}
+			fieldPath := path + "/" + key
+			if typedField, ok := typedVal[key]; ok {
+				lostFields(val, typedField, fieldPath, lost)
+			} else {
+				*lost = append(*lost, fieldPath)
+			}
+		}
+	case []interface{}:
+		typedVal, _ := typed.([]interface{})
+		for i, val := range rawVal {
+			if i < len(typedVal) {
+				lostFields(val, typedVal[i], path+"/"+strconv.Itoa(i), lost)
+			}
+		}
+	}
+}
+
+func isEmptyValue(val interface{}) bool {
+	if val == nil {
+		return true
+	}
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Float64:
+		return v.Float() == 0
+	}
+	return false
+}
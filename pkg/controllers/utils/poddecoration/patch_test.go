@@ -26,7 +26,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
 
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
 	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/anno"
+	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/patch"
 )
 
 var _ = Describe("PodDecoration controller", func() {
@@ -120,6 +122,87 @@ var _ = Describe("PodDecoration controller", func() {
 		Expect(pod.Spec.InitContainers[0].Image).Should(Equal("nginx:v1"))
 	})
 
+	It("patch native sidecars", func() {
+		pod := &v1.Pod{
+			Spec: v1.PodSpec{
+				InitContainers: []v1.Container{{Name: "init"}},
+			},
+		}
+		pd := &appsv1alpha1.PodDecoration{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "mesh",
+				Annotations: map[string]string{
+					kuperatorv1alpha1.AnnotationPodDecorationInitContainers: `{"proxy": {"injectPolicy": "BeforeInitContainers", "restartPolicy": "Always"}}`,
+				},
+			},
+			Spec: appsv1alpha1.PodDecorationSpec{
+				Template: appsv1alpha1.PodDecorationPodTemplate{
+					InitContainers: []*v1.Container{
+						{Name: "proxy", Image: "proxy:v1"},
+						{Name: "setup", Image: "setup:v1"},
+					},
+				},
+			},
+		}
+		Expect(PatchListOfDecorations(pod, map[string]*appsv1alpha1.PodDecoration{"mesh-1": pd})).Should(BeNil())
+		Expect(pod.Spec.InitContainers).Should(HaveLen(3))
+		Expect(pod.Spec.InitContainers[0].Name).Should(Equal("proxy"))
+		Expect(pod.Spec.InitContainers[1].Name).Should(Equal("init"))
+		Expect(pod.Spec.InitContainers[2].Name).Should(Equal("setup"))
+		Expect(patch.GetNativeSidecars(pod).List()).Should(Equal([]string{"proxy"}))
+	})
+
+	It("patch with JSON and strategic merge patches", func() {
+		pod := &v1.Pod{
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{{Name: "main"}},
+			},
+		}
+		pd := &appsv1alpha1.PodDecoration{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "security",
+				Annotations: map[string]string{
+					kuperatorv1alpha1.AnnotationPodDecorationPatches: `[
+						{"type": "json", "patch": [{"op": "add", "path": "/spec/priorityClassName", "value": "high"}]},
+						{"type": "strategic", "patch": {"spec": {"securityContext": {"runAsNonRoot": true}, "containers": [{"name": "main", "securityContext": {"privileged": false}}]}}}
+					]`,
+				},
+			},
+			Spec: appsv1alpha1.PodDecorationSpec{
+				Template: appsv1alpha1.PodDecorationPodTemplate{
+					Containers: []*appsv1alpha1.ContainerPatch{
+						{Container: v1.Container{Name: "sidecar"}},
+					},
+				},
+			},
+		}
+		Expect(PatchListOfDecorations(pod, map[string]*appsv1alpha1.PodDecoration{"security-1": pd})).Should(BeNil())
+		Expect(pod.Spec.PriorityClassName).Should(Equal("high"))
+		Expect(*pod.Spec.SecurityContext.RunAsNonRoot).Should(BeTrue())
+		Expect(pod.Spec.Containers).Should(HaveLen(2))
+		Expect(*pod.Spec.Containers[0].SecurityContext.Privileged).Should(BeFalse())
+		Expect(pod.Labels).Should(HaveKey(appsv1alpha1.PodDecorationLabelPrefix + "security"))
+
+		// pod is not changed if any patch fails
+		pod = &v1.Pod{}
+		pd.Annotations[kuperatorv1alpha1.AnnotationPodDecorationPatches] = `[
+			{"type": "json", "patch": [{"op": "add", "path": "/spec/priorityClassName", "value": "high"}]},
+			{"type": "json", "patch": [{"op": "remove", "path": "/spec/dnsConfig"}]}
+		]`
+		Expect(PatchListOfDecorations(pod, map[string]*appsv1alpha1.PodDecoration{"security-1": pd})).Should(HaveOccurred())
+		Expect(pod.Spec.PriorityClassName).Should(BeEmpty())
+	})
+
+	It("patch runtimeClassName", func() {
+		kata, runc := "kata", "runc"
+		pod := &v1.Pod{}
+		Expect(PatchPodDecoration(pod, &appsv1alpha1.PodDecorationPodTemplate{RuntimeClassName: &kata})).Should(BeNil())
+		Expect(*pod.Spec.RuntimeClassName).Should(Equal(kata))
+		Expect(PatchPodDecoration(pod, &appsv1alpha1.PodDecorationPodTemplate{RuntimeClassName: &kata})).Should(BeNil())
+		Expect(PatchPodDecoration(pod, &appsv1alpha1.PodDecorationPodTemplate{RuntimeClassName: &runc})).Should(HaveOccurred())
+		Expect(*pod.Spec.RuntimeClassName).Should(Equal(kata))
+	})
+
 	It("patch Containers", func() {
 		pod := &v1.Pod{
 			Spec: v1.PodSpec{
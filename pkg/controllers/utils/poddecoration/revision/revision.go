@@ -23,6 +23,8 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/sets"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
 )
 
 func getPodDecorationPatch(pd *appsv1alpha1.PodDecoration) ([]byte, error) {
@@ -46,6 +48,16 @@ func getPodDecorationPatch(pd *appsv1alpha1.PodDecoration) ([]byte, error) {
 	specCopy["template"] = template
 	specCopy["weight"] = weight
 	objCopy["spec"] = specCopy
+
+	annotations := make(map[string]interface{})
+	for _, key := range kuperatorv1alpha1.PodDecorationRevisionAnnotations {
+		if val, ok := pd.Annotations[key]; ok {
+			annotations[key] = val
+		}
+	}
+	if len(annotations) > 0 {
+		objCopy["metadata"] = map[string]interface{}{"annotations": annotations}
+	}
 	patch, err := json.Marshal(objCopy)
 	return patch, err
 }
@@ -85,6 +97,12 @@ func (roa *RevisionOwnerAdapter) GetCurrentRevision(obj metav1.Object) string {
 	return pd.Status.CurrentRevision
 }
 
-func (roa *RevisionOwnerAdapter) GetInUsedRevisions(_ metav1.Object) (sets.String, error) {
-	return sets.NewString(), nil
+// GetInUsedRevisions returns the pinned revision, which is kept from history truncation
+func (roa *RevisionOwnerAdapter) GetInUsedRevisions(obj metav1.Object) (sets.String, error) {
+	pd, _ := obj.(*appsv1alpha1.PodDecoration)
+	res := sets.NewString()
+	if pinned := pd.Annotations[kuperatorv1alpha1.AnnotationPodDecorationPinnedRevision]; pinned != "" {
+		res.Insert(pinned)
+	}
+	return res, nil
 }
@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategy
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+	utilspoddecoration "kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/anno"
+)
+
+// AdvanceCanary returns the progress of canary steps of the PodDecoration with the status of pods, and the
+// duration after which the progress should be checked again, zero if not needed. It returns nil if the
+// PodDecoration has no canary steps.
+func AdvanceCanary(pd *appsv1alpha1.PodDecoration, status *appsv1alpha1.PodDecorationStatus, now metav1.Time) (*kuperatorv1alpha1.PodDecorationCanaryStatus, time.Duration, error) {
+	canary, err := utilspoddecoration.GetCanary(pd)
+	if err != nil || canary == nil || len(canary.Steps) == 0 {
+		return nil, 0, err
+	}
+	progress := utilspoddecoration.GetCanaryStatus(pd)
+	if progress == nil || progress.Revision != status.UpdatedRevision || int(progress.Step) >= len(canary.Steps) {
+		progress = &kuperatorv1alpha1.PodDecorationCanaryStatus{
+			Revision:      status.UpdatedRevision,
+			StepStartTime: now,
+			Condition:     kuperatorv1alpha1.CanaryProgressing,
+		}
+	}
+	if progress.Condition == kuperatorv1alpha1.CanaryCompleted || progress.Condition == kuperatorv1alpha1.CanaryHalted {
+		return progress, 0, nil
+	}
+
+	step := canary.Steps[progress.Step]
+	target := percentOf(step.Percent, int(status.MatchedPods))
+	ready := int(status.UpdatedPods) >= target
+	if step.WaitForAvailable {
+		ready = ready && int(status.UpdatedAvailablePods) >= target
+	}
+	if !ready {
+		deadline := time.Duration(kuperatorv1alpha1.DefaultCanaryProgressDeadlineSeconds) * time.Second
+		if canary.ProgressDeadlineSeconds != nil {
+			deadline = time.Duration(*canary.ProgressDeadlineSeconds) * time.Second
+		}
+		if elapsed := now.Sub(progress.StepStartTime.Time); elapsed < deadline {
+			progress.Condition = kuperatorv1alpha1.CanaryProgressing
+			progress.Message = fmt.Sprintf("step %d: %d/%d pods updated, %d available", progress.Step, status.UpdatedPods, target, status.UpdatedAvailablePods)
+			return progress, deadline - elapsed, nil
+		}
+		progress.Condition = kuperatorv1alpha1.CanaryHalted
+		progress.Message = fmt.Sprintf("step %d: %d/%d pods updated, %d available, exceeded progress deadline %s",
+			progress.Step, status.UpdatedPods, target, status.UpdatedAvailablePods, deadline)
+		return progress, 0, nil
+	}
+
+	if step.PauseSeconds > 0 {
+		if progress.StepReadyTime == nil {
+			progress.StepReadyTime = now.DeepCopy()
+		}
+		pause := time.Duration(step.PauseSeconds) * time.Second
+		if elapsed := now.Sub(progress.StepReadyTime.Time); elapsed < pause {
+			progress.Condition = kuperatorv1alpha1.CanaryPaused
+			progress.Message = fmt.Sprintf("step %d: paused until %s", progress.Step, progress.StepReadyTime.Add(pause).Format(time.RFC3339))
+			return progress, pause - elapsed, nil
+		}
+	}
+
+	if int(progress.Step) == len(canary.Steps)-1 {
+		progress.Condition = kuperatorv1alpha1.CanaryCompleted
+		progress.Message = ""
+		progress.StepReadyTime = nil
+		return progress, 0, nil
+	}
+	progress.Step++
+	progress.StepStartTime = now
+	progress.StepReadyTime = nil
+	progress.Condition = kuperatorv1alpha1.CanaryProgressing
+	progress.Message = fmt.Sprintf("step %d started", progress.Step)
+	// check again after pods are updated by the partition of the new step
+	return progress, time.Second, nil
+}
+
+// canaryPercent returns the percent of pods to update by the current canary step, false if the PodDecoration has
+// no canary steps. After steps are completed, pods are still updated by the last step, instead of falling back to
+// spec.updateStrategy.
+func canaryPercent(pd *appsv1alpha1.PodDecoration) (int32, bool) {
+	canary, err := utilspoddecoration.GetCanary(pd)
+	if err != nil || canary == nil || len(canary.Steps) == 0 {
+		return 0, false
+	}
+	progress := utilspoddecoration.GetCanaryStatus(pd)
+	if progress == nil || progress.Revision != pd.Status.UpdatedRevision || int(progress.Step) >= len(canary.Steps) {
+		return canary.Steps[0].Percent, true
+	}
+	if progress.Condition == kuperatorv1alpha1.CanaryCompleted {
+		return canary.Steps[len(canary.Steps)-1].Percent, true
+	}
+	return canary.Steps[progress.Step].Percent, true
+}
+
+// percentOf returns the number of pods in percent, rounded up
+func percentOf(percent int32, pods int) int {
+	return (int(percent)*pods + 99) / 100
+}
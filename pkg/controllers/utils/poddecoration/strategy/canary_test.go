@@ -0,0 +1,157 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategy
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+func TestAdvanceCanary(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	pd := &appsv1alpha1.PodDecoration{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				kuperatorv1alpha1.AnnotationPodDecorationCanary: `{"steps": [{"percent": 10, "waitForAvailable": true, "pauseSeconds": 60}, {"percent": 100}], "progressDeadlineSeconds": 300}`,
+			},
+		},
+	}
+	status := &appsv1alpha1.PodDecorationStatus{UpdatedRevision: "foo-2", MatchedPods: 20}
+	now := metav1.NewTime(time.Unix(1000, 0))
+	advance := func(after time.Duration) (*kuperatorv1alpha1.PodDecorationCanaryStatus, time.Duration) {
+		progress, requeueAfter, err := AdvanceCanary(pd, status, metav1.NewTime(now.Add(after)))
+		g.Expect(err).ShouldNot(gomega.HaveOccurred())
+		val, _ := json.Marshal(progress)
+		pd.Annotations[kuperatorv1alpha1.AnnotationPodDecorationCanaryStatus] = string(val)
+		return progress, requeueAfter
+	}
+
+	// wait for 2 pods updated and available
+	progress, requeueAfter := advance(0)
+	g.Expect(progress.Step).Should(gomega.BeEquivalentTo(0))
+	g.Expect(progress.Condition).Should(gomega.Equal(kuperatorv1alpha1.CanaryProgressing))
+	g.Expect(requeueAfter).Should(gomega.Equal(300 * time.Second))
+	status.UpdatedPods = 2
+	status.UpdatedAvailablePods = 1
+	progress, _ = advance(10 * time.Second)
+	g.Expect(progress.Condition).Should(gomega.Equal(kuperatorv1alpha1.CanaryProgressing))
+
+	// paused after pods are available
+	status.UpdatedAvailablePods = 2
+	progress, requeueAfter = advance(20 * time.Second)
+	g.Expect(progress.Condition).Should(gomega.Equal(kuperatorv1alpha1.CanaryPaused))
+	g.Expect(requeueAfter).Should(gomega.Equal(60 * time.Second))
+
+	// next step after the pause
+	progress, _ = advance(80 * time.Second)
+	g.Expect(progress.Step).Should(gomega.BeEquivalentTo(1))
+	g.Expect(progress.Condition).Should(gomega.Equal(kuperatorv1alpha1.CanaryProgressing))
+	g.Expect(progress.StepStartTime.Time).Should(gomega.Equal(now.Add(80 * time.Second)))
+
+	// halted if pods are not updated within the deadline
+	progress, _ = advance(400 * time.Second)
+	g.Expect(progress.Condition).Should(gomega.Equal(kuperatorv1alpha1.CanaryHalted))
+	status.UpdatedPods = 20
+	progress, _ = advance(500 * time.Second)
+	g.Expect(progress.Condition).Should(gomega.Equal(kuperatorv1alpha1.CanaryHalted))
+
+	// restart from the first step if status is removed
+	delete(pd.Annotations, kuperatorv1alpha1.AnnotationPodDecorationCanaryStatus)
+	progress, _ = advance(600 * time.Second)
+	g.Expect(progress.Step).Should(gomega.BeEquivalentTo(0))
+	g.Expect(progress.Condition).Should(gomega.Equal(kuperatorv1alpha1.CanaryPaused))
+	progress, _ = advance(700 * time.Second)
+	g.Expect(progress.Step).Should(gomega.BeEquivalentTo(1))
+	progress, _ = advance(701 * time.Second)
+	g.Expect(progress.Condition).Should(gomega.Equal(kuperatorv1alpha1.CanaryCompleted))
+
+	// start over for a new revision
+	status.UpdatedRevision = "foo-3"
+	status.UpdatedPods, status.UpdatedAvailablePods = 0, 0
+	progress, _ = advance(800 * time.Second)
+	g.Expect(progress.Revision).Should(gomega.Equal("foo-3"))
+	g.Expect(progress.Step).Should(gomega.BeEquivalentTo(0))
+}
+
+func TestCanaryPartition(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	pd := &appsv1alpha1.PodDecoration{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "foo",
+			Annotations: map[string]string{
+				kuperatorv1alpha1.AnnotationPodDecorationWebhookInjection: "true",
+				kuperatorv1alpha1.AnnotationPodDecorationCanary:           `{"steps": [{"percent": 30}, {"percent": 100}]}`,
+			},
+		},
+		Spec: appsv1alpha1.PodDecorationSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+		},
+		Status: appsv1alpha1.PodDecorationStatus{CurrentRevision: "foo-1", UpdatedRevision: "foo-2"},
+	}
+	var pods []*corev1.Pod
+	for i := 0; i < 5; i++ {
+		name := "pod-" + strconv.Itoa(i)
+		pods = append(pods, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      name,
+				UID:       types.UID(name),
+				Labels:    map[string]string{"app": "foo", kuperatorv1alpha1.LabelPodWebhookInjection: "true"},
+			},
+		})
+	}
+	updated := func(mgr *strategyManager) int {
+		var count int
+		for _, pod := range pods {
+			if updatedRevisions, _ := mgr.EffectivePodRevisions(pod); updatedRevisions["foo"] == "foo-2" {
+				count++
+			}
+		}
+		return count
+	}
+	mgr := &strategyManager{managers: map[string]map[string]*podDecorationManager{}, clusterManagers: map[string]*podDecorationManager{}}
+	// the first step before progress is recorded
+	g.Expect(mgr.UpdateSelectedPods(context.TODO(), pd, pods)).Should(gomega.BeNil())
+	g.Expect(updated(mgr)).Should(gomega.Equal(2))
+
+	pd.Annotations[kuperatorv1alpha1.AnnotationPodDecorationCanaryStatus] = `{"revision": "foo-2", "step": 1, "condition": "Progressing"}`
+	g.Expect(mgr.UpdateSelectedPods(context.TODO(), pd, pods)).Should(gomega.BeNil())
+	g.Expect(updated(mgr)).Should(gomega.Equal(5))
+
+	// halted at the step
+	pd.Annotations[kuperatorv1alpha1.AnnotationPodDecorationCanaryStatus] = `{"revision": "foo-2", "step": 0, "condition": "Halted"}`
+	g.Expect(mgr.UpdateSelectedPods(context.TODO(), pd, pods)).Should(gomega.BeNil())
+	g.Expect(updated(mgr)).Should(gomega.Equal(2))
+
+	// the partition of the last step is kept after completed, instead of spec.updateStrategy
+	pd.Annotations[kuperatorv1alpha1.AnnotationPodDecorationCanaryStatus] = `{"revision": "foo-2", "step": 1, "condition": "Completed"}`
+	pd.Spec.UpdateStrategy.RollingUpdate = &appsv1alpha1.PodDecorationRollingUpdate{Partition: int32Pointer(4)}
+	g.Expect(mgr.UpdateSelectedPods(context.TODO(), pd, pods)).Should(gomega.BeNil())
+	g.Expect(updated(mgr)).Should(gomega.Equal(5))
+}
@@ -31,6 +31,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
@@ -42,6 +43,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"kusionstack.io/kuperator/pkg/controllers/utils"
+	utilspoddecoration "kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/anno"
 )
 
 const (
@@ -61,6 +63,9 @@ type Controller interface {
 	RegisterGenericEventChannel(chan<- event.GenericEvent)
 	// InjectClient inject manager client into Controller
 	InjectClient(client.Client) error
+	// SyncNamespace loads PodDecorations of the namespace, including cluster-wide ones selecting it, instead of
+	// all PodDecorations loaded by Start. It is used out of controller, e.g. by the preview tool.
+	SyncNamespace(ctx context.Context, namespace string) error
 }
 
 type Updater interface {
@@ -74,26 +79,33 @@ type Updater interface {
 }
 
 type Reader interface {
-	// LatestPodDecorations are a set of the most recent PodDecorations in the namespace.
+	// LatestPodDecorations are a set of the most recent PodDecorations in the namespace, including
+	// cluster-wide ones selecting the namespace.
 	LatestPodDecorations(namespace string) []*appsv1alpha1.PodDecoration
 	// EffectivePodRevisions is used to select the suitable version from the UpdatedRevision
 	// and CurrentRevision among a set of the latest Decorations.
 	EffectivePodRevisions(*corev1.Pod) (updatedRevisions, stableRevisions map[string]string)
+	// IsOverridden returns true if the PodDecoration is overridden on the pod by another one in the same group,
+	// or by the namespace-local one with the same name.
+	IsOverridden(*corev1.Pod, *appsv1alpha1.PodDecoration) bool
 }
 
 func init() {
 	SharedStrategyController = &strategyManager{
-		managers: map[string]map[string]*podDecorationManager{},
+		managers:        map[string]map[string]*podDecorationManager{},
+		clusterManagers: map[string]*podDecorationManager{},
 	}
 }
 
 type strategyManager struct {
 	client.Client
 	// PDNamespace:PDName:Manager
-	managers  map[string]map[string]*podDecorationManager
-	listeners []chan<- event.GenericEvent
-	synced    bool
-	mu        sync.RWMutex
+	managers map[string]map[string]*podDecorationManager
+	// PDName:Manager of cluster-wide PodDecorations
+	clusterManagers map[string]*podDecorationManager
+	listeners       []chan<- event.GenericEvent
+	synced          bool
+	mu              sync.RWMutex
 }
 
 func (m *strategyManager) Start(ctx context.Context, h handler.EventHandler, q workqueue.RateLimitingInterface, p ...predicate.Predicate) error {
@@ -157,18 +169,10 @@ func (m *strategyManager) syncAllPodDecorations(ctx context.Context) error {
 			klog.Infof("wait for PodDecoration %s/%s ObservedGeneration update", pd.Namespace, pd.Name)
 			continue
 		}
-		podList := &corev1.PodList{}
-		sel := labels.Everything()
-		if pd.Spec.Selector != nil {
-			sel, _ = metav1.LabelSelectorAsSelector(pd.Spec.Selector)
-		}
-		if err := m.List(ctx, podList, &client.ListOptions{Namespace: pd.Namespace, LabelSelector: sel}); err != nil {
+		pods, err := ListSelectedPods(ctx, m.Client, pd)
+		if err != nil {
 			return err
 		}
-		var pods []*corev1.Pod
-		for idx := range podList.Items {
-			pods = append(pods, &podList.Items[idx])
-		}
 		if err := m.UpdateSelectedPods(ctx, pd, pods); err != nil {
 			klog.Errorf("fail to update PodDecotation %s/%s strategy manager, %v", pd.Namespace, pd.Name, err)
 		}
@@ -176,6 +180,42 @@ func (m *strategyManager) syncAllPodDecorations(ctx context.Context) error {
 	return nil
 }
 
+// SyncNamespace waits until PodDecorations of the namespace are observed by controller, and loads them with
+// their selected pods. It returns an error if they are not observed before ctx is done.
+func (m *strategyManager) SyncNamespace(ctx context.Context, namespace string) error {
+	var pds []appsv1alpha1.PodDecoration
+	err := wait.PollImmediateUntilWithContext(ctx, syncedPollPeriod, func(ctx context.Context) (bool, error) {
+		var err error
+		if pds, err = ListNamespaceDecorations(ctx, m.Client, namespace); err != nil {
+			return false, err
+		}
+		for i := range pds {
+			if pds[i].DeletionTimestamp == nil && pds[i].Generation != pds[i].Status.ObservedGeneration {
+				klog.Infof("wait for PodDecoration %s/%s ObservedGeneration update", pds[i].Namespace, pds[i].Name)
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("fail to sync PodDecorations of namespace %s: %w", namespace, err)
+	}
+	for i := range pds {
+		pd := &pds[i]
+		if pd.DeletionTimestamp != nil {
+			continue
+		}
+		pods, err := ListSelectedPods(ctx, m.Client, pd)
+		if err != nil {
+			return err
+		}
+		if err := m.UpdateSelectedPods(ctx, pd, pods); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *strategyManager) HasSynced() bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -202,39 +242,131 @@ func (m *strategyManager) WaitForSync(ctx context.Context) error {
 }
 
 func (m *strategyManager) LatestPodDecorations(namespace string) (pds []*appsv1alpha1.PodDecoration) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	namespacedMgr, ok := m.managers[namespace]
-	if ok {
-		for _, mgr := range namespacedMgr {
-			pds = append(pds, mgr.latestPodDecoration.DeepCopy())
-		}
+	for _, mgr := range m.namespaceManagers(namespace) {
+		pds = append(pds, mgr.latest().DeepCopy())
 	}
 	return
 }
 
 func (m *strategyManager) EffectivePodRevisions(po *corev1.Pod) (updatedRevisions, stableRevisions map[string]string) {
 	updatedRevisions, stableRevisions = map[string]string{}, map[string]string{}
-	m.mu.RLock()
-	namespacedMgr, ok := m.managers[po.Namespace]
-	m.mu.RUnlock()
-	if !ok {
-		return
-	}
-	for pdName, mgr := range namespacedMgr {
+	for _, mgr := range m.effectiveManagers(po) {
 		revision, isUpdated := mgr.getSuitableRevision(po)
 		if revision == nil || *revision == "" {
 			continue
 		}
 		if isUpdated {
-			updatedRevisions[pdName] = *revision
+			updatedRevisions[mgr.name] = *revision
 		} else {
-			stableRevisions[pdName] = *revision
+			stableRevisions[mgr.name] = *revision
 		}
 	}
 	return
 }
 
+// IsOverridden compares the given PodDecoration with others selecting the pod, instead of the cached one, which
+// may be stale or not registered yet.
+func (m *strategyManager) IsOverridden(po *corev1.Pod, pd *appsv1alpha1.PodDecoration) bool {
+	group := utilspoddecoration.GetGroup(pd)
+	clusterWide := utilspoddecoration.IsClusterWide(pd)
+	for _, mgr := range m.namespaceManagers(po.Namespace) {
+		if mgr.namespace == pd.Namespace && mgr.name == pd.Name {
+			continue
+		}
+		latest := mgr.latest()
+		if clusterWide && mgr.name == pd.Name && !utilspoddecoration.IsClusterWide(latest) {
+			return true
+		}
+		if group == "" || utilspoddecoration.GetGroup(latest) != group || !match(latest.Spec.Selector, po.Labels) {
+			continue
+		}
+		if !overrides(pd, latest) {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceManagers returns managers of PodDecorations in the namespace, and cluster-wide ones selecting the
+// namespace. Cluster-wide ones are overridden by namespace-local ones with the same name.
+func (m *strategyManager) namespaceManagers(namespace string) []*podDecorationManager {
+	m.mu.RLock()
+	var res []*podDecorationManager
+	names := sets.NewString()
+	for name, mgr := range m.managers[namespace] {
+		if mgr.latest() == nil {
+			continue
+		}
+		res = append(res, mgr)
+		names.Insert(name)
+	}
+	var clusterMgrs []*podDecorationManager
+	for name, mgr := range m.clusterManagers {
+		if !names.Has(name) && mgr.latest() != nil {
+			clusterMgrs = append(clusterMgrs, mgr)
+		}
+	}
+	m.mu.RUnlock()
+	if len(clusterMgrs) == 0 {
+		return res
+	}
+	ns := &corev1.Namespace{}
+	if err := m.Get(context.TODO(), types.NamespacedName{Name: namespace}, ns); err != nil {
+		klog.Errorf("fail to get namespace %s, %v", namespace, err)
+		return res
+	}
+	for _, mgr := range clusterMgrs {
+		if utilspoddecoration.SelectsNamespace(mgr.latest(), ns) {
+			res = append(res, mgr)
+		}
+	}
+	return res
+}
+
+// effectiveManagers returns managers of PodDecorations selecting the pod. In each group, only the one overriding
+// others is returned.
+func (m *strategyManager) effectiveManagers(po *corev1.Pod) []*podDecorationManager {
+	var res []*podDecorationManager
+	groups := map[string]*podDecorationManager{}
+	for _, mgr := range m.namespaceManagers(po.Namespace) {
+		latest := mgr.latest()
+		if !match(latest.Spec.Selector, po.Labels) {
+			continue
+		}
+		group := utilspoddecoration.GetGroup(latest)
+		if group == "" {
+			res = append(res, mgr)
+			continue
+		}
+		if other, ok := groups[group]; !ok || overrides(latest, other.latest()) {
+			groups[group] = mgr
+		}
+	}
+	for _, mgr := range groups {
+		res = append(res, mgr)
+	}
+	return res
+}
+
+// overrides returns true if PodDecoration a overrides b in the same group, by higher weight, being
+// namespace-local, or by name
+func overrides(a, b *appsv1alpha1.PodDecoration) bool {
+	var wa, wb int32
+	if a.Spec.Weight != nil {
+		wa = *a.Spec.Weight
+	}
+	if b.Spec.Weight != nil {
+		wb = *b.Spec.Weight
+	}
+	if wa != wb {
+		return wa > wb
+	}
+	if aw, bw := utilspoddecoration.IsClusterWide(a), utilspoddecoration.IsClusterWide(b); aw != bw {
+		return bw
+	}
+	return a.Name > b.Name
+}
+
 func (m *strategyManager) UpdateSelectedPods(ctx context.Context, pd *appsv1alpha1.PodDecoration, pods []*corev1.Pod) error {
 	mgr := m.podDecorationMgr(pd)
 	if err := mgr.updateSelectedPods(ctx, pd, pods); err != nil {
@@ -249,23 +381,43 @@ func (m *strategyManager) UpdateSelectedPods(ctx context.Context, pd *appsv1alph
 func (m *strategyManager) DeletePodDecoration(pd *appsv1alpha1.PodDecoration) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	namespacedMgr, ok := m.managers[pd.Namespace]
-	if !ok {
-		return
+	if mgr := m.deleteMgr(pd, utilspoddecoration.IsClusterWide(pd)); mgr != nil {
+		for i := range m.listeners {
+			mgr.broadcast(m.listeners[i])
+		}
 	}
-	mgr, ok := namespacedMgr[pd.Name]
-	if !ok {
-		return
+}
+
+// deleteMgr deletes the cluster-wide or namespace-local manager of the PodDecoration
+func (m *strategyManager) deleteMgr(pd *appsv1alpha1.PodDecoration, clusterWide bool) *podDecorationManager {
+	if clusterWide {
+		mgr, ok := m.clusterManagers[pd.Name]
+		if ok {
+			delete(m.clusterManagers, pd.Name)
+		}
+		return mgr
 	}
-	delete(namespacedMgr, pd.Name)
-	for i := range m.listeners {
-		mgr.broadcast(m.listeners[i])
+	mgr, ok := m.managers[pd.Namespace][pd.Name]
+	if ok {
+		delete(m.managers[pd.Namespace], pd.Name)
 	}
+	return mgr
 }
 
 func (m *strategyManager) podDecorationMgr(pd *appsv1alpha1.PodDecoration) *podDecorationManager {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	clusterWide := utilspoddecoration.IsClusterWide(pd)
+	// namespace selector may be added or removed
+	m.deleteMgr(pd, !clusterWide)
+	if clusterWide {
+		pm, ok := m.clusterManagers[pd.Name]
+		if !ok {
+			pm = m.newPodDecorationMgr(pd)
+			m.clusterManagers[pd.Name] = pm
+		}
+		return pm
+	}
 	namespacedManager, ok := m.managers[pd.Namespace]
 	if !ok {
 		namespacedManager = make(map[string]*podDecorationManager)
@@ -275,24 +427,38 @@ func (m *strategyManager) podDecorationMgr(pd *appsv1alpha1.PodDecoration) *podD
 	if ok {
 		return pm
 	}
-	pm = &podDecorationManager{
+	pm = m.newPodDecorationMgr(pd)
+	namespacedManager[pd.Name] = pm
+	return pm
+}
+
+func (m *strategyManager) newPodDecorationMgr(pd *appsv1alpha1.PodDecoration) *podDecorationManager {
+	return &podDecorationManager{
 		c:             m.Client,
 		name:          pd.Name,
 		namespace:     pd.Namespace,
 		effectivePods: map[string]*podInfo{},
 	}
-	namespacedManager[pd.Name] = pm
-	return pm
 }
 
 type podDecorationManager struct {
-	c                        client.Client
-	name, namespace          string
+	c               client.Client
+	name, namespace string
+	// effectivePods and partitionOldRevisionPods are keyed by namespace/name of pods, and relatedCollaSets
+	// are namespace/name of CollaSets, because pods of cluster-wide PodDecorations are in many namespaces
 	effectivePods            effectivePods
 	relatedCollaSets         sets.String
 	partitionOldRevisionPods sets.String
-	latestPodDecoration      *appsv1alpha1.PodDecoration
-	mu                       sync.RWMutex
+	// canary is true if pods are updated by the partition of the current canary step
+	canary              bool
+	latestPodDecoration *appsv1alpha1.PodDecoration
+	mu                  sync.RWMutex
+}
+
+func (pm *podDecorationManager) latest() *appsv1alpha1.PodDecoration {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.latestPodDecoration
 }
 
 func (pm *podDecorationManager) updateSelectedPods(ctx context.Context, pd *appsv1alpha1.PodDecoration, pods []*corev1.Pod) error {
@@ -311,20 +477,26 @@ func (pm *podDecorationManager) updateSelectedPods(ctx context.Context, pd *apps
 	oldPods := pm.effectivePods
 	collaSets := sets.NewString()
 	for _, pod := range pods {
-		if !IsActive(pod) {
+		if !IsActive(pod, pm.latestPodDecoration) {
 			continue
 		}
 		newPodInfo, err := getter.buildPodInfo(pod, pm.latestPodDecoration)
 		if err != nil {
 			return err
 		}
-		collaSets.Insert(newPodInfo.collaSet)
-		newEffectivePods[pod.Name] = newPodInfo
+		if newPodInfo.collaSet != "" {
+			collaSets.Insert(newPodInfo.collaSetKey())
+		}
+		newEffectivePods[newPodInfo.key()] = newPodInfo
 		existInstanceId.Insert(newPodInfo.InstanceKey())
 	}
-	for podName, info := range oldPods {
+	for podKey, info := range oldPods {
+		// No placeholder for deleted pods not controlled by CollaSet
+		if info.collaSet == "" {
+			continue
+		}
 		// Scaled, release placeholder
-		collaSets.Insert(info.collaSet)
+		collaSets.Insert(info.collaSetKey())
 		if existInstanceId.Has(info.InstanceKey()) {
 			continue
 		}
@@ -332,7 +504,7 @@ func (pm *podDecorationManager) updateSelectedPods(ctx context.Context, pd *apps
 		if !utils.Selected(pm.latestPodDecoration.Spec.Selector, info.labels) {
 			continue
 		}
-		_, ok := newEffectivePods[podName]
+		_, ok := newEffectivePods[podKey]
 		if !ok {
 			resource, err := getter.relatePodInfo(info, pd.Name)
 			if err != nil {
@@ -345,13 +517,17 @@ func (pm *podDecorationManager) updateSelectedPods(ctx context.Context, pd *apps
 			// Placeholder case: pod deleted but instanceId exists.
 			if resource.AllocatedIDs.Has(info.instanceId) {
 				info.state.IsDeleted = true
-				newEffectivePods[podName] = info
+				newEffectivePods[podKey] = info
 			}
 		}
 	}
 	pm.relatedCollaSets = collaSets
 	pm.effectivePods = newEffectivePods
-	if pm.latestPodDecoration.Spec.UpdateStrategy.RollingUpdate != nil &&
+	var percent int32
+	if percent, pm.canary = canaryPercent(pm.latestPodDecoration); pm.canary {
+		pm.updatePartitionPods(pm.effectivePods, pm.latestPodDecoration.Status.UpdatedRevision,
+			len(pm.effectivePods)-percentOf(percent, len(pm.effectivePods)))
+	} else if pm.latestPodDecoration.Spec.UpdateStrategy.RollingUpdate != nil &&
 		pm.latestPodDecoration.Spec.UpdateStrategy.RollingUpdate.Partition != nil {
 		pm.updatePartitionPods(pm.effectivePods, pm.latestPodDecoration.Status.UpdatedRevision,
 			int(*pm.latestPodDecoration.Spec.UpdateStrategy.RollingUpdate.Partition))
@@ -361,8 +537,9 @@ func (pm *podDecorationManager) updateSelectedPods(ctx context.Context, pd *apps
 }
 
 func (pm *podDecorationManager) broadcast(ch chan<- event.GenericEvent) {
-	for name := range pm.relatedCollaSets {
-		ch <- event.GenericEvent{Object: &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: pm.namespace, Name: name}}}
+	for key := range pm.relatedCollaSets {
+		namespace, name, _ := cache.SplitMetaNamespaceKey(key)
+		ch <- event.GenericEvent{Object: &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}}
 	}
 }
 
@@ -374,6 +551,13 @@ func (pm *podDecorationManager) getSuitableRevision(pod *corev1.Pod) (revision *
 	}
 	updateRev := pm.latestPodDecoration.Status.UpdatedRevision
 	currentRev := pm.latestPodDecoration.Status.CurrentRevision
+	// by canary steps, which take precedence over the update strategy
+	if pm.canary {
+		if pm.partitionOldRevisionPods.Has(podKey(pod.Namespace, pod.Name)) {
+			return &currentRev, false
+		}
+		return &updateRev, true
+	}
 	// default nil select all
 	if pm.latestPodDecoration.Spec.UpdateStrategy.RollingUpdate == nil {
 		return &updateRev, true
@@ -387,7 +571,7 @@ func (pm *podDecorationManager) getSuitableRevision(pod *corev1.Pod) (revision *
 	}
 	// by partition
 	if pm.latestPodDecoration.Spec.UpdateStrategy.RollingUpdate.Partition != nil {
-		if pm.partitionOldRevisionPods.Has(pod.Name) {
+		if pm.partitionOldRevisionPods.Has(podKey(pod.Namespace, pod.Name)) {
 			return &currentRev, false
 		}
 		return &updateRev, true
@@ -407,7 +591,7 @@ func (pm *podDecorationManager) updatePartitionPods(pods effectivePods, revision
 		idx = 0
 	}
 	for ; idx < len(sortedPodInfos.infos); idx++ {
-		pm.partitionOldRevisionPods.Insert(sortedPodInfos.infos[idx].name)
+		pm.partitionOldRevisionPods.Insert(sortedPodInfos.infos[idx].key())
 	}
 }
 
@@ -420,7 +604,7 @@ func newPodRelatedResourceGetter(ctx context.Context, c client.Client) *podRelat
 	}
 }
 
-// podRelatedResourceGetter
+// podRelatedResourceGetter caches related resources by namespace/name of pods and CollaSets
 type podRelatedResourceGetter struct {
 	ctx context.Context
 	client.Client
@@ -438,7 +622,8 @@ type relatedResource struct {
 }
 
 func (r *podRelatedResourceGetter) relatedPod(po *corev1.Pod, pdName string) (*relatedResource, error) {
-	if resource, ok := r.podResources[po.Name]; ok {
+	key := podKey(po.Namespace, po.Name)
+	if resource, ok := r.podResources[key]; ok {
 		return resource, nil
 	}
 	ownerRef := metav1.GetControllerOf(po)
@@ -446,8 +631,9 @@ func (r *podRelatedResourceGetter) relatedPod(po *corev1.Pod, pdName string) (*r
 		return nil, fmt.Errorf("pod %s was not controlled by collaset", po.Name)
 	}
 
-	if resource, ok := r.collaSetResources[ownerRef.Name]; ok {
-		r.podResources[po.Name] = resource
+	collaSetKey := podKey(po.Namespace, ownerRef.Name)
+	if resource, ok := r.collaSetResources[collaSetKey]; ok {
+		r.podResources[key] = resource
 		return resource, nil
 	}
 
@@ -455,13 +641,13 @@ func (r *podRelatedResourceGetter) relatedPod(po *corev1.Pod, pdName string) (*r
 	if err != nil {
 		return nil, err
 	}
-	r.collaSetResources[ownerRef.Name] = resource
-	r.podResources[po.Name] = resource
+	r.collaSetResources[collaSetKey] = resource
+	r.podResources[key] = resource
 	return resource, nil
 }
 
 func (r *podRelatedResourceGetter) relatePodInfo(info *podInfo, pdName string) (*relatedResource, error) {
-	resource, ok := r.collaSetResources[info.collaSet]
+	resource, ok := r.collaSetResources[info.collaSetKey()]
 	if ok {
 		return resource, nil
 	}
@@ -469,8 +655,8 @@ func (r *podRelatedResourceGetter) relatePodInfo(info *podInfo, pdName string) (
 	if err != nil {
 		return nil, err
 	}
-	r.collaSetResources[info.collaSet] = resource
-	r.podResources[info.name] = resource
+	r.collaSetResources[info.collaSetKey()] = resource
+	r.podResources[info.key()] = resource
 	return resource, nil
 }
 
@@ -493,12 +679,16 @@ func (r *podRelatedResourceGetter) getResources(namespace, collaSetName, pdName
 }
 
 func (r *podRelatedResourceGetter) buildPodInfo(pod *corev1.Pod, pd *appsv1alpha1.PodDecoration) (*podInfo, error) {
+	if !isControlledByCollaSet(pod) {
+		return buildStandalonePodInfo(pod, pd), nil
+	}
 	resource, err := r.relatedPod(pod, pd.Name)
 	if err != nil {
 		return nil, err
 	}
 	info := &podInfo{
 		name:            pod.Name,
+		uid:             string(pod.UID),
 		namespace:       pod.Namespace,
 		labels:          pod.Labels,
 		collaSet:        resource.CollaSet.Name,
@@ -514,6 +704,21 @@ func (r *podRelatedResourceGetter) buildPodInfo(pod *corev1.Pod, pd *appsv1alpha
 	return info, nil
 }
 
+// buildStandalonePodInfo builds info of pod not controlled by CollaSet, which is decorated by the pod webhook
+// on creation. It is taken as the updated revision of workload, and sorted by name and UID in partition.
+func buildStandalonePodInfo(pod *corev1.Pod, pd *appsv1alpha1.PodDecoration) *podInfo {
+	info := &podInfo{
+		name:      pod.Name,
+		uid:       string(pod.UID),
+		namespace: pod.Namespace,
+		labels:    pod.Labels,
+		revision:  pod.Labels[appsv1alpha1.PodDecorationLabelPrefix+pd.Name],
+	}
+	setPodState(pod, info)
+	info.state.IsCollaSetUpdatedRevision = true
+	return info
+}
+
 func match(selector *metav1.LabelSelector, lb map[string]string) bool {
 	sel := labels.Everything()
 	if sel != nil {
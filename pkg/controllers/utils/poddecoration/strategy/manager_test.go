@@ -39,6 +39,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+	utilspoddecoration "kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/anno"
 )
 
 var (
@@ -199,6 +202,154 @@ var _ = Describe("Test PodDecoration strategy manager", func() {
 		Expect(sortedInfos.infos[2].instanceId).Should(Equal("1"))
 	})
 
+	It("Cluster-wide PodDecoration overridden by group", func() {
+		testcase := "test-pd-cluster"
+		nsA, nsB := testcase+"-a", testcase+"-b"
+		for _, name := range []string{nsA, nsB} {
+			Expect(c.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{"log-agent": "enabled"},
+			}})).Should(BeNil())
+		}
+		Expect(createNamespace(testcase + "-c")).Should(BeNil())
+		clusterWide := &appsv1alpha1.PodDecoration{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: utilspoddecoration.ClusterNamespace,
+				Name:      "log-agent",
+				Annotations: map[string]string{
+					kuperatorv1alpha1.AnnotationPodDecorationNamespaceSelector: `{"matchLabels": {"log-agent": "enabled"}}`,
+					kuperatorv1alpha1.AnnotationPodDecorationGroup:             "logging",
+					kuperatorv1alpha1.AnnotationPodDecorationWebhookInjection:  "true",
+				},
+			},
+			Spec: appsv1alpha1.PodDecorationSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+			},
+			Status: appsv1alpha1.PodDecorationStatus{UpdatedRevision: "log-agent-1"},
+		}
+		local := &appsv1alpha1.PodDecoration{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: nsA,
+				Name:      "custom-log",
+				Annotations: map[string]string{
+					kuperatorv1alpha1.AnnotationPodDecorationGroup:            "logging",
+					kuperatorv1alpha1.AnnotationPodDecorationWebhookInjection: "true",
+				},
+			},
+			Spec: appsv1alpha1.PodDecorationSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+				Weight:   int32Pointer(10),
+			},
+			Status: appsv1alpha1.PodDecorationStatus{UpdatedRevision: "custom-log-1"},
+		}
+		var pods []*corev1.Pod
+		for _, ns := range []string{nsA, nsB} {
+			pods = append(pods, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: ns,
+					Name:      "foo",
+					UID:       types.UID(ns),
+					Labels: map[string]string{
+						"app": "foo",
+						kuperatorv1alpha1.LabelPodWebhookInjection: "true",
+					},
+				},
+			})
+		}
+		mgr := &strategyManager{
+			Client:          c,
+			managers:        map[string]map[string]*podDecorationManager{},
+			clusterManagers: map[string]*podDecorationManager{},
+		}
+		Expect(mgr.UpdateSelectedPods(ctx, clusterWide, pods)).Should(BeNil())
+		Expect(mgr.UpdateSelectedPods(ctx, local, pods[:1])).Should(BeNil())
+		// pods of the same name in different namespaces
+		Expect(len(mgr.clusterManagers["log-agent"].effectivePods)).Should(Equal(2))
+		Expect(len(mgr.LatestPodDecorations(nsA))).Should(Equal(2))
+		Expect(len(mgr.LatestPodDecorations(nsB))).Should(Equal(1))
+		Expect(len(mgr.LatestPodDecorations(testcase + "-c"))).Should(Equal(0))
+
+		// overridden by the namespace-local one with higher weight in the same group
+		updatedRevisions, _ := mgr.EffectivePodRevisions(pods[0])
+		Expect(updatedRevisions).Should(Equal(map[string]string{"custom-log": "custom-log-1"}))
+		Expect(mgr.IsOverridden(pods[0], clusterWide)).Should(BeTrue())
+		updatedRevisions, _ = mgr.EffectivePodRevisions(pods[1])
+		Expect(updatedRevisions).Should(Equal(map[string]string{"log-agent": "log-agent-1"}))
+		Expect(mgr.IsOverridden(pods[1], clusterWide)).Should(BeFalse())
+
+		// namespace-local one overrides the cluster-wide one with the same name
+		mgr.DeletePodDecoration(local)
+		local.Name = "log-agent"
+		delete(local.Annotations, kuperatorv1alpha1.AnnotationPodDecorationGroup)
+		Expect(mgr.UpdateSelectedPods(ctx, local, pods[:1])).Should(BeNil())
+		Expect(len(mgr.LatestPodDecorations(nsA))).Should(Equal(1))
+		Expect(mgr.IsOverridden(pods[0], clusterWide)).Should(BeTrue())
+		updatedRevisions, _ = mgr.EffectivePodRevisions(pods[0])
+		Expect(updatedRevisions).Should(Equal(map[string]string{"log-agent": "custom-log-1"}))
+	})
+
+	It("Partition pods not controlled by CollaSet", func() {
+		testcase := "test-pd-standalone"
+		podDecoration := &appsv1alpha1.PodDecoration{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   testcase,
+				Name:        "foo",
+				Annotations: map[string]string{kuperatorv1alpha1.AnnotationPodDecorationWebhookInjection: "true"},
+			},
+			Spec: appsv1alpha1.PodDecorationSpec{
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"app": "foo",
+					},
+				},
+				UpdateStrategy: appsv1alpha1.PodDecorationUpdateStrategy{
+					RollingUpdate: &appsv1alpha1.PodDecorationRollingUpdate{
+						Partition: int32Pointer(1),
+					},
+				},
+			},
+			Status: appsv1alpha1.PodDecorationStatus{
+				CurrentRevision: "1",
+				UpdatedRevision: "2",
+			},
+		}
+		var pods []*corev1.Pod
+		for _, name := range []string{"pod-b", "pod-a", "pod-c"} {
+			pods = append(pods, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: testcase,
+					Name:      name,
+					UID:       types.UID(name),
+					Labels: map[string]string{
+						"app": "foo",
+						kuperatorv1alpha1.LabelPodWebhookInjection: "true",
+					},
+				},
+			})
+		}
+		mgr := &strategyManager{Client: c, managers: map[string]map[string]*podDecorationManager{}}
+		Expect(mgr.UpdateSelectedPods(ctx, podDecoration, pods)).Should(BeNil())
+		Expect(len(mgr.managers[testcase]["foo"].effectivePods)).Should(Equal(3))
+		Expect(len(mgr.managers[testcase]["foo"].relatedCollaSets)).Should(Equal(0))
+		// sorted by name
+		for _, pod := range pods {
+			updatedRevisions, stableRevisions := mgr.EffectivePodRevisions(pod)
+			if pod.Name == "pod-c" {
+				Expect(stableRevisions["foo"]).Should(Equal("1"))
+			} else {
+				Expect(updatedRevisions["foo"]).Should(Equal("2"))
+			}
+		}
+
+		// pods not opting in are not in effect
+		delete(pods[2].Labels, kuperatorv1alpha1.LabelPodWebhookInjection)
+		Expect(mgr.UpdateSelectedPods(ctx, podDecoration, pods)).Should(BeNil())
+		Expect(len(mgr.managers[testcase]["foo"].effectivePods)).Should(Equal(2))
+		updatedRevisions, stableRevisions := mgr.EffectivePodRevisions(pods[0])
+		Expect(len(updatedRevisions)).Should(Equal(0))
+		Expect(stableRevisions["foo"]).Should(Equal("1"))
+	})
+
 	It("RollingUpdate by Partition", func() {
 		testcase := "test-pd-2"
 		Expect(createNamespace(testcase)).Should(BeNil())
@@ -43,6 +43,7 @@ type effectivePods map[string]*podInfo
 
 type podInfo struct {
 	name            string
+	uid             string
 	namespace       string
 	labels          map[string]string
 	collaSet        string
@@ -65,7 +66,24 @@ type podState struct {
 }
 
 func (p *podInfo) InstanceKey() string {
-	return p.resourceContext + "/" + p.instanceId
+	if p.collaSet == "" {
+		// pods not controlled by CollaSet have no instance ID
+		return p.uid
+	}
+	return p.namespace + "/" + p.resourceContext + "/" + p.instanceId
+}
+
+func (p *podInfo) key() string {
+	return podKey(p.namespace, p.name)
+}
+
+func (p *podInfo) collaSetKey() string {
+	return podKey(p.namespace, p.collaSet)
+}
+
+// podKey returns namespace/name of pods or CollaSets
+func podKey(namespace, name string) string {
+	return namespace + "/" + name
 }
 
 func Compare(l, r *podInfo) bool {
@@ -114,7 +132,14 @@ func Compare(l, r *podInfo) bool {
 	if !l.state.CreationTimestamp.Equal(r.state.CreationTimestamp) {
 		return afterOrZero(l.state.CreationTimestamp, r.state.CreationTimestamp)
 	}
-	return l.instanceId < r.instanceId
+	if l.instanceId != r.instanceId {
+		return l.instanceId < r.instanceId
+	}
+	// pods not controlled by CollaSet have no instance ID
+	if l.name != r.name {
+		return l.name < r.name
+	}
+	return l.uid < r.uid
 }
 
 func setPodState(pod *corev1.Pod, info *podInfo) {
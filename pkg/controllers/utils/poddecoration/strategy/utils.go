@@ -22,6 +22,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
@@ -47,11 +48,85 @@ func GetResourceContext(ctx context.Context, c client.Client, instance *appsv1al
 	return resourceCtx, nil
 }
 
-func IsActive(po *corev1.Pod) bool {
+// ListSelectedPods lists pods selected by the PodDecoration, in namespaces selected by it if it is cluster-wide
+func ListSelectedPods(ctx context.Context, c client.Client, pd *appsv1alpha1.PodDecoration) ([]*corev1.Pod, error) {
+	sel := labels.Everything()
+	if pd.Spec.Selector != nil {
+		var err error
+		if sel, err = metav1.LabelSelectorAsSelector(pd.Spec.Selector); err != nil {
+			return nil, err
+		}
+	}
+	namespaces := []string{pd.Namespace}
+	if utilspoddecoration.IsClusterWide(pd) {
+		nsSelector, err := utilspoddecoration.GetNamespaceSelector(pd)
+		if err != nil {
+			return nil, err
+		}
+		nsList := &corev1.NamespaceList{}
+		if err = c.List(ctx, nsList, &client.ListOptions{LabelSelector: nsSelector}); err != nil {
+			return nil, err
+		}
+		namespaces = namespaces[:0]
+		for i := range nsList.Items {
+			namespaces = append(namespaces, nsList.Items[i].Name)
+		}
+	}
+	var pods []*corev1.Pod
+	for _, ns := range namespaces {
+		podList := &corev1.PodList{}
+		if err := c.List(ctx, podList, &client.ListOptions{Namespace: ns, LabelSelector: sel}); err != nil {
+			return nil, err
+		}
+		for i := range podList.Items {
+			pods = append(pods, &podList.Items[i])
+		}
+	}
+	return pods, nil
+}
+
+// ListNamespaceDecorations lists PodDecorations which may decorate pods in the namespace, including the
+// namespace-local ones and cluster-wide ones selecting the namespace
+func ListNamespaceDecorations(ctx context.Context, c client.Client, namespace string) ([]appsv1alpha1.PodDecoration, error) {
+	pdList := &appsv1alpha1.PodDecorationList{}
+	if err := c.List(ctx, pdList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	var pds []appsv1alpha1.PodDecoration
+	for i := range pdList.Items {
+		if !utilspoddecoration.IsClusterWide(&pdList.Items[i]) {
+			pds = append(pds, pdList.Items[i])
+		}
+	}
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return pds, client.IgnoreNotFound(err)
+	}
+	if namespace != utilspoddecoration.ClusterNamespace {
+		pdList = &appsv1alpha1.PodDecorationList{}
+		if err := c.List(ctx, pdList, client.InNamespace(utilspoddecoration.ClusterNamespace)); err != nil {
+			return nil, err
+		}
+	}
+	for i := range pdList.Items {
+		if utilspoddecoration.IsClusterWide(&pdList.Items[i]) && utilspoddecoration.SelectsNamespace(&pdList.Items[i], ns) {
+			pds = append(pds, pdList.Items[i])
+		}
+	}
+	return pds, nil
+}
+
+// IsActive returns true if the pod is not deleting, and is controlled by CollaSet or decorated by the pod webhook
+func IsActive(po *corev1.Pod, pd *appsv1alpha1.PodDecoration) bool {
+	if po.DeletionTimestamp != nil {
+		return false
+	}
+	return isControlledByCollaSet(po) || utilspoddecoration.InjectedByWebhook(po, pd)
+}
+
+func isControlledByCollaSet(po *corev1.Pod) bool {
 	ownerRef := metav1.GetControllerOf(po)
-	return ownerRef != nil &&
-		ownerRef.Kind == "CollaSet" &&
-		po.DeletionTimestamp == nil
+	return ownerRef != nil && ownerRef.Kind == "CollaSet"
 }
 
 func getAllocatedId(context *appsv1alpha1.ResourceContext) sets.String {
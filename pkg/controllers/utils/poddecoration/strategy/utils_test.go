@@ -0,0 +1,146 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package strategy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+	utilspoddecoration "kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/anno"
+)
+
+func TestListNamespaceDecorations(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).Should(gomega.Succeed())
+	g.Expect(appsv1alpha1.AddToScheme(scheme)).Should(gomega.Succeed())
+
+	newClusterWide := func(name, selector string) *appsv1alpha1.PodDecoration {
+		return &appsv1alpha1.PodDecoration{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   utilspoddecoration.ClusterNamespace,
+			Name:        name,
+			Annotations: map[string]string{kuperatorv1alpha1.AnnotationPodDecorationNamespaceSelector: selector},
+		}}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo", Labels: map[string]string{"mesh": "enabled"}}},
+		&appsv1alpha1.PodDecoration{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "local"}},
+		&appsv1alpha1.PodDecoration{ObjectMeta: metav1.ObjectMeta{Namespace: "bar", Name: "other"}},
+		newClusterWide("mesh", `{"matchLabels": {"mesh": "enabled"}}`),
+		newClusterWide("log", `{"matchLabels": {"log": "enabled"}}`),
+	).Build()
+
+	pds, err := ListNamespaceDecorations(context.Background(), c, "foo")
+	g.Expect(err).Should(gomega.BeNil())
+	var names []string
+	for i := range pds {
+		names = append(names, pds[i].Name)
+	}
+	g.Expect(names).Should(gomega.ConsistOf("local", "mesh"))
+}
+
+func TestIsOverridden(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).Should(gomega.Succeed())
+	g.Expect(appsv1alpha1.AddToScheme(scheme)).Should(gomega.Succeed())
+
+	newPD := func(name string, weight int32) *appsv1alpha1.PodDecoration {
+		return &appsv1alpha1.PodDecoration{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "foo",
+				Name:        name,
+				Annotations: map[string]string{kuperatorv1alpha1.AnnotationPodDecorationGroup: "logging"},
+			},
+			Spec: appsv1alpha1.PodDecorationSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+				Weight:   &weight,
+			},
+		}
+	}
+	ctx := context.Background()
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "foo-0", Labels: map[string]string{"app": "foo"}}}
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+	mgr := &strategyManager{
+		Client:          c,
+		managers:        map[string]map[string]*podDecorationManager{},
+		clusterManagers: map[string]*podDecorationManager{},
+	}
+	low, high := newPD("low", 1), newPD("high", 10)
+	g.Expect(mgr.UpdateSelectedPods(ctx, low, nil)).Should(gomega.Succeed())
+
+	// the one with higher weight is not registered yet on the first reconciling
+	g.Expect(mgr.IsOverridden(pod, high)).Should(gomega.BeFalse())
+	g.Expect(mgr.IsOverridden(pod, low)).Should(gomega.BeFalse())
+
+	g.Expect(mgr.UpdateSelectedPods(ctx, high, nil)).Should(gomega.Succeed())
+	g.Expect(mgr.IsOverridden(pod, high)).Should(gomega.BeFalse())
+	g.Expect(mgr.IsOverridden(pod, low)).Should(gomega.BeTrue())
+
+	// the given instance is compared, instead of the cached one
+	low.Spec.Weight = int32Pointer(20)
+	g.Expect(mgr.IsOverridden(pod, low)).Should(gomega.BeFalse())
+
+	// pods not selected by the other one are not overridden
+	pod.Labels = map[string]string{"app": "bar"}
+	g.Expect(mgr.IsOverridden(pod, newPD("other", 0))).Should(gomega.BeFalse())
+}
+
+func TestSyncNamespace(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	scheme := runtime.NewScheme()
+	g.Expect(clientgoscheme.AddToScheme(scheme)).Should(gomega.Succeed())
+	g.Expect(appsv1alpha1.AddToScheme(scheme)).Should(gomega.Succeed())
+
+	newPD := func(namespace, name string, generation int64) *appsv1alpha1.PodDecoration {
+		return &appsv1alpha1.PodDecoration{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Generation: generation},
+			Spec: appsv1alpha1.PodDecorationSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+			},
+			Status: appsv1alpha1.PodDecorationStatus{ObservedGeneration: 1},
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newPD("foo", "local", 1),
+		newPD("bar", "pending", 2),
+	).Build()
+	mgr := &strategyManager{
+		Client:          c,
+		managers:        map[string]map[string]*podDecorationManager{},
+		clusterManagers: map[string]*podDecorationManager{},
+	}
+	ctx := context.Background()
+	g.Expect(mgr.SyncNamespace(ctx, "foo")).Should(gomega.Succeed())
+	g.Expect(mgr.LatestPodDecorations("foo")).Should(gomega.HaveLen(1))
+	g.Expect(mgr.LatestPodDecorations("bar")).Should(gomega.BeEmpty())
+
+	// the wait for PodDecorations not observed by controller is bounded
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	g.Expect(mgr.SyncNamespace(timeoutCtx, "bar")).Should(gomega.HaveOccurred())
+}
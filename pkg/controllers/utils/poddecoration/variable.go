@@ -0,0 +1,157 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poddecoration
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/sets"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+)
+
+// Variables $(NAME) in the template and patches of PodDecoration are replaced with values of each pod. Escaped
+// $$(NAME) and references to other env of containers are kept, which are expanded by kubelet. Known variables are
+// replaced before kubelet, so they take precedence over env of containers with the same name, e.g. $(POD_NAME) is
+// always the name of pod even if the container declares env POD_NAME. Use $$(POD_NAME) to refer to the env instead.
+const (
+	// VariableInstanceID is the ID of pod in ResourceContext, empty if pod is not controlled by CollaSet
+	VariableInstanceID = "INSTANCE_ID"
+	// VariableCollaSetName is the name of CollaSet controlling the pod, empty if not controlled by CollaSet
+	VariableCollaSetName = "COLLASET_NAME"
+	// VariablePodName is the name of pod. The name of pod created with generateName is generated in advance.
+	VariablePodName = "POD_NAME"
+	// VariablePodNamespace is the namespace of pod
+	VariablePodNamespace = "POD_NAMESPACE"
+)
+
+// KnownVariables are variables supported in PodDecoration
+var KnownVariables = sets.NewString(VariableInstanceID, VariableCollaSetName, VariablePodName, VariablePodNamespace)
+
+const (
+	maxNameLength          = 63
+	randomLength           = 5
+	maxGeneratedNameLength = maxNameLength - randomLength
+)
+
+// resolveTemplate returns the template with variables replaced with values of pod
+func resolveTemplate(pod *corev1.Pod, template *appsv1alpha1.PodDecorationPodTemplate) (*appsv1alpha1.PodDecorationPodTemplate, error) {
+	raw, err := json.Marshal(template)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(raw, []byte("$(")) {
+		return template, nil
+	}
+	resolved := &appsv1alpha1.PodDecorationPodTemplate{}
+	if err = json.Unmarshal(resolveVariables(raw, pod), resolved); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// resolveVariables replaces variables in raw with values of pod
+func resolveVariables(raw []byte, pod *corev1.Pod) []byte {
+	return []byte(expand(string(raw), func(name string) (string, bool) {
+		return variableValue(pod, name)
+	}))
+}
+
+func variableValue(pod *corev1.Pod, name string) (string, bool) {
+	switch name {
+	case VariableInstanceID:
+		return pod.Labels[appsv1alpha1.PodInstanceIDLabelKey], true
+	case VariableCollaSetName:
+		if ownerRef := metav1.GetControllerOf(pod); ownerRef != nil && ownerRef.Kind == "CollaSet" {
+			return ownerRef.Name, true
+		}
+		return "", true
+	case VariablePodName:
+		if pod.Name == "" && pod.GenerateName != "" {
+			// generate name as apiserver does, which is unknown to webhooks and controllers before creation
+			base := pod.GenerateName
+			if len(base) > maxGeneratedNameLength {
+				base = base[:maxGeneratedNameLength]
+			}
+			pod.Name = base + utilrand.String(randomLength)
+		}
+		return pod.Name, true
+	case VariablePodNamespace:
+		return pod.Namespace, true
+	}
+	return "", false
+}
+
+// Variables returns names of variables referenced in s, escaped $$(NAME) are not included
+func Variables(s string) []string {
+	var names []string
+	expand(s, func(name string) (string, bool) {
+		names = append(names, name)
+		return "", false
+	})
+	return names
+}
+
+// expand replaces $(NAME) in s with the value returned by mapping. $$ and variables unknown to mapping are kept.
+func expand(s string, mapping func(string) (string, bool)) string {
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			buf.WriteByte(s[i])
+			continue
+		}
+		switch s[i+1] {
+		case '$':
+			// escaped, kept for kubelet
+			buf.WriteString("$$")
+			i++
+		case '(':
+			end := strings.IndexByte(s[i+2:], ')')
+			if end < 0 || !isVariableName(s[i+2:i+2+end]) {
+				buf.WriteByte(s[i])
+				continue
+			}
+			name := s[i+2 : i+2+end]
+			if val, ok := mapping(name); ok {
+				buf.WriteString(val)
+			} else {
+				buf.WriteString(s[i : i+3+end])
+			}
+			i += 2 + end
+		default:
+			buf.WriteByte(s[i])
+		}
+	}
+	return buf.String()
+}
+
+func isVariableName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || i > 0 && (c >= '0' && c <= '9' || c == '.' || c == '-') {
+			continue
+		}
+		return false
+	}
+	return true
+}
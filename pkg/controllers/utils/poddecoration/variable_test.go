@@ -0,0 +1,77 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poddecoration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+)
+
+func TestExpand(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	mapping := func(name string) (string, bool) {
+		if name == "A" {
+			return "a", true
+		}
+		return "", false
+	}
+	g.Expect(expand("$(A)-$(B)", mapping)).Should(gomega.Equal("a-$(B)"))
+	g.Expect(expand("$$(A)$(A)", mapping)).Should(gomega.Equal("$$(A)a"))
+	g.Expect(expand("$(A", mapping)).Should(gomega.Equal("$(A"))
+	g.Expect(expand("$(1A) $A $", mapping)).Should(gomega.Equal("$(1A) $A $"))
+	g.Expect(Variables("$(A) $$(B) $(C)")).Should(gomega.Equal([]string{"A", "C"}))
+}
+
+func TestResolveTemplate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "foo-",
+			Namespace:    "default",
+			Labels:       map[string]string{appsv1alpha1.PodInstanceIDLabelKey: "3"},
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "CollaSet", Name: "foo", Controller: func(b bool) *bool { return &b }(true)},
+			},
+		},
+	}
+	template := &appsv1alpha1.PodDecorationPodTemplate{
+		Metadata: []*appsv1alpha1.PodDecorationPodTemplateMeta{
+			{Labels: map[string]string{"instance": "$(COLLASET_NAME)-$(INSTANCE_ID)"}},
+		},
+		Containers: []*appsv1alpha1.ContainerPatch{
+			{
+				Container: corev1.Container{
+					Name: "sidecar",
+					Args: []string{"--pod=$(POD_NAMESPACE)/$(POD_NAME)", "--dir=$(DATA_DIR)"},
+				},
+			},
+		},
+	}
+	resolved, err := resolveTemplate(pod, template)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(resolved.Metadata[0].Labels["instance"]).Should(gomega.Equal("foo-3"))
+	// name of pod is generated as it is referenced
+	g.Expect(strings.HasPrefix(pod.Name, "foo-")).Should(gomega.BeTrue())
+	g.Expect(resolved.Containers[0].Args).Should(gomega.Equal([]string{"--pod=default/" + pod.Name, "--dir=$(DATA_DIR)"}))
+	// template is not changed
+	g.Expect(template.Metadata[0].Labels["instance"]).Should(gomega.Equal("$(COLLASET_NAME)-$(INSTANCE_ID)"))
+}
@@ -30,12 +30,18 @@ const (
 	GraceDeleteWebhook featuregate.Feature = "GraceDeleteWebhook"
 	// ReclaimPodScaleStrategy enables reclaim of collaset.spec.scaleStrategy.podToDelete
 	ReclaimPodScaleStrategy featuregate.Feature = "ReclaimPodScaleStrategy"
+	// PodOpsLifecycleCondition enables the OpsLifecycle condition summarizing lifecycles on pod status
+	PodOpsLifecycleCondition featuregate.Feature = "PodOpsLifecycleCondition"
+	// PodDecorationWebhookInjection enables the pod webhook decorating pods not controlled by CollaSet
+	PodDecorationWebhookInjection featuregate.Feature = "PodDecorationWebhookInjection"
 )
 
 var defaultFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
-	AlibabaCloudSlb:         {Default: false, PreRelease: featuregate.Alpha},
-	GraceDeleteWebhook:      {Default: false, PreRelease: featuregate.Alpha},
-	ReclaimPodScaleStrategy: {Default: false, PreRelease: featuregate.Alpha},
+	AlibabaCloudSlb:               {Default: false, PreRelease: featuregate.Alpha},
+	GraceDeleteWebhook:            {Default: false, PreRelease: featuregate.Alpha},
+	ReclaimPodScaleStrategy:       {Default: false, PreRelease: featuregate.Alpha},
+	PodOpsLifecycleCondition:      {Default: false, PreRelease: featuregate.Alpha},
+	PodDecorationWebhookInjection: {Default: false, PreRelease: featuregate.Alpha},
 }
 
 func init() {
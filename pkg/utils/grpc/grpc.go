@@ -0,0 +1,145 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	// Timeout of each call, the same as the timeout of HTTP requests
+	Timeout = time.Second * 30
+
+	SchemeGRPC  = "grpc"
+	SchemeGRPCS = "grpcs"
+)
+
+var DefaultConns = &connSet{conns: map[string]*grpc.ClientConn{}, owners: map[string]string{}}
+
+// connSet caches connections by target, ca and client certificate, so a rotated certificate gets a new connection
+type connSet struct {
+	conns map[string]*grpc.ClientConn
+	// owners is the id of the connection in conns used by each owner and target
+	owners map[string]string
+	mu     sync.Mutex
+}
+
+// ParseTarget returns the target of the URL and whether TLS is required. URLs are like grpc://host:port
+// for plaintext and grpcs://host:port for TLS.
+func ParseTarget(rawURL string) (string, bool, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false, err
+	}
+	switch u.Scheme {
+	case SchemeGRPC:
+		return u.Host, false, nil
+	case SchemeGRPCS:
+		return u.Host, true, nil
+	}
+	return "", false, fmt.Errorf("unsupported scheme %q of grpc url, should be %s or %s", u.Scheme, SchemeGRPC, SchemeGRPCS)
+}
+
+// GetConn returns the connection to the URL. The base64 encoded ca is used to verify the server, and the
+// client certificate is sent if cert and key are set. Owner identifies the caller, e.g. the webhook, whose
+// previous connection to the target is closed once its ca or certificate is changed.
+func (s *connSet) GetConn(owner, rawURL, ca string, cert, key []byte) (*grpc.ClientConn, error) {
+	target, secure, err := ParseTarget(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	h.Write([]byte(target))
+	h.Write([]byte(ca))
+	h.Write(cert)
+	h.Write(key)
+	id := fmt.Sprintf("%t/%s", secure, hex.EncodeToString(h.Sum(nil)))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if conn, ok := s.conns[id]; ok {
+		s.setOwner(owner, target, id)
+		return conn, nil
+	}
+
+	creds := insecure.NewCredentials()
+	if secure {
+		tlsConfig := &tls.Config{}
+		if ca != "" && ca != "Cg==" {
+			bt, err := base64.StdEncoding.DecodeString(ca)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			tlsConfig.RootCAs.AppendCertsFromPEM(bt)
+		}
+		if len(cert) > 0 {
+			certificate, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{certificate}
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	// the connection is established lazily, so it does not fail when the server is down
+	conn, err := grpc.Dial(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	s.conns[id] = conn
+	s.setOwner(owner, target, id)
+	return conn, nil
+}
+
+// setOwner records the connection to the target used by the owner, and closes its previous connection if no
+// other owner uses it. It is called with the lock held.
+func (s *connSet) setOwner(owner, target, id string) {
+	if owner == "" {
+		return
+	}
+	key := owner + "/" + target
+	prev, ok := s.owners[key]
+	if ok && prev == id {
+		return
+	}
+	s.owners[key] = id
+	if !ok {
+		return
+	}
+	for _, other := range s.owners {
+		if other == prev {
+			return
+		}
+	}
+	if conn, ok := s.conns[prev]; ok {
+		_ = conn.Close()
+		delete(s.conns, prev)
+	}
+}
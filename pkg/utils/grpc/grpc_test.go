@@ -0,0 +1,44 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"testing"
+
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestConnOwner(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	s := &connSet{conns: map[string]*grpc.ClientConn{}, owners: map[string]string{}}
+	old, err := s.GetConn("webhook-a", "grpc://127.0.0.1:9443", "ca-1", nil, nil)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	// connections to other targets of the owner are kept
+	other, err := s.GetConn("webhook-a", "grpc://127.0.0.1:9444", "ca-1", nil, nil)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+
+	conn, err := s.GetConn("webhook-a", "grpc://127.0.0.1:9443", "ca-2", nil, nil)
+	g.Expect(err).ShouldNot(gomega.HaveOccurred())
+	g.Expect(conn).ShouldNot(gomega.BeIdenticalTo(old))
+	g.Expect(old.GetState()).Should(gomega.Equal(connectivity.Shutdown))
+	g.Expect(other.GetState()).ShouldNot(gomega.Equal(connectivity.Shutdown))
+	g.Expect(s.conns).Should(gomega.HaveLen(2))
+	g.Expect(conn.Close()).Should(gomega.Succeed())
+	g.Expect(other.Close()).Should(gomega.Succeed())
+}
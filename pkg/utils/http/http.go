@@ -18,9 +18,11 @@ package http
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -81,6 +83,20 @@ func DoHttpAndHttpsRequestWithToken(method, url string, body interface{}, header
 	return c.Do(req)
 }
 
+// DoHttpAndHttpsRequestWithClientCert sends the request with client certificate for mTLS. Ca with base64, cert and key with PEM.
+// Owner identifies the caller, e.g. the webhook, whose previous client is dropped once its certificate is rotated.
+func DoHttpAndHttpsRequestWithClientCert(method, url string, body interface{}, header map[string]string, ca string, cert, key []byte, owner string) (*http.Response, error) {
+	req, err := buildReq(method, url, body, header)
+	if err != nil {
+		return nil, err
+	}
+	c, err := DefaultClient.GetClientWithCert(owner, ca, cert, key)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
 func buildReq(method, url string, body interface{}, header map[string]string) (*http.Request, error) {
 	buf := &bytes.Buffer{}
 	if body != nil {
@@ -106,13 +122,18 @@ func newSharedClient() *clientSet {
 	return &clientSet{
 		caClientSet: map[string]*http.Client{},
 		tkClientSet: map[string]*http.Client{},
+		crClientSet: map[string]*http.Client{},
+		crOwners:    map[string]string{},
 	}
 }
 
 type clientSet struct {
 	caClientSet map[string]*http.Client
 	tkClientSet map[string]*http.Client
-	mu          sync.RWMutex
+	crClientSet map[string]*http.Client
+	// crOwners is the id of the client in crClientSet used by each owner
+	crOwners map[string]string
+	mu       sync.RWMutex
 }
 
 func (s *clientSet) GetClientWithCa(ca string) (c *http.Client, err error) {
@@ -135,6 +156,69 @@ func (s *clientSet) GetClientWithKey(key string) (c *http.Client, err error) {
 	return c, nil
 }
 
+// GetClientWithCert returns the client with the client certificate, clients are cached by ca, cert and key,
+// so a rotated certificate gets a new client. The previous client of the owner is dropped if no other owner uses it.
+func (s *clientSet) GetClientWithCert(owner, ca string, cert, key []byte) (*http.Client, error) {
+	h := sha256.New()
+	h.Write([]byte(ca))
+	h.Write(cert)
+	h.Write(key)
+	id := hex.EncodeToString(h.Sum(nil))
+	s.mu.RLock()
+	c, ok := s.crClientSet[id]
+	s.mu.RUnlock()
+	if ok {
+		s.setOwner(owner, id)
+		return c, nil
+	}
+
+	certificate, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{certificate}}
+	if ca != "" && ca != "Cg==" {
+		bt, err := base64.StdEncoding.DecodeString(ca)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		tlsConfig.RootCAs.AppendCertsFromPEM(bt)
+	}
+	c = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}, Timeout: timeout}
+	s.mu.Lock()
+	s.crClientSet[id] = c
+	s.mu.Unlock()
+	s.setOwner(owner, id)
+	return c, nil
+}
+
+// setOwner records the client used by the owner, and drops its previous client if no other owner uses it
+func (s *clientSet) setOwner(owner, id string) {
+	if owner == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, ok := s.crOwners[owner]
+	if ok && prev == id {
+		return
+	}
+	s.crOwners[owner] = id
+	if !ok {
+		return
+	}
+	for _, other := range s.crOwners {
+		if other == prev {
+			return
+		}
+	}
+	if c, ok := s.crClientSet[prev]; ok {
+		c.CloseIdleConnections()
+		delete(s.crClientSet, prev)
+	}
+}
+
 /*
  *  newClient.
  *	Case 1: Different ca use different client.
@@ -0,0 +1,42 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/onsi/gomega"
+)
+
+func TestClientOwner(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	s := newSharedClient()
+	s.crClientSet["cert-1"] = &http.Client{}
+	s.crClientSet["cert-2"] = &http.Client{}
+	s.setOwner("webhook-a", "cert-1")
+	s.setOwner("webhook-b", "cert-1")
+
+	// the client is still used by another owner
+	s.setOwner("webhook-a", "cert-2")
+	g.Expect(s.crClientSet).Should(gomega.HaveKey("cert-1"))
+
+	// the client of the rotated certificate is dropped
+	s.setOwner("webhook-b", "cert-2")
+	g.Expect(s.crClientSet).ShouldNot(gomega.HaveKey("cert-1"))
+	g.Expect(s.crClientSet).Should(gomega.HaveKey("cert-2"))
+}
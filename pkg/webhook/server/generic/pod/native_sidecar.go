@@ -0,0 +1,56 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+)
+
+// setNativeSidecarRestartPolicy sets restartPolicy of native sidecars injected by PodDecoration to Always on the
+// pod json, which is not supported by corev1.Container of this version.
+func setNativeSidecarRestartPolicy(raw []byte, sidecars sets.String) ([]byte, error) {
+	if sidecars.Len() == 0 {
+		return raw, nil
+	}
+	pod := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &pod); err != nil {
+		return nil, err
+	}
+	for _, c := range initContainersOf(pod) {
+		if name, _ := c["name"].(string); sidecars.Has(name) {
+			c["restartPolicy"] = string(kuperatorv1alpha1.ContainerRestartPolicyAlways)
+		}
+	}
+	return json.Marshal(pod)
+}
+
+func initContainersOf(pod map[string]interface{}) (containers []map[string]interface{}) {
+	spec, _ := pod["spec"].(map[string]interface{})
+	initContainers, _ := spec["initContainers"].([]interface{})
+	for i := range initContainers {
+		if c, ok := initContainers[i].(map[string]interface{}); ok {
+			if _, ok := c["name"].(string); ok {
+				containers = append(containers, c)
+			}
+		}
+	}
+	return containers
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func TestPatchRaw(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	// os, schedulingGates and restartPolicy of init containers are unknown to corev1.Pod of this version
+	raw := []byte(`{"metadata": {"name": "foo"}, "spec": {"os": {"name": "linux"}, "schedulingGates": [{"name": "gate"}],` +
+		`"initContainers": [{"name": "log-agent", "restartPolicy": "Always"}, {"name": "init"}], "containers": [{"name": "app"}]}}`)
+	original := &corev1.Pod{}
+	g.Expect(json.Unmarshal(raw, original)).Should(gomega.Succeed())
+	mutated := original.DeepCopy()
+	mutated.Labels = map[string]string{"app": "foo"}
+	mutated.Spec.InitContainers = append([]corev1.Container{{Name: "proxy"}}, mutated.Spec.InitContainers...)
+
+	patched, err := patchRaw(raw, original, mutated)
+	g.Expect(err).Should(gomega.BeNil())
+	patched, err = setNativeSidecarRestartPolicy(patched, sets.NewString("proxy"))
+	g.Expect(err).Should(gomega.BeNil())
+
+	res := map[string]interface{}{}
+	g.Expect(json.Unmarshal(patched, &res)).Should(gomega.Succeed())
+	spec := res["spec"].(map[string]interface{})
+	g.Expect(spec).Should(gomega.HaveKey("os"))
+	g.Expect(spec).Should(gomega.HaveKey("schedulingGates"))
+	g.Expect(res["metadata"]).Should(gomega.HaveKeyWithValue("labels", map[string]interface{}{"app": "foo"}))
+	initContainers := initContainersOf(res)
+	g.Expect(initContainers).Should(gomega.HaveLen(3))
+	g.Expect(initContainers[0]["name"]).Should(gomega.Equal("proxy"))
+	g.Expect(initContainers[0]["restartPolicy"]).Should(gomega.Equal("Always"))
+	g.Expect(initContainers[1]["restartPolicy"]).Should(gomega.Equal("Always"))
+	g.Expect(initContainers[2]).ShouldNot(gomega.HaveKey("restartPolicy"))
+}
@@ -24,9 +24,11 @@ import (
 
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/patch"
 	commonutils "kusionstack.io/kuperator/pkg/utils"
 	"kusionstack.io/kuperator/pkg/utils/mixin"
 )
@@ -67,6 +69,11 @@ func (h *MutatingHandler) Handle(ctx context.Context, req admission.Request) (re
 		logger.Error(err, "failed to decode admission request")
 		return admission.Errored(http.StatusBadRequest, err)
 	}
+	// namespace may be absent in the object on creation
+	if pod.Namespace == "" {
+		pod.Namespace = req.Namespace
+	}
+	original := pod.DeepCopy()
 	var oldPod *corev1.Pod
 	if req.Operation == admissionv1.Update || req.Operation == admissionv1.Delete {
 		oldPod = &corev1.Pod{}
@@ -83,11 +90,34 @@ func (h *MutatingHandler) Handle(ctx context.Context, req admission.Request) (re
 		}
 	}
 
-	marshaled, err := json.Marshal(pod)
+	marshaled, err := patchRaw(req.AdmissionRequest.Object.Raw, original, pod)
+	if err != nil {
+		logger.Error(err, "failed to patch pod json")
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	marshaled, err = setNativeSidecarRestartPolicy(marshaled, patch.GetNativeSidecars(pod))
 	if err != nil {
-		logger.Error(err, "failed to marshal pod json")
+		logger.Error(err, "failed to set restartPolicy of init containers")
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
 	return admission.PatchResponseFromRaw(req.AdmissionRequest.Object.Raw, marshaled)
 }
+
+// patchRaw applies changes from original to mutated pod on the raw object in request. Fields unknown to
+// corev1.Pod of this version are kept in raw, because they are in neither original nor mutated pod.
+func patchRaw(raw []byte, original, mutated *corev1.Pod) ([]byte, error) {
+	originalBytes, err := json.Marshal(original)
+	if err != nil {
+		return nil, err
+	}
+	mutatedBytes, err := json.Marshal(mutated)
+	if err != nil {
+		return nil, err
+	}
+	patchBytes, err := strategicpatch.CreateTwoWayMergePatch(originalBytes, mutatedBytes, &corev1.Pod{})
+	if err != nil {
+		return nil, err
+	}
+	return strategicpatch.StrategicMergePatch(raw, patchBytes, &corev1.Pod{})
+}
@@ -25,6 +25,7 @@ import (
 
 	"kusionstack.io/kuperator/pkg/webhook/server/generic/pod/gracedelete"
 	"kusionstack.io/kuperator/pkg/webhook/server/generic/pod/opslifecycle"
+	"kusionstack.io/kuperator/pkg/webhook/server/generic/pod/poddecoration"
 )
 
 var webhooks []AdmissionWebhook
@@ -38,6 +39,7 @@ type AdmissionWebhook interface {
 func init() {
 	webhooks = append(webhooks, opslifecycle.New())
 	webhooks = append(webhooks, gracedelete.New())
+	webhooks = append(webhooks, poddecoration.New())
 }
 
 func RegisterAdmissionWebhook(webhook AdmissionWebhook) {
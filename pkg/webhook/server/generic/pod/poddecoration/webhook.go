@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poddecoration
+
+import (
+	"context"
+	"fmt"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+	utilspoddecoration "kusionstack.io/kuperator/pkg/controllers/utils/poddecoration"
+	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/anno"
+	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/strategy"
+	"kusionstack.io/kuperator/pkg/features"
+	"kusionstack.io/kuperator/pkg/utils/feature"
+)
+
+// PodDecoration decorates pods not controlled by CollaSet on creation, with PodDecorations opting in by
+// the webhook-injection annotation. Both the pod and PodDecoration have to opt in. Pods controlled by
+// CollaSet are decorated by the CollaSet controller.
+type PodDecoration struct {
+	controller strategy.Controller
+}
+
+func New() *PodDecoration {
+	return &PodDecoration{controller: strategy.SharedStrategyController}
+}
+
+func (pd *PodDecoration) Name() string {
+	return "PodDecorationWebhook"
+}
+
+func (pd *PodDecoration) Mutating(ctx context.Context, c client.Client, oldPod, newPod *corev1.Pod, operation admissionv1.Operation) error {
+	// PodDecorationWebhookInjection FeatureGate defaults to false
+	// Add '--feature-gates=PodDecorationWebhookInjection=true' to container args, to enable it
+	if !feature.DefaultFeatureGate.Enabled(features.PodDecorationWebhookInjection) || operation != admissionv1.Create {
+		return nil
+	}
+	if newPod.Labels[kuperatorv1alpha1.LabelPodWebhookInjection] != "true" {
+		return nil
+	}
+	if ownerRef := metav1.GetControllerOf(newPod); ownerRef != nil && ownerRef.Kind == "CollaSet" {
+		return nil
+	}
+	// already decorated, if the pod is also received by webhook of pods controlled by KusionStack
+	if _, ok := newPod.Annotations[appsv1alpha1.AnnotationPodDecorationRevision]; ok {
+		return nil
+	}
+	if err := pd.controller.WaitForSync(ctx); err != nil {
+		return fmt.Errorf("fail to wait for PodDecoration strategy manager synced: %w", err)
+	}
+
+	enabled := sets.NewString()
+	for _, latest := range pd.controller.LatestPodDecorations(newPod.Namespace) {
+		if anno.IsWebhookInjection(latest) {
+			enabled.Insert(latest.Name)
+		}
+	}
+	if enabled.Len() == 0 {
+		return nil
+	}
+	getter, err := utilspoddecoration.NewPodDecorationGetter(c, newPod.Namespace)
+	if err != nil {
+		return err
+	}
+	effective, err := getter.GetEffective(ctx, newPod)
+	if err != nil {
+		return err
+	}
+	pds := map[string]*appsv1alpha1.PodDecoration{}
+	for revision, decoration := range effective {
+		if enabled.Has(decoration.Name) {
+			pds[revision] = decoration
+		}
+	}
+	if len(pds) == 0 {
+		return nil
+	}
+	return utilspoddecoration.PatchListOfDecorations(newPod, pds)
+}
+
+func (pd *PodDecoration) Validating(ctx context.Context, c client.Client, oldPod, newPod *corev1.Pod, operation admissionv1.Operation) error {
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The KusionStack Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poddecoration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/strategy"
+	"kusionstack.io/kuperator/pkg/utils/feature"
+)
+
+func TestMutating(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	runtime.Must(feature.DefaultMutableFeatureGate.Set("PodDecorationWebhookInjection=true"))
+	runtime.Must(appsv1alpha1.AddToScheme(scheme.Scheme))
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+
+	pd := &appsv1alpha1.PodDecoration{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "sidecar",
+			Annotations: map[string]string{kuperatorv1alpha1.AnnotationPodDecorationWebhookInjection: "true"},
+		},
+		Spec: appsv1alpha1.PodDecorationSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+			Template: appsv1alpha1.PodDecorationPodTemplate{
+				Containers: []*appsv1alpha1.ContainerPatch{
+					{
+						InjectPolicy: appsv1alpha1.AfterPrimaryContainer,
+						Container:    corev1.Container{Name: "sidecar", Image: "proxy:v1"},
+					},
+				},
+			},
+		},
+		Status: appsv1alpha1.PodDecorationStatus{CurrentRevision: "sidecar-1", UpdatedRevision: "sidecar-1"},
+	}
+	g.Expect(strategy.SharedStrategyController.InjectClient(c)).Should(gomega.Succeed())
+	strategy.SharedStrategyController.Synced()
+	g.Expect(strategy.SharedStrategyController.UpdateSelectedPods(ctx, pd, nil)).Should(gomega.Succeed())
+
+	newPod := func(labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo", Labels: labels},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "main", Image: "nginx"}}},
+		}
+	}
+	webhook := New()
+
+	// pod opting in
+	pod := newPod(map[string]string{"app": "foo", kuperatorv1alpha1.LabelPodWebhookInjection: "true"})
+	g.Expect(webhook.Mutating(ctx, c, nil, pod, admissionv1.Create)).Should(gomega.Succeed())
+	g.Expect(pod.Spec.Containers).Should(gomega.HaveLen(2))
+	g.Expect(pod.Spec.Containers[1].Name).Should(gomega.Equal("sidecar"))
+	g.Expect(pod.Labels[appsv1alpha1.PodDecorationLabelPrefix+"sidecar"]).Should(gomega.Equal("sidecar-1"))
+
+	// decorated once
+	g.Expect(webhook.Mutating(ctx, c, nil, pod, admissionv1.Create)).Should(gomega.Succeed())
+	g.Expect(pod.Spec.Containers).Should(gomega.HaveLen(2))
+
+	// pod not opting in
+	pod = newPod(map[string]string{"app": "foo"})
+	g.Expect(webhook.Mutating(ctx, c, nil, pod, admissionv1.Create)).Should(gomega.Succeed())
+	g.Expect(pod.Spec.Containers).Should(gomega.HaveLen(1))
+
+	// pod controlled by CollaSet
+	pod = newPod(map[string]string{"app": "foo", kuperatorv1alpha1.LabelPodWebhookInjection: "true"})
+	controller := true
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "CollaSet", Name: "foo", Controller: &controller}}
+	g.Expect(webhook.Mutating(ctx, c, nil, pod, admissionv1.Create)).Should(gomega.Succeed())
+	g.Expect(pod.Spec.Containers).Should(gomega.HaveLen(1))
+
+	// PodDecoration not opting in
+	delete(pd.Annotations, kuperatorv1alpha1.AnnotationPodDecorationWebhookInjection)
+	g.Expect(strategy.SharedStrategyController.UpdateSelectedPods(ctx, pd, nil)).Should(gomega.Succeed())
+	pod = newPod(map[string]string{"app": "foo", kuperatorv1alpha1.LabelPodWebhookInjection: "true"})
+	g.Expect(webhook.Mutating(ctx, c, nil, pod, admissionv1.Create)).Should(gomega.Succeed())
+	g.Expect(pod.Spec.Containers).Should(gomega.HaveLen(1))
+}
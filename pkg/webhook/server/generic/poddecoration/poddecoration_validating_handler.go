@@ -18,23 +18,39 @@ package poddecoration
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"path"
 	"path/filepath"
 	"strings"
 
 	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/apis/core"
 	k8scorev1 "k8s.io/kubernetes/pkg/apis/core/v1"
 	corevalidation "k8s.io/kubernetes/pkg/apis/core/validation"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	operatingv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+	utilspoddecoration "kusionstack.io/kuperator/pkg/controllers/utils/poddecoration"
+	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/anno"
+	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/patch"
+	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/strategy"
+	"kusionstack.io/kuperator/pkg/utils"
 	"kusionstack.io/kuperator/pkg/utils/mixin"
 )
 
@@ -65,7 +81,107 @@ func (h *ValidatingHandler) Handle(ctx context.Context, req admission.Request) (
 	if err := ValidatePodDecoration(pd); err != nil {
 		return admission.Denied(err.Error())
 	}
-	return admission.Allowed("")
+	var old *appsv1alpha1.PodDecoration
+	if req.Operation == admissionv1.Update {
+		old = &appsv1alpha1.PodDecoration{}
+		if err := h.Decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+	if pinned := anno.GetPinnedRevision(pd); pinned != "" && (old == nil || anno.GetPinnedRevision(old) != pinned) {
+		if err := ValidatePinnedRevision(ctx, h.Client, pd, pinned); err != nil {
+			return admission.Denied(err.Error())
+		}
+	}
+	// updates by controller, e.g. finalizers and conflicts, are not checked
+	if old != nil && !decorationChanged(old, pd) {
+		return admission.Allowed("")
+	}
+	if old == nil || variablesChanged(old, pd) {
+		if errs := ValidateVariables(pd, field.NewPath("spec", "template")); len(errs) > 0 {
+			return admission.Denied(errs.ToAggregate().Error())
+		}
+	}
+	// cluster-wide PodDecorations selecting the namespace may decorate the same pods
+	pds, err := strategy.ListNamespaceDecorations(ctx, h.Client, pd.Namespace)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	warnings, err := ValidateConflicts(pd, pds)
+	if err != nil {
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("").WithWarnings(warnings...)
+}
+
+func decorationChanged(old, pd *appsv1alpha1.PodDecoration) bool {
+	if !equality.Semantic.DeepEqual(old.Spec, pd.Spec) {
+		return true
+	}
+	for _, key := range append(operatingv1alpha1.PodDecorationRevisionAnnotations, operatingv1alpha1.AnnotationPodDecorationConflictPolicy) {
+		if old.Annotations[key] != pd.Annotations[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// variablesChanged checks whether the template or patches, in which variables are referenced, are changed
+func variablesChanged(old, pd *appsv1alpha1.PodDecoration) bool {
+	return !equality.Semantic.DeepEqual(old.Spec.Template, pd.Spec.Template) ||
+		old.Annotations[operatingv1alpha1.AnnotationPodDecorationPatches] != pd.Annotations[operatingv1alpha1.AnnotationPodDecorationPatches]
+}
+
+// ValidatePinnedRevision checks the pinned revision is in history of the PodDecoration
+func ValidatePinnedRevision(ctx context.Context, c client.Client, pd *appsv1alpha1.PodDecoration, pinned string) error {
+	fldPath := field.NewPath("metadata", "annotations").Key(operatingv1alpha1.AnnotationPodDecorationPinnedRevision)
+	revision := &appsv1.ControllerRevision{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: pd.Namespace, Name: pinned}, revision); err != nil {
+		if apierrors.IsNotFound(err) {
+			return field.NotFound(fldPath, pinned)
+		}
+		return err
+	}
+	if owner := metav1.GetControllerOf(revision); owner == nil || owner.Kind != "PodDecoration" || owner.UID != pd.UID {
+		return field.Invalid(fldPath, pinned, "revision is not in history of the PodDecoration")
+	}
+	return nil
+}
+
+// ValidateConflicts returns warnings of conflicts with PodDecorations which may select the same pods, and
+// rejects pd if it is newer than a conflicting PodDecoration and either of them is strict
+func ValidateConflicts(pd *appsv1alpha1.PodDecoration, pds []appsv1alpha1.PodDecoration) (warnings []string, err error) {
+	for i := range pds {
+		other := &pds[i]
+		// the namespace-local PodDecoration overrides the cluster-wide one with the same name
+		if other.Name == pd.Name || other.DeletionTimestamp != nil || !utilspoddecoration.SelectorsOverlap(pd.Spec.Selector, other.Spec.Selector) {
+			continue
+		}
+		fields := utilspoddecoration.Conflicts(other, pd)
+		if len(fields) == 0 {
+			continue
+		}
+		msg := fmt.Sprintf("conflicts with PodDecoration %s on %s", other.Name, strings.Join(fields, ", "))
+		strict := utilspoddecoration.GetConflictPolicy(pd) == operatingv1alpha1.ConflictPolicyStrict ||
+			utilspoddecoration.GetConflictPolicy(other) == operatingv1alpha1.ConflictPolicyStrict
+		if strict && isNewer(pd, other) {
+			err = utils.Join(err, errors.New(msg))
+			continue
+		}
+		warnings = append(warnings, msg)
+	}
+	return
+}
+
+// isNewer returns true if pd is created after other, pd being created is the newest
+func isNewer(pd, other *appsv1alpha1.PodDecoration) bool {
+	if pd.CreationTimestamp.IsZero() {
+		return true
+	}
+	if pd.CreationTimestamp.Equal(&other.CreationTimestamp) {
+		return pd.Name > other.Name
+	}
+	return other.CreationTimestamp.Before(&pd.CreationTimestamp)
 }
 
 var defaultValidationOptions = corevalidation.PodValidationOptions{
@@ -80,9 +196,212 @@ func ValidatePodDecoration(pd *appsv1alpha1.PodDecoration) error {
 	allErrs := field.ErrorList{}
 	specPath := field.NewPath("spec")
 	allErrs = append(allErrs, ValidateTemplate(&pd.Spec.Template, specPath.Child("template"))...)
+	allErrs = append(allErrs, ValidateInitContainerPatches(pd, field.NewPath("metadata", "annotations").Key(operatingv1alpha1.AnnotationPodDecorationInitContainers))...)
+	allErrs = append(allErrs, ValidatePodPatches(pd, field.NewPath("metadata", "annotations").Key(operatingv1alpha1.AnnotationPodDecorationPatches))...)
+	allErrs = append(allErrs, ValidateClusterWide(pd, field.NewPath("metadata", "annotations"))...)
+	allErrs = append(allErrs, ValidateCanary(pd, field.NewPath("metadata", "annotations").Key(operatingv1alpha1.AnnotationPodDecorationCanary))...)
+	switch policy := pd.Annotations[operatingv1alpha1.AnnotationPodDecorationConflictPolicy]; operatingv1alpha1.ConflictPolicy(policy) {
+	case "", operatingv1alpha1.ConflictPolicyReport, operatingv1alpha1.ConflictPolicyStrict:
+	default:
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("metadata", "annotations").Key(operatingv1alpha1.AnnotationPodDecorationConflictPolicy),
+			policy, []string{string(operatingv1alpha1.ConflictPolicyReport), string(operatingv1alpha1.ConflictPolicyStrict)}))
+	}
 	return allErrs.ToAggregate()
 }
 
+// ValidateCanary checks canary steps are in ascending percent, and the last one updates all pods
+func ValidateCanary(pd *appsv1alpha1.PodDecoration, fldPath *field.Path) (allErrs field.ErrorList) {
+	canary, err := anno.GetCanary(pd)
+	if err != nil {
+		return append(allErrs, field.Invalid(fldPath, pd.Annotations[operatingv1alpha1.AnnotationPodDecorationCanary], err.Error()))
+	}
+	if canary == nil {
+		return
+	}
+	if len(canary.Steps) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("steps"), ""))
+	}
+	var last int32
+	for i, step := range canary.Steps {
+		idxPath := fldPath.Child("steps").Index(i)
+		if step.Percent <= last || step.Percent > 100 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("percent"), step.Percent, "must be greater than the previous step and no more than 100"))
+		}
+		if step.PauseSeconds < 0 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("pauseSeconds"), step.PauseSeconds, "must be non-negative"))
+		}
+		last = step.Percent
+	}
+	if n := len(canary.Steps); n > 0 && last != 100 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("steps").Index(n-1).Child("percent"), last, "the last step must be 100"))
+	}
+	if canary.ProgressDeadlineSeconds != nil && *canary.ProgressDeadlineSeconds <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("progressDeadlineSeconds"), *canary.ProgressDeadlineSeconds, "must be positive"))
+	}
+	return
+}
+
+// ValidateClusterWide checks the namespace selector, which is only allowed in the namespace of kuperator, and
+// the group of PodDecoration
+func ValidateClusterWide(pd *appsv1alpha1.PodDecoration, fldPath *field.Path) (allErrs field.ErrorList) {
+	if val, ok := pd.Annotations[operatingv1alpha1.AnnotationPodDecorationNamespaceSelector]; ok {
+		selectorPath := fldPath.Key(operatingv1alpha1.AnnotationPodDecorationNamespaceSelector)
+		if pd.Namespace != anno.ClusterNamespace {
+			allErrs = append(allErrs, field.Forbidden(selectorPath,
+				fmt.Sprintf("cluster-wide PodDecorations are only allowed in namespace %s", anno.ClusterNamespace)))
+		} else if _, err := anno.GetNamespaceSelector(pd); err != nil {
+			allErrs = append(allErrs, field.Invalid(selectorPath, val, err.Error()))
+		}
+	}
+	if group, ok := pd.Annotations[operatingv1alpha1.AnnotationPodDecorationGroup]; ok {
+		for _, msg := range validation.IsQualifiedName(group) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(operatingv1alpha1.AnnotationPodDecorationGroup), group, msg))
+		}
+	}
+	return
+}
+
+// ValidateVariables checks variables referenced in the template and patches are supported. References to env of
+// the same container are allowed, which are expanded by kubelet, including env from envFrom. References in primary
+// containers are allowed, because they may refer to env of the patched containers of workloads.
+// It is only checked on creation or changes of the template and patches, so that PodDecorations created before
+// are still able to be updated.
+func ValidateVariables(pd *appsv1alpha1.PodDecoration, fldPath *field.Path) (allErrs field.ErrorList) {
+	template := pd.Spec.Template.DeepCopy()
+	for i, c := range template.InitContainers {
+		allErrs = append(allErrs, validateVariablesIn(c, containerEnv(c.Env, c.EnvFrom), fldPath.Child("initContainers").Index(i))...)
+	}
+	for i, c := range template.Containers {
+		allErrs = append(allErrs, validateVariablesIn(c, containerEnv(c.Env, c.EnvFrom), fldPath.Child("containers").Index(i))...)
+	}
+	template.InitContainers, template.Containers, template.PrimaryContainers = nil, nil, nil
+	allErrs = append(allErrs, validateVariablesIn(template, nil, fldPath)...)
+	if patches := pd.Annotations[operatingv1alpha1.AnnotationPodDecorationPatches]; patches != "" {
+		allErrs = append(allErrs, validateVariablesIn(json.RawMessage(patches), nil,
+			field.NewPath("metadata", "annotations").Key(operatingv1alpha1.AnnotationPodDecorationPatches))...)
+	}
+	return
+}
+
+func validateVariablesIn(obj interface{}, isEnv func(string) bool, fldPath *field.Path) (allErrs field.ErrorList) {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return append(allErrs, field.InternalError(fldPath, err))
+	}
+	unknown := sets.NewString()
+	for _, name := range utilspoddecoration.Variables(string(raw)) {
+		if !utilspoddecoration.KnownVariables.Has(name) && (isEnv == nil || !isEnv(name)) {
+			unknown.Insert(name)
+		}
+	}
+	if unknown.Len() > 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath, unknown.List(),
+			fmt.Sprintf("unsupported variables, supported: %s, use $$(NAME) to escape", strings.Join(utilspoddecoration.KnownVariables.List(), ", "))))
+	}
+	return
+}
+
+// containerEnv returns whether a name may be env of the container. Names from envFrom are unknown until the
+// ConfigMaps and Secrets are read, so any name with the prefix of envFrom is taken as env.
+func containerEnv(envs []corev1.EnvVar, envFrom []corev1.EnvFromSource) func(string) bool {
+	names := sets.NewString()
+	for _, env := range envs {
+		names.Insert(env.Name)
+	}
+	return func(name string) bool {
+		if names.Has(name) {
+			return true
+		}
+		for _, from := range envFrom {
+			if strings.HasPrefix(name, from.Prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ValidatePodPatches applies patches in order on a sample pod with the template applied
+func ValidatePodPatches(pd *appsv1alpha1.PodDecoration, fldPath *field.Path) (allErrs field.ErrorList) {
+	patches, err := anno.GetPodPatches(pd)
+	if err != nil {
+		return append(allErrs, field.Invalid(fldPath, pd.Annotations[operatingv1alpha1.AnnotationPodDecorationPatches], err.Error()))
+	}
+	if len(patches) == 0 {
+		return
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: pd.Namespace},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "main", Image: "sample"}},
+		},
+	}
+	_ = utilspoddecoration.PatchPodDecoration(pod, &pd.Spec.Template)
+	podBytes, err := json.Marshal(pod)
+	if err != nil {
+		return append(allErrs, field.InternalError(fldPath, err))
+	}
+	for i := range patches {
+		idxPath := fldPath.Index(i)
+		switch patches[i].Type {
+		case operatingv1alpha1.JSONPatchType, operatingv1alpha1.StrategicMergePatchType:
+		default:
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("type"), patches[i].Type,
+				[]string{string(operatingv1alpha1.JSONPatchType), string(operatingv1alpha1.StrategicMergePatchType)}))
+			continue
+		}
+		patched, err := patch.ApplyPodPatch(podBytes, &patches[i])
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("patch"), string(patches[i].Patch.Raw), err.Error()))
+			continue
+		}
+		// fields unknown to corev1.Pod of this version are dropped when patching pods
+		lost, err := patch.LostFields(patched)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("patch"), string(patches[i].Patch.Raw), err.Error()))
+			continue
+		}
+		if len(lost) > 0 {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("patch"), string(patches[i].Patch.Raw),
+				fmt.Sprintf("fields are not supported: %s", strings.Join(lost, ", "))))
+			continue
+		}
+		podBytes = patched
+	}
+	return
+}
+
+func ValidateInitContainerPatches(pd *appsv1alpha1.PodDecoration, fldPath *field.Path) (allErrs field.ErrorList) {
+	patches, err := anno.GetInitContainerPatches(pd)
+	if err != nil {
+		return append(allErrs, field.Invalid(fldPath, pd.Annotations[operatingv1alpha1.AnnotationPodDecorationInitContainers], err.Error()))
+	}
+	initContainers := sets.NewString()
+	for _, c := range pd.Spec.Template.InitContainers {
+		initContainers.Insert(c.Name)
+	}
+	for name, patch := range patches {
+		if !initContainers.Has(name) {
+			allErrs = append(allErrs, field.NotFound(fldPath.Child(name), "init container not found in spec.template.initContainers"))
+			continue
+		}
+		if patch == nil {
+			continue
+		}
+		switch patch.InjectPolicy {
+		case "", operatingv1alpha1.BeforeInitContainers, operatingv1alpha1.AfterInitContainers:
+		default:
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child(name, "injectPolicy"), patch.InjectPolicy,
+				[]string{string(operatingv1alpha1.BeforeInitContainers), string(operatingv1alpha1.AfterInitContainers)}))
+		}
+		if patch.RestartPolicy != nil && *patch.RestartPolicy != operatingv1alpha1.ContainerRestartPolicyAlways {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child(name, "restartPolicy"), *patch.RestartPolicy,
+				[]string{string(operatingv1alpha1.ContainerRestartPolicyAlways)}))
+		}
+	}
+	return
+}
+
 func ValidateTemplate(template *appsv1alpha1.PodDecorationPodTemplate, fldPath *field.Path) (allErrs field.ErrorList) {
 	operatingv1alpha1.SetDefaultPodSpecVolumes(template.Volumes)
 	allErrs = append(allErrs, ValidatePrimaryContainers(template.PrimaryContainers, fldPath.Child("primaryContainers"))...)
@@ -90,6 +409,9 @@ func ValidateTemplate(template *appsv1alpha1.PodDecorationPodTemplate, fldPath *
 	allErrs = append(allErrs, ValidateContainers(template.InitContainers, fldPath.Child("initContainers"))...)
 	allErrs = append(allErrs, ValidateVolumes(template.Volumes, fldPath.Child("volumes"))...)
 	allErrs = append(allErrs, ValidateTolerations(template.Tolerations, fldPath.Child("tolerations"))...)
+	if template.RuntimeClassName != nil {
+		allErrs = append(allErrs, corevalidation.ValidateRuntimeClassName(*template.RuntimeClassName, fldPath.Child("runtimeClassName"))...)
+	}
 	return
 }
 
@@ -17,13 +17,22 @@ limitations under the License.
 package poddecoration
 
 import (
+	"context"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatingv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+	"kusionstack.io/kuperator/pkg/controllers/utils/poddecoration/anno"
 )
 
 var _ = Describe("PodDecoration webhook", func() {
@@ -141,6 +150,241 @@ var _ = Describe("PodDecoration webhook", func() {
 			}
 			Expect(ValidatePodDecoration(pd)).ShouldNot(HaveOccurred())
 		})
+		It("validating native sidecars", func() {
+			pd := &appsv1alpha1.PodDecoration{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						operatingv1alpha1.AnnotationPodDecorationInitContainers: `{"proxy": {"injectPolicy": "BeforeInitContainers", "restartPolicy": "Always"}}`,
+					},
+				},
+				Spec: appsv1alpha1.PodDecorationSpec{
+					Template: appsv1alpha1.PodDecorationPodTemplate{
+						InitContainers: []*corev1.Container{
+							{
+								Name:  "proxy",
+								Image: "proxy:v1",
+							},
+						},
+					},
+				},
+			}
+			Expect(ValidatePodDecoration(pd)).ShouldNot(HaveOccurred())
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationInitContainers] = `{"proxy": {"restartPolicy": "OnFailure"}}`
+			Expect(ValidatePodDecoration(pd)).Should(HaveOccurred())
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationInitContainers] = `{"proxy": {"injectPolicy": "First"}}`
+			Expect(ValidatePodDecoration(pd)).Should(HaveOccurred())
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationInitContainers] = `{"agent": {"restartPolicy": "Always"}}`
+			Expect(ValidatePodDecoration(pd)).Should(HaveOccurred())
+		})
+		It("validating patches", func() {
+			pd := &appsv1alpha1.PodDecoration{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						operatingv1alpha1.AnnotationPodDecorationPatches: `[
+							{"type": "json", "patch": [{"op": "add", "path": "/spec/priorityClassName", "value": "high"}]},
+							{"type": "strategic", "patch": {"spec": {"hostAliases": [{"ip": "127.0.0.1", "hostnames": ["foo"]}]}}},
+							{"type": "json", "patch": [{"op": "replace", "path": "/spec/priorityClassName", "value": "low"}]}
+						]`,
+					},
+				},
+			}
+			Expect(ValidatePodDecoration(pd)).ShouldNot(HaveOccurred())
+			// the path is absent on the sample pod
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationPatches] = `[{"type": "json", "patch": [{"op": "remove", "path": "/spec/dnsConfig"}]}]`
+			Expect(ValidatePodDecoration(pd)).Should(HaveOccurred())
+			// the patched pod is invalid
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationPatches] = `[{"type": "strategic", "patch": {"spec": {"priority": "high"}}}]`
+			Expect(ValidatePodDecoration(pd)).Should(HaveOccurred())
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationPatches] = `[{"type": "merge", "patch": {}}]`
+			Expect(ValidatePodDecoration(pd)).Should(HaveOccurred())
+			// fields unknown to the pod of this version are lost
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationPatches] = `[{"type": "strategic", "patch": {"spec": {"topologySpreadConstraints": [
+				{"topologyKey": "zone", "maxSkew": 1, "whenUnsatisfiable": "DoNotSchedule", "matchLabelKeys": ["pod-template-hash"]}]}}}]`
+			Expect(ValidatePodDecoration(pd)).Should(HaveOccurred())
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationPatches] = `[{"type": "json", "patch": [{"op": "add", "path": "/spec/os", "value": {"name": "linux"}}]}]`
+			Expect(ValidatePodDecoration(pd)).Should(HaveOccurred())
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationPatches] = `[{"type": "strategic", "patch": {"spec": {"hostNetwork": false, "containers": [
+				{"name": "main", "resources": {"limits": {"cpu": "1000m"}}}]}}}]`
+			Expect(ValidatePodDecoration(pd)).ShouldNot(HaveOccurred())
+		})
+		It("validating conflicts", func() {
+			newPD := func(name string, created int64, image string) appsv1alpha1.PodDecoration {
+				return appsv1alpha1.PodDecoration{
+					ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.Unix(created, 0)},
+					Spec: appsv1alpha1.PodDecorationSpec{
+						Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+						Template: appsv1alpha1.PodDecorationPodTemplate{
+							InitContainers: []*corev1.Container{{Name: "agent", Image: image}},
+						},
+					},
+				}
+			}
+			older, newer := newPD("older", 1, "agent:v1"), newPD("newer", 2, "agent:v2")
+			warnings, err := ValidateConflicts(&newer, []appsv1alpha1.PodDecoration{older})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(warnings).Should(Equal([]string{"conflicts with PodDecoration older on initContainer agent"}))
+
+			// the newer one is rejected in strict mode
+			older.Annotations = map[string]string{operatingv1alpha1.AnnotationPodDecorationConflictPolicy: string(operatingv1alpha1.ConflictPolicyStrict)}
+			_, err = ValidateConflicts(&newer, []appsv1alpha1.PodDecoration{older})
+			Expect(err).Should(HaveOccurred())
+			_, err = ValidateConflicts(&older, []appsv1alpha1.PodDecoration{newer})
+			Expect(err).ShouldNot(HaveOccurred())
+
+			// selectors not overlapping
+			newer.Spec.Selector.MatchLabels["app"] = "bar"
+			warnings, err = ValidateConflicts(&newer, []appsv1alpha1.PodDecoration{older})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(warnings).Should(BeEmpty())
+
+			newer.Annotations = map[string]string{operatingv1alpha1.AnnotationPodDecorationConflictPolicy: "Ignore"}
+			Expect(ValidatePodDecoration(&newer)).Should(HaveOccurred())
+		})
+		It("validating pinned revision", func() {
+			controller := true
+			pd := &appsv1alpha1.PodDecoration{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo", UID: "foo"},
+			}
+			revision := func(name string, uid types.UID) *appsv1.ControllerRevision {
+				return &appsv1.ControllerRevision{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace:       "default",
+						Name:            name,
+						OwnerReferences: []metav1.OwnerReference{{Kind: "PodDecoration", Name: "foo", UID: uid, Controller: &controller}},
+					},
+				}
+			}
+			c := fake.NewClientBuilder().WithObjects(revision("foo-1", "foo"), revision("foo-2", "deleted")).Build()
+			Expect(ValidatePinnedRevision(context.TODO(), c, pd, "foo-1")).ShouldNot(HaveOccurred())
+			// revision of the PodDecoration deleted before
+			Expect(ValidatePinnedRevision(context.TODO(), c, pd, "foo-2")).Should(HaveOccurred())
+			Expect(ValidatePinnedRevision(context.TODO(), c, pd, "foo-3")).Should(HaveOccurred())
+		})
+		It("validating variables", func() {
+			path := field.NewPath("spec", "template")
+			pd := &appsv1alpha1.PodDecoration{
+				Spec: appsv1alpha1.PodDecorationSpec{
+					Template: appsv1alpha1.PodDecorationPodTemplate{
+						Metadata: []*appsv1alpha1.PodDecorationPodTemplateMeta{
+							{Labels: map[string]string{"instance": "$(INSTANCE_ID)"}},
+						},
+						Containers: []*appsv1alpha1.ContainerPatch{
+							{
+								Container: corev1.Container{
+									Name:  "sidecar",
+									Image: "nginx:v1",
+									Args:  []string{"--pod=$(POD_NAME)", "--dir=$(DATA_DIR)", "--raw=$$(HOME)"},
+									Env:   []corev1.EnvVar{{Name: "DATA_DIR", Value: "/data"}},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(ValidateVariables(pd, path).ToAggregate()).ShouldNot(HaveOccurred())
+
+			// env not declared in the container
+			pd.Spec.Template.Containers[0].Args = append(pd.Spec.Template.Containers[0].Args, "--home=$(HOME)")
+			Expect(ValidateVariables(pd, path).ToAggregate()).Should(HaveOccurred())
+
+			// env of containers is not allowed out of containers
+			pd.Spec.Template.Containers[0].Args = nil
+			pd.Spec.Template.Metadata[0].Labels["dir"] = "$(DATA_DIR)"
+			Expect(ValidateVariables(pd, path).ToAggregate()).Should(HaveOccurred())
+
+			delete(pd.Spec.Template.Metadata[0].Labels, "dir")
+			// env from envFrom is unknown, names with the prefix are allowed
+			pd.Spec.Template.Containers[0].Args = []string{"--home=$(CFG_HOME)"}
+			pd.Spec.Template.Containers[0].EnvFrom = []corev1.EnvFromSource{
+				{Prefix: "CFG_", ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "cfg"}}},
+			}
+			Expect(ValidateVariables(pd, path).ToAggregate()).ShouldNot(HaveOccurred())
+			pd.Spec.Template.Containers[0].Args = []string{"--home=$(HOME)"}
+			Expect(ValidateVariables(pd, path).ToAggregate()).Should(HaveOccurred())
+			pd.Spec.Template.Containers[0].Args = nil
+
+			// env of patched containers may be referenced in primary containers
+			pd.Spec.Template.PrimaryContainers = []*appsv1alpha1.PrimaryContainerPatch{
+				{PodDecorationPrimaryContainer: appsv1alpha1.PodDecorationPrimaryContainer{
+					Env: []corev1.EnvVar{{Name: "APP_HOME", Value: "$(HOME)/app"}},
+				}},
+			}
+			Expect(ValidateVariables(pd, path).ToAggregate()).ShouldNot(HaveOccurred())
+
+			pd.Annotations = map[string]string{
+				operatingv1alpha1.AnnotationPodDecorationPatches: `[{"type":"StrategicMergePatch","patch":{"metadata":{"labels":{"owner":"$(OWNER)"}}}}]`,
+			}
+			Expect(ValidateVariables(pd, path).ToAggregate()).Should(HaveOccurred())
+
+			// variables are not checked on updates without changes of template and patches
+			updated := pd.DeepCopy()
+			updated.Finalizers = []string{"kusionstack.io/finalizer"}
+			Expect(variablesChanged(pd, updated)).Should(BeFalse())
+			updated.Spec.Template.Containers[0].Image = "nginx:v2"
+			Expect(variablesChanged(pd, updated)).Should(BeTrue())
+		})
+
+		It("validating cluster-wide", func() {
+			pd := &appsv1alpha1.PodDecoration{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: anno.ClusterNamespace,
+					Name:      "log-agent",
+					Annotations: map[string]string{
+						operatingv1alpha1.AnnotationPodDecorationNamespaceSelector: `{"matchLabels": {"log-agent": "enabled"}}`,
+						operatingv1alpha1.AnnotationPodDecorationGroup:             "logging",
+					},
+				},
+			}
+			Expect(ValidatePodDecoration(pd)).ShouldNot(HaveOccurred())
+
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationGroup] = "logging group"
+			Expect(ValidatePodDecoration(pd)).Should(HaveOccurred())
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationGroup] = "logging"
+
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationNamespaceSelector] = `{"matchLabels": "log-agent"}`
+			Expect(ValidatePodDecoration(pd)).Should(HaveOccurred())
+
+			// only allowed in the namespace of kuperator
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationNamespaceSelector] = `{}`
+			Expect(ValidatePodDecoration(pd)).ShouldNot(HaveOccurred())
+			pd.Namespace = "default"
+			Expect(ValidatePodDecoration(pd)).Should(HaveOccurred())
+		})
+
+		It("validating canary", func() {
+			pd := &appsv1alpha1.PodDecoration{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						operatingv1alpha1.AnnotationPodDecorationCanary: `{"steps": [{"percent": 10, "waitForAvailable": true, "pauseSeconds": 60}, {"percent": 100}]}`,
+					},
+				},
+			}
+			Expect(ValidatePodDecoration(pd)).ShouldNot(HaveOccurred())
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationCanary] = `{"steps": [{"percent": 50}, {"percent": 10}]}`
+			Expect(ValidatePodDecoration(pd)).Should(HaveOccurred())
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationCanary] = `{"steps": [{"percent": 120}]}`
+			Expect(ValidatePodDecoration(pd)).Should(HaveOccurred())
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationCanary] = `{"steps": [{"percent": 10}, {"percent": 50}]}`
+			Expect(ValidatePodDecoration(pd)).Should(HaveOccurred())
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationCanary] = `{"steps": []}`
+			Expect(ValidatePodDecoration(pd)).Should(HaveOccurred())
+			pd.Annotations[operatingv1alpha1.AnnotationPodDecorationCanary] = `{"steps": [{"percent": 100}], "progressDeadlineSeconds": 0}`
+			Expect(ValidatePodDecoration(pd)).Should(HaveOccurred())
+		})
+
+		It("validating runtimeClassName", func() {
+			runtimeClassName := "kata"
+			pd := &appsv1alpha1.PodDecoration{
+				Spec: appsv1alpha1.PodDecorationSpec{
+					Template: appsv1alpha1.PodDecorationPodTemplate{
+						RuntimeClassName: &runtimeClassName,
+					},
+				},
+			}
+			Expect(ValidatePodDecoration(pd)).ShouldNot(HaveOccurred())
+			runtimeClassName = "Kata_Runtime"
+			Expect(ValidatePodDecoration(pd)).Should(HaveOccurred())
+		})
 		It("validating Tolerations", func() {
 			pd := &appsv1alpha1.PodDecoration{
 				Spec: appsv1alpha1.PodDecorationSpec{
@@ -22,10 +22,16 @@ import (
 	"net/http"
 
 	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/sets"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+	podtransitionruleutils "kusionstack.io/kuperator/pkg/controllers/podtransitionrule/utils"
 	commonutils "kusionstack.io/kuperator/pkg/utils"
 	"kusionstack.io/kuperator/pkg/utils/mixin"
 )
@@ -61,6 +67,15 @@ func (h *MutatingHandler) Handle(ctx context.Context, req admission.Request) (re
 		return admission.Errored(http.StatusBadRequest, err)
 	}
 	SetDefaultPodTransitionRule(rs)
+	var old *appsv1alpha1.PodTransitionRule
+	if req.Operation == admissionv1.Update {
+		old = &appsv1alpha1.PodTransitionRule{}
+		if err := h.Decoder.DecodeRaw(req.OldObject, old); err != nil {
+			logger.Error(err, "failed to decode old podtransitionrule")
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+	SetApprovals(rs, old, req.UserInfo.Username, metav1.Now())
 	marshaled, err := json.Marshal(rs)
 	if err != nil {
 		logger.Error(err, "failed to marshal podtransitionrule json")
@@ -90,3 +105,55 @@ func SetDefaultPodTransitionRule(rs *appsv1alpha1.PodTransitionRule) {
 		}
 	}
 }
+
+// SetApprovals sets ID, ApprovedBy and ApprovedAt of approvals which are not in the old PodTransitionRule,
+// so that the approval record shows who approved pods and when. Approvals in the old one are matched by pods
+// and count, and keep their ID, ApprovedBy and ApprovedAt, e.g. when the annotation is applied again from a
+// manifest without them. Invalid approvals are left to validation.
+func SetApprovals(rs, old *appsv1alpha1.PodTransitionRule, user string, now metav1.Time) {
+	approvals, err := podtransitionruleutils.GetApprovals(rs)
+	if err != nil || len(approvals) == 0 {
+		return
+	}
+	var oldApprovals map[string][]kuperatorv1alpha1.Approval
+	if old != nil {
+		oldApprovals, _ = podtransitionruleutils.GetApprovals(old)
+	}
+
+	changed := false
+	for ruleName := range approvals {
+		matched := sets.NewInt()
+		for i := range approvals[ruleName] {
+			approval := &approvals[ruleName][i]
+			if j := matchApproval(oldApprovals[ruleName], approval, matched); j >= 0 {
+				matched.Insert(j)
+				if !equality.Semantic.DeepEqual(oldApprovals[ruleName][j], *approval) {
+					*approval = oldApprovals[ruleName][j]
+					changed = true
+				}
+				continue
+			}
+			approval.ID = rand.String(8)
+			approval.ApprovedBy = user
+			approval.ApprovedAt = &now
+			changed = true
+		}
+	}
+	if changed {
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleApprovals] = commonutils.DumpJSON(approvals)
+	}
+}
+
+// matchApproval returns the index of the first approval not matched yet with the same pods and count, and the
+// same ID if it is set. It returns -1 if not found.
+func matchApproval(approvals []kuperatorv1alpha1.Approval, approval *kuperatorv1alpha1.Approval, matched sets.Int) int {
+	for i := range approvals {
+		if matched.Has(i) || approval.ID != "" && approval.ID != approvals[i].ID {
+			continue
+		}
+		if approvals[i].Count == approval.Count && equality.Semantic.DeepEqual(approvals[i].Pods, approval.Pods) {
+			return i
+		}
+	}
+	return -1
+}
@@ -25,16 +25,26 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
 	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+	"kusionstack.io/kuperator/pkg/controllers/podtransitionrule/processor/rules"
+	podtransitionruleutils "kusionstack.io/kuperator/pkg/controllers/podtransitionrule/utils"
 	commonutils "kusionstack.io/kuperator/pkg/utils"
+	utilsgrpc "kusionstack.io/kuperator/pkg/utils/grpc"
 	"kusionstack.io/kuperator/pkg/utils/mixin"
 )
 
@@ -100,9 +110,330 @@ func (h *ValidatingHandler) validate(rs *appsv1alpha1.PodTransitionRule) error {
 			errList = append(errList, field.Invalid(fRule.Child(rule.Name), nil, "minAvailableValue and maxUnavailableValue must have at least one configured"))
 		}
 	}
+	if mode, ok := rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleMode]; ok {
+		errList = append(errList, validateRuleMode(kuperatorv1alpha1.RuleMode(mode), field.NewPath("metadata", "annotations").Key(kuperatorv1alpha1.AnnotationPodTransitionRuleMode))...)
+	}
+	errList = append(errList, validateExtendedRules(rs)...)
+	errList = append(errList, validateApprovals(rs)...)
 	return errList.ToAggregate()
 }
 
+func validateApprovals(rs *appsv1alpha1.PodTransitionRule) field.ErrorList {
+	var errList field.ErrorList
+	fApprovals := field.NewPath("metadata", "annotations").Key(kuperatorv1alpha1.AnnotationPodTransitionRuleApprovals)
+	approvals, err := podtransitionruleutils.GetApprovals(rs)
+	if err != nil {
+		return append(errList, field.Invalid(fApprovals, rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleApprovals], err.Error()))
+	}
+	extendedRules, _ := podtransitionruleutils.GetExtendedRules(rs)
+	for name, ruleApprovals := range approvals {
+		if def := extendedRules[name]; def == nil || def.ManualApproval == nil {
+			errList = append(errList, field.NotFound(fApprovals.Child(name), "manual approval rule not found"))
+			continue
+		}
+		for i, approval := range ruleApprovals {
+			fApproval := fApprovals.Child(name).Index(i)
+			if approval.Count < 0 {
+				errList = append(errList, field.Invalid(fApproval.Child("count"), approval.Count, "should not be negative"))
+			}
+			if (len(approval.Pods) == 0) == (approval.Count == 0) {
+				errList = append(errList, field.Invalid(fApproval, commonutils.DumpJSON(approval), "exactly one of pods and count is required"))
+			}
+			for j, podName := range approval.Pods {
+				if podName == "" {
+					errList = append(errList, field.Required(fApproval.Child("pods").Index(j), "pod name is required"))
+				}
+			}
+		}
+	}
+	return errList
+}
+
+func validateExtendedRules(rs *appsv1alpha1.PodTransitionRule) field.ErrorList {
+	var errList field.ErrorList
+	fExtended := field.NewPath("metadata", "annotations").Key(kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules)
+	extendedRules, err := podtransitionruleutils.GetExtendedRules(rs)
+	if err != nil {
+		return append(errList, field.Invalid(fExtended, rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules], err.Error()))
+	}
+
+	specRules := map[string]*appsv1alpha1.TransitionRule{}
+	for i := range rs.Spec.Rules {
+		specRules[rs.Spec.Rules[i].Name] = &rs.Spec.Rules[i]
+	}
+	for name, def := range extendedRules {
+		if _, ok := specRules[name]; !ok {
+			errList = append(errList, field.NotFound(fExtended.Child(name), "rule not found in spec.rules"))
+			continue
+		}
+		if def == nil {
+			errList = append(errList, field.Required(fExtended.Child(name), "rule definition is required"))
+			continue
+		}
+		switch types := ruleTypes(specRules[name], def); len(types) {
+		case 0:
+			errList = append(errList, field.Required(fExtended.Child(name), "one rule type is required in the rule or its definition"))
+		case 1:
+		default:
+			errList = append(errList, field.Invalid(fExtended.Child(name), strings.Join(types, ", "), "only one rule type is allowed in the rule and its definition"))
+		}
+		if def.Mode != "" {
+			errList = append(errList, validateRuleMode(def.Mode, fExtended.Child(name).Child("mode"))...)
+		}
+		if def.MetricCheck != nil {
+			errList = append(errList, ValidateMetricCheck(def.MetricCheck, fExtended.Child(name).Child("metricCheck"))...)
+		}
+		if def.Cel != nil {
+			errList = append(errList, ValidateCel(def.Cel, fExtended.Child(name).Child("cel"))...)
+		}
+		if def.MaintenanceWindow != nil {
+			if _, err := rules.ParseMaintenanceWindow(def.MaintenanceWindow); err != nil {
+				errList = append(errList, field.Invalid(fExtended.Child(name).Child("maintenanceWindow"), def.MaintenanceWindow, err.Error()))
+			}
+		}
+		if def.RateLimit != nil {
+			if def.RateLimit.Limit <= 0 {
+				errList = append(errList, field.Invalid(fExtended.Child(name).Child("rateLimit", "limit"), def.RateLimit.Limit, "limit should be positive"))
+			}
+			if def.RateLimit.Window.Duration <= 0 {
+				errList = append(errList, field.Invalid(fExtended.Child(name).Child("rateLimit", "window"), def.RateLimit.Window.Duration.String(), "window should be positive"))
+			}
+		}
+		if def.Webhook != nil {
+			fWebhook := fExtended.Child(name).Child("webhook")
+			if specRules[name].Webhook == nil {
+				errList = append(errList, field.Forbidden(fWebhook, "webhook extension requires webhook of the rule in spec.rules"))
+			} else {
+				errList = append(errList, ValidateWebhookProtocol(specRules[name].Webhook, def.Webhook.Protocol, fWebhook.Child("protocol"))...)
+			}
+			errList = append(errList, ValidateWebhookExtension(def.Webhook, fWebhook)...)
+		}
+		if def.Dependency != nil {
+			errList = append(errList, ValidateDependency(def.Dependency, fExtended.Child(name).Child("dependency"))...)
+		}
+		if def.Group != nil {
+			errList = append(errList, ValidateRuleGroup(name, def.Group, specRules, extendedRules, fExtended.Child(name).Child("group"))...)
+		}
+		if def.Custom != nil {
+			errList = append(errList, ValidateCustom(specRules[name], def.Custom, fExtended.Child(name).Child("custom"))...)
+		}
+		if def.AvailablePolicy != nil {
+			fAvailable := fExtended.Child(name).Child("availablePolicy")
+			if specRules[name].AvailablePolicy == nil {
+				errList = append(errList, field.Forbidden(fAvailable, "availablePolicy extension requires availablePolicy of the rule in spec.rules"))
+			}
+			for _, msg := range validation.IsQualifiedName(def.AvailablePolicy.TopologyKey) {
+				errList = append(errList, field.Invalid(fAvailable.Child("topologyKey"), def.AvailablePolicy.TopologyKey, msg))
+			}
+		}
+	}
+	return errList
+}
+
+// ruleTypes returns types of the rule set in spec and in its extended definition. Webhook and availablePolicy
+// of the definition extend the rule in spec, so they are not counted.
+func ruleTypes(rule *appsv1alpha1.TransitionRule, def *kuperatorv1alpha1.ExtendedRuleDefinition) []string {
+	var types []string
+	for name, set := range map[string]bool{
+		"availablePolicy":   rule.AvailablePolicy != nil,
+		"labelCheck":        rule.LabelCheck != nil,
+		"webhook":           rule.Webhook != nil,
+		"metricCheck":       def.MetricCheck != nil,
+		"cel":               def.Cel != nil,
+		"maintenanceWindow": def.MaintenanceWindow != nil,
+		"rateLimit":         def.RateLimit != nil,
+		"dependency":        def.Dependency != nil,
+		"manualApproval":    def.ManualApproval != nil,
+		"group":             def.Group != nil,
+		"custom":            def.Custom != nil,
+	} {
+		if set {
+			types = append(types, name)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+func ValidateWebhookExtension(ext *kuperatorv1alpha1.WebhookExtension, f *field.Path) field.ErrorList {
+	var errList field.ErrorList
+	if ext.BearerTokenSecretRef != nil {
+		if ext.BearerTokenSecretRef.Name == "" {
+			errList = append(errList, field.Required(f.Child("bearerTokenSecretRef", "name"), "secret name is required"))
+		}
+		if ext.BearerTokenSecretRef.Key == "" {
+			errList = append(errList, field.Required(f.Child("bearerTokenSecretRef", "key"), "secret key is required"))
+		}
+	}
+	if ext.ClientCertSecretRef != nil && ext.ClientCertSecretRef.Name == "" {
+		errList = append(errList, field.Required(f.Child("clientCertSecretRef", "name"), "secret name is required"))
+	}
+	if ext.ApprovalCacheSeconds < 0 {
+		errList = append(errList, field.Invalid(f.Child("approvalCacheSeconds"), ext.ApprovalCacheSeconds, "should not be negative"))
+	}
+	if b := ext.Backoff; b != nil {
+		if b.InitialIntervalSeconds < 0 {
+			errList = append(errList, field.Invalid(f.Child("backoff", "initialIntervalSeconds"), b.InitialIntervalSeconds, "should not be negative"))
+		}
+		if b.MaxIntervalSeconds < 0 || (b.MaxIntervalSeconds > 0 && b.MaxIntervalSeconds < b.InitialIntervalSeconds) {
+			errList = append(errList, field.Invalid(f.Child("backoff", "maxIntervalSeconds"), b.MaxIntervalSeconds, "should not be less than initialIntervalSeconds"))
+		}
+		if b.MaxRetries < 0 {
+			errList = append(errList, field.Invalid(f.Child("backoff", "maxRetries"), b.MaxRetries, "should not be negative"))
+		}
+	}
+	if p := ext.Polling; p != nil {
+		if p.MaxConcurrentTasks < 0 {
+			errList = append(errList, field.Invalid(f.Child("polling", "maxConcurrentTasks"), p.MaxConcurrentTasks, "should not be negative"))
+		}
+		if p.DeadlineSeconds < 0 {
+			errList = append(errList, field.Invalid(f.Child("polling", "deadlineSeconds"), p.DeadlineSeconds, "should not be negative"))
+		}
+	}
+	return errList
+}
+
+func ValidateRuleGroup(name string, group *kuperatorv1alpha1.RuleGroup, specRules map[string]*appsv1alpha1.TransitionRule,
+	extendedRules map[string]*kuperatorv1alpha1.ExtendedRuleDefinition, f *field.Path,
+) field.ErrorList {
+	var errList field.ErrorList
+	if len(group.Rules) == 0 {
+		errList = append(errList, field.Required(f.Child("rules"), "member rules are required"))
+	}
+	if group.MinPassed < 0 || int(group.MinPassed) > len(group.Rules) {
+		errList = append(errList, field.Invalid(f.Child("minPassed"), group.MinPassed, fmt.Sprintf("should be in [0, %d]", len(group.Rules))))
+	}
+	members := sets.NewString()
+	for i, member := range group.Rules {
+		fMember := f.Child("rules").Index(i)
+		if members.Has(member) {
+			errList = append(errList, field.Duplicate(fMember, member))
+			continue
+		}
+		members.Insert(member)
+		if member == name {
+			errList = append(errList, field.Invalid(fMember, member, "group can not be a member of itself"))
+			continue
+		}
+		if _, ok := specRules[member]; !ok {
+			errList = append(errList, field.NotFound(fMember, member))
+			continue
+		}
+		if def := extendedRules[member]; def != nil && def.Group != nil {
+			errList = append(errList, field.Forbidden(fMember, "groups can not be nested"))
+		}
+		// member rules belong to only one group
+		for otherName, other := range extendedRules {
+			if otherName < name && other != nil && other.Group != nil && sets.NewString(other.Group.Rules...).Has(member) {
+				errList = append(errList, field.Forbidden(fMember, fmt.Sprintf("rule is already a member of group %s", otherName)))
+			}
+		}
+	}
+	return errList
+}
+
+// ValidateDependency checks the workload is specified, and at least one criterion is set
+func ValidateDependency(dep *kuperatorv1alpha1.DependencyRule, f *field.Path) field.ErrorList {
+	var errList field.ErrorList
+	if dep.APIVersion == "" {
+		errList = append(errList, field.Required(f.Child("apiVersion"), "apiVersion is required"))
+	} else if _, err := schema.ParseGroupVersion(dep.APIVersion); err != nil {
+		errList = append(errList, field.Invalid(f.Child("apiVersion"), dep.APIVersion, err.Error()))
+	}
+	if dep.Kind == "" {
+		errList = append(errList, field.Required(f.Child("kind"), "kind is required"))
+	}
+	if !dep.Updated && dep.MinReadyValue == nil && len(dep.Conditions) == 0 {
+		errList = append(errList, field.Required(f, "at least one of updated, minReadyValue and conditions is required"))
+	}
+	if dep.Name == "" {
+		errList = append(errList, field.Required(f.Child("name"), "name is required"))
+	}
+	if dep.MinReadyValue != nil {
+		if _, err := intstr.GetScaledValueFromIntOrPercent(dep.MinReadyValue, 100, true); err != nil {
+			errList = append(errList, field.Invalid(f.Child("minReadyValue"), dep.MinReadyValue.String(), err.Error()))
+		}
+	}
+	return errList
+}
+
+// ValidateWebhookProtocol checks the protocol, and URLs of the webhook with grpc protocol
+func ValidateWebhookProtocol(webhook *appsv1alpha1.TransitionRuleWebhook, protocol kuperatorv1alpha1.WebhookProtocol, f *field.Path) field.ErrorList {
+	switch protocol {
+	case "", kuperatorv1alpha1.WebhookProtocolHTTP:
+		return nil
+	case kuperatorv1alpha1.WebhookProtocolGRPC:
+	default:
+		return field.ErrorList{field.NotSupported(f, protocol, []string{string(kuperatorv1alpha1.WebhookProtocolHTTP), string(kuperatorv1alpha1.WebhookProtocolGRPC)})}
+	}
+	var errList field.ErrorList
+	if _, _, err := utilsgrpc.ParseTarget(webhook.ClientConfig.URL); err != nil {
+		errList = append(errList, field.Invalid(f, webhook.ClientConfig.URL, fmt.Sprintf("invalid url of webhook: %v", err)))
+	}
+	if webhook.ClientConfig.Poll != nil {
+		if _, _, err := utilsgrpc.ParseTarget(webhook.ClientConfig.Poll.URL); err != nil {
+			errList = append(errList, field.Invalid(f, webhook.ClientConfig.Poll.URL, fmt.Sprintf("invalid url of poll: %v", err)))
+		}
+	}
+	return errList
+}
+
+func validateRuleMode(mode kuperatorv1alpha1.RuleMode, f *field.Path) field.ErrorList {
+	switch mode {
+	case kuperatorv1alpha1.RuleModeEnforce, kuperatorv1alpha1.RuleModeAudit:
+		return nil
+	}
+	return field.ErrorList{field.NotSupported(f, mode, []string{string(kuperatorv1alpha1.RuleModeEnforce), string(kuperatorv1alpha1.RuleModeAudit)})}
+}
+
+func ValidateCustom(rule *appsv1alpha1.TransitionRule, custom *kuperatorv1alpha1.CustomRule, f *field.Path) field.ErrorList {
+	var errList field.ErrorList
+	factory, ok := rules.GetRulerFactory(custom.Kind)
+	if !ok {
+		return append(errList, field.NotSupported(f.Child("kind"), custom.Kind, rules.RegisteredRulerKinds()))
+	}
+	if _, err := factory(rule, custom.Params, nil); err != nil {
+		params := ""
+		if custom.Params != nil {
+			params = string(custom.Params.Raw)
+		}
+		errList = append(errList, field.Invalid(f.Child("params"), params, err.Error()))
+	}
+	return errList
+}
+
+func ValidateMetricCheck(metricCheck *kuperatorv1alpha1.MetricCheckRule, f *field.Path) field.ErrorList {
+	var errList field.ErrorList
+	if u, err := url.Parse(metricCheck.Address); err != nil || u.Scheme == "" || u.Host == "" {
+		errList = append(errList, field.Invalid(f.Child("address"), metricCheck.Address, "invalid Prometheus address"))
+	}
+	if metricCheck.Query == "" {
+		errList = append(errList, field.Required(f.Child("query"), "PromQL query is required"))
+	}
+	switch metricCheck.Scope {
+	case "", kuperatorv1alpha1.MetricCheckScopePod, kuperatorv1alpha1.MetricCheckScopeAggregate:
+	default:
+		errList = append(errList, field.NotSupported(f.Child("scope"), metricCheck.Scope,
+			[]string{string(kuperatorv1alpha1.MetricCheckScopePod), string(kuperatorv1alpha1.MetricCheckScopeAggregate)}))
+	}
+	switch metricCheck.Threshold.Operator {
+	case kuperatorv1alpha1.MetricOperatorLessThan, kuperatorv1alpha1.MetricOperatorLessOrEqual,
+		kuperatorv1alpha1.MetricOperatorGreaterThan, kuperatorv1alpha1.MetricOperatorGreaterOrEqual,
+		kuperatorv1alpha1.MetricOperatorEqual, kuperatorv1alpha1.MetricOperatorNotEqual:
+	default:
+		errList = append(errList, field.NotSupported(f.Child("threshold", "operator"), metricCheck.Threshold.Operator,
+			[]string{"<", "<=", ">", ">=", "==", "!="}))
+	}
+	if _, err := strconv.ParseFloat(metricCheck.Threshold.Value, 64); err != nil {
+		errList = append(errList, field.Invalid(f.Child("threshold", "value"), metricCheck.Threshold.Value, err.Error()))
+	}
+	if metricCheck.NoDataPolicy != nil && *metricCheck.NoDataPolicy != kuperatorv1alpha1.NoDataPolicyPass && *metricCheck.NoDataPolicy != kuperatorv1alpha1.NoDataPolicyReject {
+		errList = append(errList, field.NotSupported(f.Child("noDataPolicy"), *metricCheck.NoDataPolicy,
+			[]string{string(kuperatorv1alpha1.NoDataPolicyReject), string(kuperatorv1alpha1.NoDataPolicyPass)}))
+	}
+	return errList
+}
+
 func ValidateWebhook(webhook *appsv1alpha1.TransitionRuleWebhook, f *field.Path) *field.Error {
 	if err := CheckServerReachable(webhook.ClientConfig.URL); err != nil {
 		return field.Invalid(f.Child("clientConfig").Child("url"), webhook.ClientConfig.URL, err.Error())
@@ -154,3 +485,16 @@ func CheckCaBundle(ca string) error {
 	}
 	return nil
 }
+
+func ValidateCel(celRule *kuperatorv1alpha1.CelRule, f *field.Path) field.ErrorList {
+	var errList field.ErrorList
+	if celRule.Expression == "" {
+		errList = append(errList, field.Required(f.Child("expression"), "cel expression is required"))
+	} else if _, err := rules.CompileCelExpression(celRule.Expression); err != nil {
+		errList = append(errList, field.Invalid(f.Child("expression"), celRule.Expression, err.Error()))
+	}
+	if _, err := rules.ParseCelReason(celRule.Reason); err != nil {
+		errList = append(errList, field.Invalid(f.Child("reason"), celRule.Reason, err.Error()))
+	}
+	return errList
+}
@@ -17,15 +17,25 @@
 package podtransitionrule
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	appsv1alpha1 "kusionstack.io/kube-api/apps/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kuperatorv1alpha1 "kusionstack.io/kuperator/apis/apps/v1alpha1"
+	"kusionstack.io/kuperator/pkg/controllers/podtransitionrule/processor/rules"
+	podtransitionruleutils "kusionstack.io/kuperator/pkg/controllers/podtransitionrule/utils"
 )
 
 var _ = Describe("PodTransitionRule Validating", func() {
@@ -166,6 +176,320 @@ var _ = Describe("PodTransitionRule Validating", func() {
 		}
 		Expect(NewValidatingHandler().validate(rs)).Should(BeNil())
 	})
+	It("Validate MetricCheck", func() {
+		rs.Spec = appsv1alpha1.PodTransitionRuleSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"test": "test"},
+			},
+			Rules: []appsv1alpha1.TransitionRule{
+				{
+					Name: "error-rate",
+				},
+			},
+		}
+		rs.Annotations = map[string]string{
+			kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules: `{"error-rate": {"metricCheck": {"address": "http://prometheus:9090", "query": "error_rate", "threshold": {"operator": "<", "value": "0.01"}}}}`,
+		}
+		Expect(NewValidatingHandler().validate(rs)).Should(BeNil())
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"error-rate": {"metricCheck": {"address": "prometheus", "query": "", "threshold": {"operator": "~", "value": "1%"}}}}`
+		err := NewValidatingHandler().validate(rs)
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("address"))
+		Expect(err.Error()).Should(ContainSubstring("query"))
+		Expect(err.Error()).Should(ContainSubstring("operator"))
+		Expect(err.Error()).Should(ContainSubstring("threshold.value"))
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"not-exist": {"metricCheck": {"address": "http://prometheus:9090", "query": "error_rate", "threshold": {"operator": "<", "value": "0.01"}}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		// exactly one rule type is required
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"error-rate": {"mode": "Audit"}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"error-rate": {"metricCheck": {"address": "http://prometheus:9090", "query": "error_rate", "threshold": {"operator": "<", "value": "0.01"}}, "cel": {"expression": "true"}}}`
+		err = NewValidatingHandler().validate(rs)
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("cel, metricCheck"))
+		rs.Spec.Rules[0].LabelCheck = &appsv1alpha1.LabelCheckRule{Requires: &metav1.LabelSelector{}}
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"error-rate": {"metricCheck": {"address": "http://prometheus:9090", "query": "error_rate", "threshold": {"operator": "<", "value": "0.01"}}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"error-rate": {"mode": "Audit"}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(BeNil())
+		rs.Annotations = nil
+	})
+	It("Validate Cel", func() {
+		rs.Spec = appsv1alpha1.PodTransitionRuleSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"test": "test"},
+			},
+			Rules: []appsv1alpha1.TransitionRule{
+				{
+					Name: "ready",
+				},
+			},
+		}
+		rs.Annotations = map[string]string{
+			kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules: `{"ready": {"cel": {"expression": "pod.metadata.annotations['ready'] == 'true'", "reason": "{{ .pod.metadata.name }} not ready"}}}`,
+		}
+		Expect(NewValidatingHandler().validate(rs)).Should(BeNil())
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"ready": {"cel": {"expression": "pod.metadata.name", "reason": "{{ .pod"}}}`
+		err := NewValidatingHandler().validate(rs)
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("expression"))
+		Expect(err.Error()).Should(ContainSubstring("reason"))
+		rs.Annotations = nil
+	})
+	It("Validate MaintenanceWindow", func() {
+		rs.Spec = appsv1alpha1.PodTransitionRuleSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"test": "test"},
+			},
+			Rules: []appsv1alpha1.TransitionRule{
+				{
+					Name: "window",
+				},
+			},
+		}
+		rs.Annotations = map[string]string{
+			kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules: `{"window": {"maintenanceWindow": {"timeZone": "Asia/Shanghai", "windows": [{"schedule": "0 2 * * 1-5", "duration": "3h"}]}}}`,
+		}
+		Expect(NewValidatingHandler().validate(rs)).Should(BeNil())
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"window": {"maintenanceWindow": {"timeZone": "Mars/Olympus", "windows": [{"schedule": "0 2 * * 1-5", "duration": "3h"}]}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"window": {"maintenanceWindow": {"freezes": [{"start": "2024-01-12T00:00:00Z", "end": "2024-01-10T00:00:00Z"}]}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		rs.Annotations = nil
+	})
+	It("Validate AvailablePolicy TopologyKey", func() {
+		maxUnavailable := intstr.FromInt(1)
+		rs.Spec = appsv1alpha1.PodTransitionRuleSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"test": "test"},
+			},
+			Rules: []appsv1alpha1.TransitionRule{
+				{
+					Name: "available",
+					TransitionRuleDefinition: appsv1alpha1.TransitionRuleDefinition{
+						AvailablePolicy: &appsv1alpha1.AvailableRule{
+							MaxUnavailableValue: &maxUnavailable,
+						},
+					},
+				},
+				{
+					Name: "other",
+				},
+			},
+		}
+		rs.Annotations = map[string]string{
+			kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules: `{"available": {"availablePolicy": {"topologyKey": "topology.kubernetes.io/zone"}}}`,
+		}
+		Expect(NewValidatingHandler().validate(rs)).Should(BeNil())
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"available": {"availablePolicy": {"topologyKey": ""}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"other": {"availablePolicy": {"topologyKey": "topology.kubernetes.io/zone"}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		rs.Annotations = nil
+	})
+	It("Validate Custom", func() {
+		rules.RegisterRuler("always-pass", func(rule *appsv1alpha1.TransitionRule, params *runtime.RawExtension, client client.Client) (rules.Ruler, error) {
+			if params == nil {
+				return nil, fmt.Errorf("params is required")
+			}
+			return nil, nil
+		})
+		rs.Spec = appsv1alpha1.PodTransitionRuleSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"test": "test"},
+			},
+			Rules: []appsv1alpha1.TransitionRule{
+				{
+					Name: "custom",
+				},
+			},
+		}
+		rs.Annotations = map[string]string{
+			kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules: `{"custom": {"custom": {"kind": "always-pass", "params": {}}}}`,
+		}
+		Expect(NewValidatingHandler().validate(rs)).Should(BeNil())
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"custom": {"custom": {"kind": "always-pass"}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"custom": {"custom": {"kind": "unknown", "params": {}}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		rs.Annotations = nil
+	})
+	It("Validate Dependency", func() {
+		rs.Spec = appsv1alpha1.PodTransitionRuleSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"test": "test"},
+			},
+			Rules: []appsv1alpha1.TransitionRule{
+				{
+					Name: "dependency",
+				},
+			},
+		}
+		rs.Annotations = map[string]string{
+			kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules: `{"dependency": {"dependency": {"apiVersion": "apps.kusionstack.io/v1alpha1", "kind": "CollaSet", "name": "db", "minReadyValue": "80%", "conditions": ["Available"]}}}`,
+		}
+		Expect(NewValidatingHandler().validate(rs)).Should(BeNil())
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"dependency": {"dependency": {"apiVersion": "apps/v1", "kind": "Deployment"}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"dependency": {"dependency": {"apiVersion": "apps/v1", "kind": "Deployment", "name": "db", "minReadyValue": "80"}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		// workloads of any kind
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"dependency": {"dependency": {"apiVersion": "apps/v1", "kind": "DaemonSet", "name": "db", "updated": true}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(BeNil())
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"dependency": {"dependency": {"apiVersion": "apps/v1/beta", "kind": "Deployment", "name": "db", "updated": true}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		// no criterion
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"dependency": {"dependency": {"apiVersion": "apps/v1", "kind": "StatefulSet", "name": "db"}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"dependency": {"dependency": {"apiVersion": "apps/v1", "kind": "StatefulSet", "name": "db", "updated": true}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(BeNil())
+		rs.Annotations = nil
+	})
+	It("Validate Rule Group", func() {
+		rs.Spec = appsv1alpha1.PodTransitionRuleSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"test": "test"},
+			},
+			Rules: []appsv1alpha1.TransitionRule{
+				{Name: "approval"},
+				{Name: "emergency"},
+				{Name: "approval-or-emergency"},
+				{Name: "other"},
+			},
+		}
+		rs.Annotations = map[string]string{
+			kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules: `{"approval-or-emergency": {"group": {"rules": ["approval", "emergency"], "minPassed": 1}}}`,
+		}
+		Expect(NewValidatingHandler().validate(rs)).Should(BeNil())
+		// minPassed out of range
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"approval-or-emergency": {"group": {"rules": ["approval", "emergency"], "minPassed": 3}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		// unknown member
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"approval-or-emergency": {"group": {"rules": ["approval", "unknown"]}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		// nested groups
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"approval-or-emergency": {"group": {"rules": ["approval", "other"]}}, "other": {"group": {"rules": ["emergency"]}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		// member of two groups
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"approval-or-emergency": {"group": {"rules": ["approval", "emergency"]}}, "other": {"group": {"rules": ["emergency"]}}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		rs.Annotations = nil
+	})
+	It("Validate Approvals", func() {
+		rs.Spec = appsv1alpha1.PodTransitionRuleSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"test": "test"},
+			},
+			Rules: []appsv1alpha1.TransitionRule{
+				{
+					Name: "approve",
+				},
+			},
+		}
+		rs.Annotations = map[string]string{
+			kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules: `{"approve": {"manualApproval": {}}}`,
+			kuperatorv1alpha1.AnnotationPodTransitionRuleApprovals:     `{"approve": [{"pods": ["foo-0"]}, {"count": 2}]}`,
+		}
+		Expect(NewValidatingHandler().validate(rs)).Should(BeNil())
+
+		// new approvals are stamped with the user and time
+		old := rs.DeepCopy()
+		SetApprovals(rs, nil, "alice", metav1.Now())
+		approvals, err := podtransitionruleutils.GetApprovals(rs)
+		Expect(err).Should(BeNil())
+		Expect(approvals["approve"][0].ApprovedBy).Should(Equal("alice"))
+		Expect(approvals["approve"][1].ID).ShouldNot(BeEmpty())
+		old.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleApprovals] = rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleApprovals]
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleApprovals] = strings.Replace(
+			rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleApprovals], `]}`, `,{"pods":["foo-1"],"approvedBy":"mallory"}]}`, 1)
+		SetApprovals(rs, old, "bob", metav1.Now())
+		approvals, _ = podtransitionruleutils.GetApprovals(rs)
+		Expect(approvals["approve"]).Should(HaveLen(3))
+		Expect(approvals["approve"][0].ApprovedBy).Should(Equal("alice"))
+		Expect(approvals["approve"][2].ApprovedBy).Should(Equal("bob"))
+
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleApprovals] = `{"approve": [{"pods": ["foo-0"], "count": 1}]}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleApprovals] = `{"unknown": [{"count": 1}]}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		rs.Annotations = nil
+	})
+	It("Re-apply Approvals", func() {
+		manifest := `{"approve": [{"pods": ["foo-0"]}, {"count": 2}]}`
+		rs.Annotations = map[string]string{
+			kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules: `{"approve": {"manualApproval": {}}}`,
+			kuperatorv1alpha1.AnnotationPodTransitionRuleApprovals:     manifest,
+		}
+		SetApprovals(rs, nil, "alice", metav1.Now())
+		ruler := &rules.ManualApprovalRuler{Name: "approve", ManualApproval: &kuperatorv1alpha1.ManualApprovalRule{}}
+		targets := map[string]*corev1.Pod{}
+		for i := 0; i < 5; i++ {
+			name := fmt.Sprintf("foo-%d", i)
+			targets[name] = &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		}
+		res := ruler.Filter(rs, targets, sets.StringKeySet(targets))
+		Expect(res.Passed.List()).Should(Equal([]string{"foo-0", "foo-1", "foo-2"}))
+		rs.Status.RuleStates = []*appsv1alpha1.RuleState{res.RuleState}
+
+		// approvals applied again without ID, approvedBy and approvedAt keep the existing ones
+		old := rs.DeepCopy()
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleApprovals] = manifest
+		SetApprovals(rs, old, "bob", metav1.Now())
+		Expect(rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleApprovals]).Should(Equal(old.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleApprovals]))
+		res = ruler.Filter(rs, targets, sets.StringKeySet(targets))
+		Expect(res.Passed.List()).Should(Equal([]string{"foo-0", "foo-1", "foo-2"}))
+		rs.Annotations = nil
+		rs.Status.RuleStates = nil
+	})
+	It("Validate Webhook Polling", func() {
+		ext := &kuperatorv1alpha1.WebhookExtension{
+			Polling: &kuperatorv1alpha1.WebhookPolling{MaxConcurrentTasks: 2, DeadlineSeconds: 30},
+		}
+		Expect(ValidateWebhookExtension(ext, field.NewPath("webhook"))).Should(BeEmpty())
+		ext.Polling.MaxConcurrentTasks = -1
+		Expect(ValidateWebhookExtension(ext, field.NewPath("webhook"))).Should(HaveLen(1))
+	})
+	It("Validate Webhook Protocol", func() {
+		webhook := &appsv1alpha1.TransitionRuleWebhook{
+			ClientConfig: appsv1alpha1.ClientConfigBeta1{
+				URL:  "grpcs://approval.example.com:8443",
+				Poll: &appsv1alpha1.Poll{URL: "grpcs://approval.example.com:8443"},
+			},
+		}
+		Expect(ValidateWebhookProtocol(webhook, kuperatorv1alpha1.WebhookProtocolGRPC, field.NewPath("protocol"))).Should(BeEmpty())
+		Expect(ValidateWebhookProtocol(webhook, "thrift", field.NewPath("protocol"))).Should(HaveLen(1))
+		webhook.ClientConfig.Poll.URL = "https://approval.example.com/poll"
+		Expect(ValidateWebhookProtocol(webhook, kuperatorv1alpha1.WebhookProtocolGRPC, field.NewPath("protocol"))).Should(HaveLen(1))
+		Expect(ValidateWebhookProtocol(webhook, kuperatorv1alpha1.WebhookProtocolHTTP, field.NewPath("protocol"))).Should(BeEmpty())
+	})
+	It("Validate Mode", func() {
+		rs.Spec = appsv1alpha1.PodTransitionRuleSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"test": "test"},
+			},
+			Rules: []appsv1alpha1.TransitionRule{
+				{
+					Name: "labelCheck",
+					TransitionRuleDefinition: appsv1alpha1.TransitionRuleDefinition{
+						LabelCheck: &appsv1alpha1.LabelCheckRule{
+							Requires: &metav1.LabelSelector{MatchLabels: map[string]string{"ready": "true"}},
+						},
+					},
+				},
+			},
+		}
+		rs.Annotations = map[string]string{
+			kuperatorv1alpha1.AnnotationPodTransitionRuleMode:          "Audit",
+			kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules: `{"labelCheck": {"mode": "Enforce"}}`,
+		}
+		Expect(NewValidatingHandler().validate(rs)).Should(BeNil())
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleMode] = "DryRun"
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleMode] = "Audit"
+		rs.Annotations[kuperatorv1alpha1.AnnotationPodTransitionRuleExtendedRules] = `{"labelCheck": {"mode": "audit"}}`
+		Expect(NewValidatingHandler().validate(rs)).Should(HaveOccurred())
+		rs.Annotations = nil
+	})
 	It("Mutating PodTransitionRule", func() {
 		rs.Spec = appsv1alpha1.PodTransitionRuleSpec{
 			Selector: &metav1.LabelSelector{